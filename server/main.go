@@ -1,22 +1,15 @@
+// 命令行入口：解析参数后启动 splholder 服务，或运行 doctor 自检
 package main
 
 import (
-	"bytes"
 	"context"
-	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
-	"runtime"
-	"strconv"
-	"strings"
-	"syscall"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"solana-spl-holder/splholder"
+
 	"github.com/spf13/cobra"
 )
 
@@ -26,941 +19,46 @@ var (
 	GitCommit = "unknown"
 )
 
-// 全局日志记录器
-var (
-	logger   = log.New(os.Stdout, "[solana-spl-holder] ", log.LstdFlags|log.Lshortfile)
-	errorLog = log.New(os.Stderr, "[ERROR] ", log.LstdFlags|log.Lshortfile)
-	infoLog  = log.New(os.Stdout, "[INFO] ", log.LstdFlags)
-	debugLog = log.New(os.Stdout, "[DEBUG] ", log.LstdFlags)
-)
-
-// 获取当前Go协程ID（仅用于日志调试）
-func getGoroutineID() string {
-	var buf [64]byte
-	n := runtime.Stack(buf[:], false)
-	stack := string(buf[:n])
-	var id string
-	fmt.Sscanf(stack, "goroutine %s ", &id)
-	return id
-}
-
-// 错误包装函数
-func wrapError(operation string, err error) error {
-	if err == nil {
-		return nil
-	}
-	return fmt.Errorf("%s: %w", operation, err)
-}
-
-// 日志辅助函数
-func logError(operation string, err error) {
-	if err != nil {
-		errorLog.Printf("%s: %v", operation, err)
-	}
-}
-
-func logInfo(format string, args ...interface{}) {
-	infoLog.Printf(format, args...)
-}
-
-func logDebug(format string, args ...interface{}) {
-	debugLog.Printf(format, args...)
-}
-
-// =================================================================
-// 1. 数据结构定义 (用于JSON解析和数据库映射)
-// =================================================================
+var errorLog = log.New(os.Stderr, "[ERROR] ", log.LstdFlags|log.Lshortfile)
 
-// RPCRequest 定义了发送到 Solana RPC 的请求体结构
-type RPCRequest struct {
-	Jsonrpc string        `json:"jsonrpc"`
-	ID      string        `json:"id"`
-	Method  string        `json:"method"`
-	Params  []interface{} `json:"params"`
-}
-
-// RPCResponse 定义了从 Solana RPC 返回的响应体结构
-type RPCResponse struct {
-	Jsonrpc string       `json:"jsonrpc"`
-	ID      string       `json:"id"`
-	Result  []ResultItem `json:"result"`
-	Error   *RPCError    `json:"error,omitempty"`
-}
-
-type RPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
-
-// ResultItem 对应响应中 result 数组的每个元素
-type ResultItem struct {
-	Pubkey  string  `json:"pubkey"`
-	Account Account `json:"account"`
-}
-
-// Account 对应账户信息
-type Account struct {
-	Lamports uint64 `json:"lamports"`
-	Data     Data   `json:"data"`
-	Owner    string `json:"owner"`
-}
-
-// Data 对应账户数据
-type Data struct {
-	Parsed Parsed `json:"parsed"`
-}
-
-// Parsed 对应解析后的数据
-type Parsed struct {
-	Info Info   `json:"info"`
-	Type string `json:"type"`
-}
-
-// Info 包含详细的代币信息
-type Info struct {
-	IsNative    bool        `json:"isNative"`
-	Owner       string      `json:"owner"`
-	State       string      `json:"state"`
-	TokenAmount TokenAmount `json:"tokenAmount"`
-}
-
-// TokenAmount 包含代币数量信息
-type TokenAmount struct {
-	Amount         string  `json:"amount"`
-	Decimals       int     `json:"decimals"`
-	UIAmount       float64 `json:"uiAmount"`
-	UIAmountString string  `json:"uiAmountString"`
-}
-
-// Holder 对应数据库中的 'holder' 表结构
-type Holder struct {
-	ID             int64     `json:"id"`
-	Mint           string    `json:"mint"`
-	Pubkey         string    `json:"pubkey"`
-	Lamports       uint64    `json:"lamports"`
-	IsNative       bool      `json:"isNative"`
-	Owner          string    `json:"owner"`
-	State          string    `json:"state"`
-	Decimals       int       `json:"decimals"`
-	Amount         string    `json:"amount"`
-	UIAmount       float64   `json:"uiAmount"`
-	UIAmountString string    `json:"uiAmountString"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
-}
-
-
-
-// HolderUpdateRequest 更新Holder状态的请求结构
-type HolderUpdateRequest struct {
-	State string `json:"state" validate:"required"`
-}
-
-// 验证Holder更新请求
-func (req *HolderUpdateRequest) Validate() error {
-	validStates := []string{"uninitialized", "initialized", "frozen"}
-	for _, validState := range validStates {
-		if req.State == validState {
-			return nil
-		}
-	}
-	return fmt.Errorf("state必须是以下值之一: %v", validStates)
-}
-
-// 查询spl表所有mint
-func getAllMintAddresses(db *sql.DB) ([]string, error) {
-	rows, err := db.Query("SELECT mint FROM spl")
-	if err != nil {
-		return nil, wrapError("查询mint列表", err)
-	}
-	defer rows.Close()
-
-	var result []string
-	for rows.Next() {
-		var mint string
-		if err := rows.Scan(&mint); err != nil {
-			return nil, wrapError("扫描mint", err)
-		}
-		result = append(result, mint)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, wrapError("遍历查询结果", err)
-	}
-
-	logInfo("成功获取到 %d 个mint地址", len(result))
-	return result, nil
-}
-
-// MariaDB插入/更新
-func upsertHolderMariaDB(dbOrTx interface{}, mintAddress string, item ResultItem) error {
-	// 数据验证
-	if mintAddress == "" {
-		return fmt.Errorf("mint地址不能为空")
-	}
-	if item.Pubkey == "" {
-		return fmt.Errorf("pubkey不能为空")
-	}
-
-	info := item.Account.Data.Parsed.Info
-	sqlStr := `INSERT INTO holder (
-		mint, pubkey, lamports, is_native, owner, state, decimals, amount, ui_amount, ui_amount_string, created_at, updated_at
-	) VALUES (
-		?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
-	) ON DUPLICATE KEY UPDATE
-		lamports = VALUES(lamports),
-		is_native = VALUES(is_native),
-		owner = VALUES(owner),
-		state = VALUES(state),
-		decimals = VALUES(decimals),
-		amount = VALUES(amount),
-		ui_amount = VALUES(ui_amount),
-		ui_amount_string = VALUES(ui_amount_string),
-		updated_at = CURRENT_TIMESTAMP;`
-
-	var execFn func(string, ...interface{}) (sql.Result, error)
-	switch v := dbOrTx.(type) {
-	case *sql.DB:
-		execFn = v.Exec
-	case *sql.Tx:
-		execFn = v.Exec
-	default:
-		return fmt.Errorf("无效的数据库连接类型")
-	}
-
-	_, err := execFn(sqlStr,
-		mintAddress,
-		item.Pubkey,
-		item.Account.Lamports,
-		info.IsNative,
-		info.Owner,
-		info.State,
-		info.TokenAmount.Decimals,
-		info.TokenAmount.Amount,
-		info.TokenAmount.UIAmount,
-		info.TokenAmount.UIAmountString,
-	)
-	if err != nil {
-		return wrapError(fmt.Sprintf("更新持有者数据(pubkey: %s)", item.Pubkey), err)
-	}
-	return nil
-}
-
-// 检查表是否存在
-func checkTableExists(db *sql.DB, tableName string) (bool, error) {
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", tableName).Scan(&count)
-	if err != nil {
-		return false, wrapError(fmt.Sprintf("检查表%s是否存在", tableName), err)
-	}
-	return count > 0, nil
-}
-
-// 检查视图是否存在
-func checkViewExists(db *sql.DB, viewName string) (bool, error) {
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.views WHERE table_schema = DATABASE() AND table_name = ?", viewName).Scan(&count)
-	if err != nil {
-		return false, wrapError(fmt.Sprintf("检查视图%s是否存在", viewName), err)
-	}
-	return count > 0, nil
-}
-
-// MariaDB初始化
-func initMariaDB(connStr string) (*sql.DB, error) {
-	if connStr == "" {
-		return nil, fmt.Errorf("数据库连接字符串不能为空")
-	}
-
-	logInfo("正在连接数据库...")
-	db, err := sql.Open("mysql", connStr)
-	if err != nil {
-		return nil, wrapError("打开数据库连接", err)
-	}
-
-	// 设置连接池参数
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
-	if err = db.Ping(); err != nil {
-		return nil, wrapError("数据库连接测试", err)
-	}
-
-	logInfo("数据库连接成功")
-
-	// 检查spl视图是否存在
-	splViewExists, err := checkViewExists(db, "spl")
-	if err != nil {
-		return nil, wrapError("检查spl视图是否存在", err)
-	}
-	if !splViewExists {
-		logError("数据库检查失败", fmt.Errorf("spl视图不存在，请先创建spl视图"))
-		os.Exit(1)
-	}
-
-	// 检查holder表是否存在
-	holderTableExists, err := checkTableExists(db, "holder")
-	if err != nil {
-		return nil, wrapError("检查holder表是否存在", err)
-	}
-	if !holderTableExists {
-		logError("数据库检查失败", fmt.Errorf("holder表不存在，请先创建holder表"))
-		os.Exit(1)
-	}
-
-	logInfo("数据库表和视图检查完成")
-	return db, nil
-}
-
-// API响应结构
-type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Total   int         `json:"total,omitempty"`
-	Page    int         `json:"page,omitempty"`
-	Limit   int         `json:"limit,omitempty"`
-}
-
-// 发送JSON响应
-func sendJSONResponse(w http.ResponseWriter, statusCode int, response APIResponse) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(response)
-}
-
-
-
-
-
-
-
-
-
-// 更新Holder状态
-func updateHolderState(db *sql.DB, mintAddress, pubkey, state string) (*Holder, error) {
-	// 检查记录是否存在
-	var exists bool
-	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM holder WHERE mint = ? AND pubkey = ?)", mintAddress, pubkey).Scan(&exists)
-	if err != nil {
-		return nil, wrapError("检查Holder记录是否存在", err)
-	}
-
-	if !exists {
-		return nil, fmt.Errorf("mint为 %s 且 pubkey为 %s 的Holder记录不存在", mintAddress, pubkey)
-	}
-
-	// 更新状态
-	_, err = db.Exec("UPDATE holder SET state = ?, updated_at = CURRENT_TIMESTAMP WHERE mint = ? AND pubkey = ?", state, mintAddress, pubkey)
-	if err != nil {
-		return nil, wrapError("更新Holder状态", err)
-	}
-
-	// 查询更新后的记录
-	var holder Holder
-	err = db.QueryRow(`
-		SELECT id, mint, pubkey, lamports, is_native, owner, state, decimals, 
-		       amount, ui_amount, ui_amount_string, created_at, updated_at 
-		FROM holder 
-		WHERE mint = ? AND pubkey = ?
-	`, mintAddress, pubkey).Scan(
-		&holder.ID, &holder.Mint, &holder.Pubkey, &holder.Lamports,
-		&holder.IsNative, &holder.Owner, &holder.State, &holder.Decimals,
-		&holder.Amount, &holder.UIAmount, &holder.UIAmountString,
-		&holder.CreatedAt, &holder.UpdatedAt,
-	)
-	if err != nil {
-		return nil, wrapError("查询更新后的Holder记录", err)
-	}
-
-	return &holder, nil
-}
-
-// 处理更新Holder状态的HTTP请求
-func handleUpdateHolderState(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPut {
-			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
-				Success: false,
-				Error:   "Method not allowed",
-			})
-			return
-		}
-
-		// 从URL路径中提取mint_address和pubkey
-		path := r.URL.Path
-		parts := strings.Split(strings.Trim(path, "/"), "/")
-		if len(parts) < 3 {
-			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
-				Success: false,
-				Error:   "Missing mint_address or pubkey",
-			})
-			return
-		}
-
-		mintAddress := parts[len(parts)-2]
-		pubkey := parts[len(parts)-1]
-		if mintAddress == "" || pubkey == "" {
-			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
-				Success: false,
-				Error:   "Invalid mint_address or pubkey",
-			})
-			return
-		}
-
-		// 解析请求体
-		var req HolderUpdateRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			logError("Failed to decode request body", err)
-			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
-				Success: false,
-				Error:   "Invalid JSON format",
-			})
-			return
-		}
-
-		// 验证请求
-		if err := req.Validate(); err != nil {
-			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
-				Success: false,
-				Error:   err.Error(),
-			})
-			return
-		}
-
-		// 更新Holder状态
-		holder, err := updateHolderState(db, mintAddress, pubkey, req.State)
+// 依次运行启动自检：先校验命令行参数，通过后再检查数据库、表结构和RPC节点
+func runDoctorChecks(cmd *cobra.Command) []splholder.DoctorCheck {
+	var config *splholder.Config
+	checks := []splholder.DoctorCheck{splholder.RunDoctorCheck("配置校验", true, func() (string, error) {
+		var err error
+		config, err = configFromFlags(cmd)
 		if err != nil {
-			logError("Failed to update holder state", err)
-			if strings.Contains(err.Error(), "不存在") {
-				sendJSONResponse(w, http.StatusNotFound, APIResponse{
-					Success: false,
-					Error:   err.Error(),
-				})
+			return "", err
+		}
+		return fmt.Sprintf("采集间隔 %v，RPC编码 %s", config.CollectionInterval(), config.RPCEncoding), nil
+	})}
+	if config == nil {
+		return checks
+	}
+	return append(checks, splholder.RunDoctorChecks(config)...)
+}
+
+// doctor子命令：输出每项自检的结果和耗时，关键检查失败时以状态码1退出
+func runDoctor(cmd *cobra.Command, args []string) {
+	checks := runDoctorChecks(cmd)
+	failed := false
+	for _, check := range checks {
+		result := "PASS"
+		if !check.Passed {
+			if check.Critical {
+				result = "FAIL"
+				failed = true
 			} else {
-				sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
-					Success: false,
-					Error:   "Failed to update holder state",
-				})
-			}
-			return
-		}
-
-		// 返回成功响应
-		sendJSONResponse(w, http.StatusOK, APIResponse{
-			Success: true,
-			Data:    holder,
-		})
-	}
-}
-
-// MariaDB API处理
-func apiHandlerMariaDB(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
-				Success: false,
-				Error:   "只支持GET方法",
-			})
-			return
-		}
-		query := r.URL.Query()
-		page, _ := strconv.Atoi(query.Get("page"))
-		if page < 1 {
-			page = 1
-		}
-		limit, _ := strconv.Atoi(query.Get("limit"))
-		if limit <= 0 {
-			limit = 10
-		}
-		if limit > 1000 {
-			limit = 1000 // 限制最大查询数量
-		}
-		offset := (page - 1) * limit
-		baseQuery := "SELECT id, mint, pubkey, lamports, is_native, owner, state, decimals, amount, ui_amount, ui_amount_string, created_at, updated_at FROM holder"
-		var args []interface{}
-		var conds []string
-		if owner := query.Get("owner"); owner != "" {
-			conds = append(conds, "owner = ?")
-			args = append(args, owner)
-		}
-		if mint := query.Get("mint"); mint != "" {
-			conds = append(conds, "mint = ?")
-			args = append(args, mint)
-		}
-		if state := query.Get("state"); state != "" {
-			conds = append(conds, "state = ?")
-			args = append(args, state)
-		}
-		if len(conds) > 0 {
-			baseQuery += " WHERE " + strings.Join(conds, " AND ")
-		}
-		sort := query.Get("sort")
-		if sort != "" {
-			dir := "ASC"
-			col := sort
-			if strings.HasPrefix(sort, "-") {
-				dir = "DESC"
-				col = sort[1:]
-			}
-			baseQuery += " ORDER BY " + col + " " + dir
-		}
-		baseQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
-		// 获取总数
-		countQuery := "SELECT COUNT(*) FROM holder"
-		if len(conds) > 0 {
-			countQuery += " WHERE " + strings.Join(conds, " AND ")
-		}
-		var total int
-		err := db.QueryRow(countQuery, args...).Scan(&total)
-		if err != nil {
-			logError("查询总数", err)
-			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
-				Success: false,
-				Error:   "查询总数失败",
-			})
-			return
-		}
-
-		rows, err := db.Query(baseQuery, args...)
-		if err != nil {
-			logError("查询持有者数据", err)
-			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
-				Success: false,
-				Error:   "查询数据失败",
-			})
-			return
-		}
-		defer rows.Close()
-
-		var holders []Holder
-		for rows.Next() {
-			var h Holder
-			err := rows.Scan(&h.ID, &h.Mint, &h.Pubkey, &h.Lamports, &h.IsNative, &h.Owner, &h.State, &h.Decimals, &h.Amount, &h.UIAmount, &h.UIAmountString, &h.CreatedAt, &h.UpdatedAt)
-			if err != nil {
-				logError("扫描数据行", err)
-				sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
-					Success: false,
-					Error:   "数据解析失败",
-				})
-				return
+				result = "WARN"
 			}
-			holders = append(holders, h)
 		}
-
-		if err := rows.Err(); err != nil {
-			logError("遍历查询结果", err)
-			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
-				Success: false,
-				Error:   "数据遍历失败",
-			})
-			return
-		}
-
-		if holders == nil {
-			holders = []Holder{}
-		}
-
-		sendJSONResponse(w, http.StatusOK, APIResponse{
-			Success: true,
-			Data:    holders,
-			Total:   total,
-			Page:    page,
-			Limit:   limit,
-		})
+		fmt.Printf("[%s] %s (%v): %s\n", result, check.Name, check.Duration.Round(time.Millisecond), check.Detail)
 	}
-}
-
-// fetchAndStoreData 从 RPC 获取数据并存入数据库
-func fetchAndStoreData(ctx context.Context, rpcURL string, db *sql.DB, httpClient *http.Client, mintAddress string) {
-	if mintAddress == "" {
-		logError("获取数据", fmt.Errorf("mint地址不能为空"))
-		return
-	}
-
-	requestPayload := RPCRequest{
-		Jsonrpc: "2.0",
-		ID:      "1",
-		Method:  "getProgramAccounts",
-		Params: []interface{}{
-			"TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb", // SPL Token Program ID
-			map[string]interface{}{
-				"encoding": "jsonParsed",
-				"filters": []map[string]interface{}{
-					{
-						"memcmp": map[string]interface{}{
-							"offset": 0, // `mint` 字段的偏移量是 0
-							"bytes":  mintAddress,
-						},
-					},
-				},
-			},
-		},
-	}
-
-	reqBodyBytes, err := json.Marshal(requestPayload)
-	if err != nil {
-		logError("序列化请求体", err)
-		return
-	}
-
-	logInfo("开始获取 SPL token 账户信息: %s", mintAddress)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", rpcURL, bytes.NewBuffer(reqBodyBytes))
-	if err != nil {
-		logError("创建HTTP请求", err)
-		return
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "solana-spl-holder/1.0")
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		logError("执行HTTP请求", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		logError("HTTP请求失败", fmt.Errorf("状态码: %d, 状态: %s", resp.StatusCode, resp.Status))
-		return
-	}
-
-	var rpcResponse RPCResponse
-	if decodeErr := json.NewDecoder(resp.Body).Decode(&rpcResponse); decodeErr != nil {
-		logError("解析JSON响应", err)
-		return
-	}
-
-	if rpcResponse.Error != nil {
-		logError("RPC调用失败", fmt.Errorf("代码: %d, 消息: %s", rpcResponse.Error.Code, rpcResponse.Error.Message))
-		return
-	}
-
-	if len(rpcResponse.Result) == 0 {
-		logInfo("mint地址 %s 未发现持有者记录", mintAddress)
-		return
-	}
-
-	// 使用事务批量更新
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		logError("开始数据库事务", err)
-		return
-	}
-	defer func() {
-		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
-			logError("回滚事务", err)
-		}
-	}()
-
-	upsertedCount := 0
-	skippedCount := 0
-	for _, item := range rpcResponse.Result {
-		if item.Account.Data.Parsed.Type != "account" {
-			skippedCount++
-			continue
-		}
-		if err := upsertHolderMariaDB(tx, mintAddress, item); err != nil {
-			logError(fmt.Sprintf("更新记录(pubkey: %s)", item.Pubkey), err)
-			skippedCount++
-			continue // 采集失败时跳过该条
-		}
-		upsertedCount++
-	}
-
-	if err := tx.Commit(); err != nil {
-		logError("提交数据库事务", err)
-		return
-	}
-	logInfo("mint地址 %s: 成功处理 %d 条记录，跳过 %d 条记录", mintAddress, upsertedCount, skippedCount)
-}
-
-func worker(ctx context.Context, rpcURL string, db *sql.DB) {
-	startTime := time.Now()
-	logInfo("[goroutine:%s] 数据采集任务开始", getGoroutineID())
-
-	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			MaxIdleConnsPerHost: 10,
-			IdleConnTimeout:     30 * time.Second,
-		},
-	}
-
-	mintAddresses, err := getAllMintAddresses(db)
-	if err != nil {
-		logError("获取mint地址列表", err)
-		return
-	}
-
-	if len(mintAddresses) == 0 {
-		logInfo("[goroutine:%s] spl表中没有mint地址，跳过本次采集", getGoroutineID())
-		return
-	}
-
-	logInfo("开始处理 %d 个mint地址", len(mintAddresses))
-	successCount := 0
-	for i, mintAddress := range mintAddresses {
-		select {
-		case <-ctx.Done():
-			logInfo("收到取消信号，停止数据采集")
-			return
-		default:
-			logDebug("处理第 %d/%d 个mint地址: %s", i+1, len(mintAddresses), mintAddress)
-			fetchAndStoreData(ctx, rpcURL, db, httpClient, mintAddress)
-			successCount++
-
-			// 添加小延迟避免过于频繁的请求
-			if i < len(mintAddresses)-1 {
-				time.Sleep(100 * time.Millisecond)
-			}
-		}
-	}
-
-	duration := time.Since(startTime)
-	logInfo("[goroutine:%s] 数据采集任务完成，处理了 %d/%d 个地址，耗时: %v", getGoroutineID(), successCount, len(mintAddresses), duration)
-}
-
-// startWorker 启动一个定时任务，周期性地获取数据
-func startWorker(ctx context.Context, interval time.Duration, rpcURL string, db *sql.DB) {
-	logInfo("启动定时数据采集任务，间隔: %v", interval)
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	// 立即执行一次
-	go worker(ctx, rpcURL, db)
-
-	for {
-		select {
-		case <-ticker.C:
-			// 在新的goroutine中执行worker，避免阻塞定时器
-			go worker(ctx, rpcURL, db)
-		case <-ctx.Done():
-			logInfo("数据采集定时任务正在关闭")
-			return
-		}
-	}
-}
-
-// 配置结构
-type Config struct {
-	RPCURL       string
-	DBConnStr    string
-	IntervalTime int
-	ListenPort   int
-}
-
-// 验证配置
-func (c *Config) Validate() error {
-	if c.RPCURL == "" {
-		return fmt.Errorf("RPC URL不能为空")
-	}
-	if c.DBConnStr == "" {
-		return fmt.Errorf("数据库连接字符串不能为空")
-	}
-	if c.IntervalTime < 10 {
-		return fmt.Errorf("采集间隔不能小于10秒")
-	}
-	if c.ListenPort < 1 || c.ListenPort > 65535 {
-		return fmt.Errorf("监听端口必须在1-65535范围内")
+	if failed {
+		fmt.Println("自检未通过")
+		os.Exit(1)
 	}
-	return nil
-}
-
-// 生成API文档HTML
-func getAPIDocumentation() string {
-	return `<!DOCTYPE html>
-<html lang="zh-CN">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Solana SPL Holder API 文档</title>
-    <style>
-        body { font-family: Arial, sans-serif; margin: 40px; line-height: 1.6; }
-        h1 { color: #333; border-bottom: 2px solid #007cba; padding-bottom: 10px; }
-        h2 { color: #007cba; margin-top: 30px; }
-        h3 { color: #555; }
-        .endpoint { background: #f4f4f4; padding: 15px; margin: 10px 0; border-radius: 5px; }
-        .method { font-weight: bold; color: white; padding: 3px 8px; border-radius: 3px; }
-        .get { background: #28a745; }
-        .put { background: #ffc107; color: black; }
-        .code { background: #f8f9fa; padding: 10px; border-radius: 3px; font-family: monospace; white-space: pre-wrap; }
-        .response { background: #e9ecef; padding: 10px; border-radius: 3px; margin-top: 10px; white-space: pre-wrap; font-family: monospace; }
-        table { border-collapse: collapse; width: 100%; margin: 10px 0; }
-        th, td { border: 1px solid #ddd; padding: 8px; text-align: left; }
-        th { background-color: #f2f2f2; }
-    </style>
-</head>
-<body>
-    <h1>🚀 Solana SPL Holder API 文档</h1>
-    
-    <h2>📋 概述</h2>
-    <p>这是一个用于管理 Solana SPL Token 持有者信息的 RESTful API 服务。</p>
-    <p><strong>基础URL:</strong> <code id="base-url"></code></p>
-    <script>
-        // 动态获取当前页面的基础URL
-        document.getElementById('base-url').textContent = window.location.origin;
-    </script>
-    
-    <h2>🔗 API 端点</h2>
-    
-    <h3>1. Holder 数据查询</h3>
-    
-    <div class="endpoint">
-        <h4><span class="method get">GET</span> /holders</h4>
-        <p><strong>描述:</strong> 查询 Token 持有者信息（支持分页、排序和多维度筛选）</p>
-        <p><strong>查询参数:</strong></p>
-        <table>
-            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
-            <tr><td>page</td><td>int</td><td>页码（默认1）</td><td>page=2</td></tr>
-            <tr><td>limit</td><td>int</td><td>每页数量（默认10，最大1000）</td><td>limit=50</td></tr>
-            <tr><td>owner</td><td>string</td><td>按持有者地址筛选</td><td>owner=13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf</td></tr>
-            <tr><td>mint_address</td><td>string</td><td>按 mint 地址筛选</td><td>mint_address=EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v</td></tr>
-            <tr><td>state</td><td>string</td><td>按状态筛选（uninitialized/initialized/frozen）</td><td>state=frozen</td></tr>
-            <tr><td>sort</td><td>string</td><td>排序字段（支持 ui_amount、pubkey、created_at，加 - 前缀为降序）</td><td>sort=-ui_amount</td></tr>
-        </table>
-        
-        <p><strong>排序说明:</strong></p>
-        <ul>
-            <li><code>sort=ui_amount</code> - 按金额升序排列</li>
-            <li><code>sort=-ui_amount</code> - 按金额降序排列</li>
-            <li><code>sort=pubkey</code> - 按公钥升序排列</li>
-            <li><code>sort=-pubkey</code> - 按公钥降序排列</li>
-            <li><code>sort=created_at</code> - 按创建时间升序排列</li>
-            <li><code>sort=-created_at</code> - 按创建时间降序排列</li>
-        </ul>
-        
-        <p><strong>查询示例:</strong></p>
-        <ul>
-            <li>基本查询: <code>/holders?page=1&limit=10</code></li>
-            <li>按状态过滤: <code>/holders?state=frozen</code></li>
-            <li>按Token过滤: <code>/holders?mint_address=EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v</code></li>
-            <li>金额排序: <code>/holders?sort=-ui_amount&limit=20</code></li>
-            <li>组合查询: <code>/holders?mint_address=EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v&state=frozen&sort=-ui_amount</code></li>
-            <li>多条件过滤: <code>/holders?state=initialized&sort=pubkey&page=2&limit=25</code></li>
-        </ul>
-        
-        <p><strong>响应示例:</strong></p>
-        <div class="response">{
-    "success": true,
-    "data": [
-        {
-            "id": 1,
-            "mint_address": "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
-            "pubkey": "13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf",
-            "state": "initialized",
-            "owner": "13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf",
-            "amount": "1000000",
-            "uiAmount": 1.0,
-            "decimals": 6,
-            "createdAt": "2024-01-01T12:00:00Z",
-            "updatedAt": "2024-01-01T12:00:00Z"
-        }
-    ],
-    "total": 100,
-    "page": 1,
-    "limit": 10
-}</div>
-    </div>
-    
-    <div class="endpoint">
-        <h4><span class="method put">PUT</span> /holders/{mint_address}/{pubkey}</h4>
-        <p><strong>描述:</strong> 更新指定 Holder 的状态</p>
-        <p><strong>路径参数:</strong></p>
-        <table>
-            <tr><th>参数</th><th>类型</th><th>描述</th></tr>
-            <tr><td>mint_address</td><td>string</td><td>Token 的 mint 地址</td></tr>
-            <tr><td>pubkey</td><td>string</td><td>Holder 的公钥地址</td></tr>
-        </table>
-        <p><strong>请求体:</strong></p>
-        <div class="code">{
-    "state": "initialized"
-}</div>
-        <p><strong>支持的状态值:</strong></p>
-        <ul>
-            <li><code>uninitialized</code> - 未初始化</li>
-<li><code>initialized</code> - 已初始化</li>
-<li><code>frozen</code> - 已冻结</li>
-        </ul>
-        <p><strong>示例:</strong> <code>/holders/Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg/13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf</code></p>
-        <p><strong>成功响应示例:</strong></p>
-        <div class="response">{
-    "success": true,
-    "data": {
-        "id": 1,
-        "mint_address": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg",
-        "pubkey": "13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf",
-        "state": "initialized",
-        "owner": "13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf",
-        "amount": "1000000",
-        "uiAmount": 1.0,
-        "decimals": 6,
-        "updatedAt": "2024-01-01T12:05:00Z"
-    }
-}</div>
-        <p><strong>错误响应示例:</strong></p>
-        <div class="response">{
-    "success": false,
-    "error": "state 必须是 uninitialized、initialized、frozen 之一"
-}</div>
-    </div>
-
-    <h3>3. 系统状态</h3>
-    
-    <div class="endpoint">
-        <h4><span class="method get">GET</span> /health</h4>
-        <p><strong>描述:</strong> 健康检查端点</p>
-        <p><strong>响应示例:</strong></p>
-        <div class="response">{
-    "success": true,
-    "data": {
-        "status": "healthy",
-        "version": "1.0.0",
-        "build_time": "2024-01-01 12:00:00 UTC",
-        "git_commit": "abc123",
-        "bin_name": "solana-spl-holder"
-    }
-}</div>
-    </div>
-    
-    <h2>📝 响应格式</h2>
-    <p>所有 API 响应都遵循统一的 JSON 格式：</p>
-    <div class="code">{
-    "success": boolean,     // 请求是否成功
-    "data": object,        // 响应数据（成功时）
-    "error": string,       // 错误信息（失败时）
-    "total": int,          // 总记录数（分页时）
-    "page": int,           // 当前页码（分页时）
-    "limit": int           // 每页数量（分页时）
-}</div>
-    
-    <h2>🔧 数据验证</h2>
-    <ul>
-        <li><strong>state:</strong> 必填，必须是 uninitialized、initialized、frozen 之一</li>
-    </ul>
-    
-    <h2>⚡ 特性</h2>
-    <ul>
-
-        <li>✅ 分页支持</li>
-        <li>✅ 多字段排序（金额、公钥、时间）</li>
-        <li>✅ 状态过滤（uninitialized/initialized/frozen）</li>
-        <li>✅ 地址过滤（mint_address、owner）</li>
-        <li>✅ 组合查询（多条件同时使用）</li>
-        <li>✅ 数据验证</li>
-        <li>✅ 错误处理</li>
-        <li>✅ 自动数据采集</li>
-        <li>✅ 健康检查</li>
-    </ul>
-    
-    <p style="margin-top: 40px; text-align: center; color: #666;">
-        <em>Solana SPL Holder API v1.0.0 - 构建于 Go</em>
-    </p>
-</body>
-</html>`
+	fmt.Println("自检通过")
 }
 
 func main() {
@@ -973,138 +71,187 @@ func main() {
 
 	rootCmd.PersistentFlags().String("rpc_url", "https://api.devnet.solana.com", "Solana节点RPC URL")
 	rootCmd.PersistentFlags().String("db_conn", "root:123456@tcp(localhost:3306)/rwa?charset=utf8mb4&parseTime=True&loc=Local", "MariaDB连接字符串")
+	rootCmd.PersistentFlags().String("db_read_conn", "", "MariaDB只读副本连接字符串，用于查询类API，为空时使用db_conn")
 	rootCmd.PersistentFlags().Int("interval_time", 300, "数据采集间隔时间(秒)")
+	rootCmd.PersistentFlags().String("interval", "", "数据采集间隔时长，如 5m、1h30m，设置时覆盖interval_time")
 	rootCmd.PersistentFlags().Int("listen_port", 8091, "HTTP服务监听端口")
+	rootCmd.PersistentFlags().String("tls_cert", "", "TLS证书文件(PEM)，与tls_key同时设置时以HTTPS提供服务并支持HTTP/2，为空时使用HTTP")
+	rootCmd.PersistentFlags().String("tls_key", "", "TLS私钥文件(PEM)")
+	rootCmd.PersistentFlags().Int("orphan_cleanup_interval", 0, "孤立Holder清理间隔时间(秒)，0表示不启用定时清理")
+	rootCmd.PersistentFlags().Int("history_retention_days", 0, "collection_status采集记录保留天数，每小时分批删除过期记录，0表示不清理")
+	rootCmd.PersistentFlags().String("rpc_encoding", splholder.RPCEncodingJSONParsed, "getProgramAccounts账户数据编码(jsonParsed/base64)，base64可显著减少大型mint的响应体积")
+	rootCmd.PersistentFlags().String("collect_states", "", "需要入库的账户状态，逗号分隔(uninitialized/initialized/frozen)，为空表示全部状态")
+	rootCmd.PersistentFlags().Int64("rpc_max_response_bytes", 1<<30, "getProgramAccounts响应体的最大字节数，超过时本次采集失败，0表示不限制")
+	rootCmd.PersistentFlags().Int("rpc_max_retries", 2, "RPC请求遇到网络错误或429/500/502/503/504时的最大重试次数(指数退避)，0表示不重试")
+	rootCmd.PersistentFlags().Int("rpc_probe_interval", 30, "RPC节点健康探测间隔(秒)，通过getSlot/getHealth检测节点是否落后，0表示不启用")
+	rootCmd.PersistentFlags().Bool("enforce_min_slot", false, "采集时以已见过的最高slot作为getProgramAccounts的minContextSlot，落后的RPC节点返回错误而不是旧快照")
+	rootCmd.PersistentFlags().Bool("snapshot_roots", false, "每个mint采集后计算持有者余额的Merkle根并保存到snapshot_root表，可通过 /spls/{mint}/snapshot-root 查询")
+	rootCmd.PersistentFlags().Bool("collect_native_balance", false, "每个mint采集后通过getMultipleAccounts分批查询持有者owner的SOL余额并保存到native_balance表，会增加RPC请求量")
+	rootCmd.PersistentFlags().Bool("profile_collection", false, "按mint输出RPC请求、JSON解析、数据库写入各阶段的耗时日志，用于判断瓶颈在RPC节点还是数据库")
+	rootCmd.PersistentFlags().String("archive_raw_responses", "", "原始getProgramAccounts响应的归档目录(gzip)，为空表示不归档")
+	rootCmd.PersistentFlags().Int("archive_retention_days", 7, "原始响应归档保留天数，0表示不按时间清理")
+	rootCmd.PersistentFlags().Int("archive_max_files", 0, "每个mint最多保留的原始响应归档文件数，0表示不限制")
+	rootCmd.PersistentFlags().String("collection_atomicity", splholder.CollectionAtomicityBestEffort, "账户写入失败时的处理方式：best-effort跳过失败的账户并提交其余记录，strict回滚该mint的整个事务")
+	rootCmd.PersistentFlags().Float64("max_failure_ratio", 1, "单个采集周期允许的mint失败比例(0-1)，超过时输出错误日志并视为采集周期失败，1表示不检查")
+	rootCmd.PersistentFlags().Int("max_concurrent_workers", 2, "同时运行的采集goroutine上限，上一个采集周期未结束时最多再启动的数量受此限制，0表示不限制")
+	rootCmd.PersistentFlags().Int("fetch_concurrency", 4, "一个采集周期内同时采集的mint数，每个mint使用独立的事务，0或1表示逐个采集")
+	rootCmd.PersistentFlags().Float64("holder_drop_alert_pct", 0, "持有者数量较上次成功采集下降超过该百分比时告警，0表示不启用；可被spl视图的holder_drop_alert_pct列按mint覆盖")
+	rootCmd.PersistentFlags().String("holder_drop_alert_webhook", "", "持有者数量告警的webhook URL，告警以JSON POST发送，为空时只输出日志")
+	rootCmd.PersistentFlags().Int("keep_top_n", 0, "每个mint只保留余额最大的前N个持有者并删除其余记录，0表示不限制；可被spl视图的keep_top_n列按mint覆盖")
+	rootCmd.PersistentFlags().String("min_ui_amount", "", "最小余额(按decimals换算后的十进制数，如0.01)，低于该值的零头账户不入库并删除已有记录，为空表示不过滤")
+	rootCmd.PersistentFlags().String("slow_request_threshold", "", "HTTP请求耗时达到该时长时输出慢请求日志，如 500ms、2s，为空表示不记录")
+	rootCmd.PersistentFlags().Int("cache_control_max_age", 0, "GET /holders 和 /spls 成功响应的 Cache-Control max-age(秒)，建议不超过采集间隔，0表示不设置")
+	rootCmd.PersistentFlags().String("price_feed_url", "", "价格源URL，返回 mint→美元价格 的JSON对象，配置后持有者查询支持include_value=true")
+	rootCmd.PersistentFlags().Int("price_cache_ttl", 60, "价格缓存时间(秒)")
+	rootCmd.PersistentFlags().String("event_broker_url", "", "每个mint采集成功后发布事件的消息队列地址，如 nats://localhost:4222，为空表示不发布")
+	rootCmd.PersistentFlags().String("event_topic", "splholder.collection", "采集事件发布的主题(NATS subject)")
+	rootCmd.PersistentFlags().String("excluded_label_categories", "program,burn,pool", "查询指定exclude_known=true时排除的地址标签分类，逗号分隔(exchange/program/burn/pool/other)，为空表示不支持exclude_known")
+	rootCmd.PersistentFlags().Int("max_offset", 0, "列表接口允许的最大分页偏移量((page-1)*limit)，超出返回400，0表示不限制")
+	rootCmd.PersistentFlags().Bool("read_only", false, "以只读维护模式启动：写操作返回503并暂停数据采集，可通过 POST /admin/read-only 切换")
+	rootCmd.PersistentFlags().Bool("skip_initial_collection", false, "跳过启动时的首次采集，等待第一个采集周期再开始")
+	rootCmd.PersistentFlags().Int("initial_collection_delay", 0, "首次采集延迟时间(秒)，0表示启动后立即采集")
+	rootCmd.PersistentFlags().Int("db_statement_timeout", 0, "数据库语句执行超时时间(秒)，由MariaDB服务端终止超时查询，0表示不限制")
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "doctor",
+		Short: "运行启动自检：检查配置、数据库、表结构、mint配置和RPC节点",
+		Run:   runDoctor,
+	})
 
 	if err := rootCmd.Execute(); err != nil {
 		errorLog.Fatalf("命令执行失败: %v", err)
 	}
 }
 
-func run(cmd *cobra.Command, args []string) {
-	// 获取命令行参数
+// 从命令行参数创建并验证配置
+func configFromFlags(cmd *cobra.Command) (*splholder.Config, error) {
 	rpcURL, _ := cmd.Flags().GetString("rpc_url")
 	dbConnStr, _ := cmd.Flags().GetString("db_conn")
+	dbReadConnStr, _ := cmd.Flags().GetString("db_read_conn")
 	interval, _ := cmd.Flags().GetInt("interval_time")
+	intervalStr, _ := cmd.Flags().GetString("interval")
+	slowRequestThresholdStr, _ := cmd.Flags().GetString("slow_request_threshold")
 	port, _ := cmd.Flags().GetInt("listen_port")
-
-	// 创建并验证配置
-	config := &Config{
-		RPCURL:       rpcURL,
-		DBConnStr:    dbConnStr,
-		IntervalTime: interval,
-		ListenPort:   port,
-	}
-
-	if err := config.Validate(); err != nil {
-		errorLog.Fatalf("配置验证失败: %v", err)
+	tlsCert, _ := cmd.Flags().GetString("tls_cert")
+	tlsKey, _ := cmd.Flags().GetString("tls_key")
+	orphanCleanupInterval, _ := cmd.Flags().GetInt("orphan_cleanup_interval")
+	historyRetentionDays, _ := cmd.Flags().GetInt("history_retention_days")
+	dbStatementTimeout, _ := cmd.Flags().GetInt("db_statement_timeout")
+	rpcEncoding, _ := cmd.Flags().GetString("rpc_encoding")
+	collectStatesStr, _ := cmd.Flags().GetString("collect_states")
+	excludedCategoriesStr, _ := cmd.Flags().GetString("excluded_label_categories")
+	skipInitialCollection, _ := cmd.Flags().GetBool("skip_initial_collection")
+	readOnly, _ := cmd.Flags().GetBool("read_only")
+	maxOffset, _ := cmd.Flags().GetInt("max_offset")
+	cacheControlMaxAge, _ := cmd.Flags().GetInt("cache_control_max_age")
+	priceFeedURL, _ := cmd.Flags().GetString("price_feed_url")
+	eventBrokerURL, _ := cmd.Flags().GetString("event_broker_url")
+	eventTopic, _ := cmd.Flags().GetString("event_topic")
+	priceCacheTTL, _ := cmd.Flags().GetInt("price_cache_ttl")
+	keepTopN, _ := cmd.Flags().GetInt("keep_top_n")
+	minUIAmount, _ := cmd.Flags().GetString("min_ui_amount")
+	maxFailureRatio, _ := cmd.Flags().GetFloat64("max_failure_ratio")
+	collectionAtomicity, _ := cmd.Flags().GetString("collection_atomicity")
+	holderDropAlertPct, _ := cmd.Flags().GetFloat64("holder_drop_alert_pct")
+	holderDropAlertWebhook, _ := cmd.Flags().GetString("holder_drop_alert_webhook")
+	maxConcurrentWorkers, _ := cmd.Flags().GetInt("max_concurrent_workers")
+	fetchConcurrency, _ := cmd.Flags().GetInt("fetch_concurrency")
+	archiveDir, _ := cmd.Flags().GetString("archive_raw_responses")
+	archiveRetentionDays, _ := cmd.Flags().GetInt("archive_retention_days")
+	archiveMaxFiles, _ := cmd.Flags().GetInt("archive_max_files")
+	rpcProbeInterval, _ := cmd.Flags().GetInt("rpc_probe_interval")
+	rpcMaxResponseBytes, _ := cmd.Flags().GetInt64("rpc_max_response_bytes")
+	rpcMaxRetries, _ := cmd.Flags().GetInt("rpc_max_retries")
+	enforceMinSlot, _ := cmd.Flags().GetBool("enforce_min_slot")
+	profileCollection, _ := cmd.Flags().GetBool("profile_collection")
+	snapshotRoots, _ := cmd.Flags().GetBool("snapshot_roots")
+	collectNativeBalance, _ := cmd.Flags().GetBool("collect_native_balance")
+	initialCollectionDelay, _ := cmd.Flags().GetInt("initial_collection_delay")
+
+	collectStates, err := splholder.ParseCollectStates(collectStatesStr)
+	if err != nil {
+		return nil, err
 	}
-
-	logInfo("=== Solana SPL 持有者查询工具启动 ===")
-	logInfo("RPC URL: %s", config.RPCURL)
-	logInfo("采集间隔: %d秒", config.IntervalTime)
-	logInfo("监听端口: %d", config.ListenPort)
-
-	db, err := initMariaDB(config.DBConnStr)
+	excludedCategories, err := splholder.ParseLabelCategories(excludedCategoriesStr)
 	if err != nil {
-		errorLog.Fatalf("数据库初始化失败: %v", err)
+		return nil, err
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			logError("关闭数据库连接", err)
-		}
-	}()
-
-	// 创建带取消功能的上下文，用于优雅关闭
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// 启动后台数据采集任务
-	go startWorker(ctx, time.Duration(config.IntervalTime)*time.Second, config.RPCURL, db)
-
-	// 设置HTTP服务器
-	mux := http.NewServeMux()
-
-	// 根路径 - API文档
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
-			http.NotFound(w, r)
-			return
-		}
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(getAPIDocumentation()))
-	})
-
-	mux.HandleFunc("/holders", apiHandlerMariaDB(db))
 
-	// Holder状态更新路由 (支持 /holders/{mint_address}/{pubkey})
-	mux.HandleFunc("/holders/", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPut:
-			handleUpdateHolderState(db)(w, r)
-		default:
-			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
-				Success: false,
-				Error:   "Method not allowed",
-			})
+	var intervalDuration time.Duration
+	if intervalStr != "" {
+		intervalDuration, err = time.ParseDuration(intervalStr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的采集间隔 %s: %v", intervalStr, err)
 		}
-	})
-
-
-
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		sendJSONResponse(w, http.StatusOK, APIResponse{
-			Success: true,
-			Data: map[string]string{
-				"status":     "healthy",
-				"version":    "1.0.0",
-				"build_time": BuildTime,
-				"git_commit": GitCommit,
-				"bin_name":   "solana-spl-holder",
-			},
-		})
-	})
-
-	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", config.ListenPort),
-		Handler:      mux,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
 	}
+	var slowRequestThreshold time.Duration
+	if slowRequestThresholdStr != "" {
+		slowRequestThreshold, err = time.ParseDuration(slowRequestThresholdStr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的慢请求阈值 %s: %v", slowRequestThresholdStr, err)
+		}
+	}
+
+	config := &splholder.Config{
+		RPCURL:                 rpcURL,
+		DBConnStr:              dbConnStr,
+		DBReadConnStr:          dbReadConnStr,
+		IntervalTime:           interval,
+		Interval:               intervalDuration,
+		ListenPort:             port,
+		TLSCertFile:            tlsCert,
+		TLSKeyFile:             tlsKey,
+		OrphanCleanupInterval:  orphanCleanupInterval,
+		HistoryRetentionDays:   historyRetentionDays,
+		DBStatementTimeout:     dbStatementTimeout,
+		RPCEncoding:            rpcEncoding,
+		CollectStates:          collectStates,
+		MaxOffset:              maxOffset,
+		ExcludedCategories:     excludedCategories,
+		CacheControlMaxAge:     cacheControlMaxAge,
+		SlowRequestThreshold:   slowRequestThreshold,
+		PriceFeedURL:           priceFeedURL,
+		PriceCacheTTL:          priceCacheTTL,
+		EventBrokerURL:         eventBrokerURL,
+		EventTopic:             eventTopic,
+		KeepTopN:               keepTopN,
+		MinUIAmount:            minUIAmount,
+		MaxFailureRatio:        maxFailureRatio,
+		CollectionAtomicity:    collectionAtomicity,
+		MaxConcurrentWorkers:   maxConcurrentWorkers,
+		FetchConcurrency:       fetchConcurrency,
+		HolderDropAlertPct:     holderDropAlertPct,
+		HolderDropAlertWebhook: holderDropAlertWebhook,
+		ArchiveDir:             archiveDir,
+		ArchiveRetentionDays:   archiveRetentionDays,
+		ArchiveMaxFiles:        archiveMaxFiles,
+		RPCProbeInterval:       rpcProbeInterval,
+		RPCMaxResponseBytes:    rpcMaxResponseBytes,
+		RPCMaxRetries:          rpcMaxRetries,
+		EnforceMinSlot:         enforceMinSlot,
+		ProfileCollection:      profileCollection,
+		SnapshotRoots:          snapshotRoots,
+		CollectNativeBalance:   collectNativeBalance,
+		ReadOnly:               readOnly,
+		SkipInitialCollection:  skipInitialCollection,
+		InitialCollectionDelay: initialCollectionDelay,
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
 
-	// 启动HTTP服务器
-	go func() {
-		logInfo("HTTP服务器启动，监听端口: %d", config.ListenPort)
-		logInfo("API端点: http://localhost:%d/holders", config.ListenPort)
-		logInfo("健康检查: http://localhost:%d/health", config.ListenPort)
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			errorLog.Fatalf("HTTP服务器启动失败: %v", err)
-		}
-	}()
-
-	// 监听中断信号以实现优雅关闭
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	logInfo("=== 服务启动完成，等待信号... ===")
-	<-quit // 阻塞直到接收到信号
-
-	logInfo("收到关闭信号，开始优雅关闭...")
-
-	// 触发worker和其他goroutine的关闭
-	cancel()
-
-	// 创建一个有超时的上下文用于关闭HTTP服务器
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		logError("HTTP服务器关闭", err)
-	} else {
-		logInfo("HTTP服务器已优雅关闭")
+func run(cmd *cobra.Command, args []string) {
+	// 创建并验证配置
+	config, err := configFromFlags(cmd)
+	if err != nil {
+		errorLog.Fatalf("配置验证失败: %v", err)
 	}
 
-	logInfo("=== 应用已成功关闭 ===")
+	splholder.BuildTime = BuildTime
+	splholder.GitCommit = GitCommit
+	if err := splholder.Run(context.Background(), config); err != nil {
+		errorLog.Fatalf("服务运行失败: %v", err)
+	}
 }
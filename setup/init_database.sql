@@ -11,26 +11,32 @@ CREATE TABLE IF NOT EXISTS dummy (
     id INT AUTO_INCREMENT PRIMARY KEY,
     symbol VARCHAR(255) NOT NULL,
     mint VARCHAR(255) NOT NULL,
+    program_id VARCHAR(64) NULL,  -- Token程序ID，NULL表示Token-2022
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     UNIQUE KEY unique_mint (mint)
 ) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;
 
--- 插入初始 Dummy 数据
-INSERT INTO dummy (symbol, mint) VALUES
-('TSLAx', 'XsDoVfqeBukxuZHWhdvWHBhgEHjGNst4MLodqsJHzoB'),
-('AAPLx', 'XsbEhLAtcf6HdfpFZ5xEMdqW8nfAvcsP5bdudRLJzJp'),
-('NVDAx', 'Xsc9qvGR1efVDFGLrVsmkzv3qi45LTBjeUKSPmx9qEh'),
-('AMZNx', 'Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg'),
-('COINx', 'Xs7ZdzSHLU9ftNJsii5fCeJhoRWSC32SQGzGQtePxNu'),
-('HOODx', 'XsvNBAYkrDRNhA7wPHQfX3ZUXZyZLdnCQDfHZ56bzpg'),
-('GOOGLx', 'XsCPL9dNWBMvFtTmwcCA5v3xWPSMEBCszbQdiLLq6aN');
+-- 旧版本创建的 dummy 表没有 program_id 列
+ALTER TABLE dummy ADD COLUMN IF NOT EXISTS program_id VARCHAR(64) NULL AFTER mint;
+
+-- 插入初始 Dummy 数据，xStocks 均为 Token-2022 代币
+INSERT INTO dummy (symbol, mint, program_id) VALUES
+('TSLAx', 'XsDoVfqeBukxuZHWhdvWHBhgEHjGNst4MLodqsJHzoB', 'TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb'),
+('AAPLx', 'XsbEhLAtcf6HdfpFZ5xEMdqW8nfAvcsP5bdudRLJzJp', 'TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb'),
+('NVDAx', 'Xsc9qvGR1efVDFGLrVsmkzv3qi45LTBjeUKSPmx9qEh', 'TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb'),
+('AMZNx', 'Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg', 'TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb'),
+('COINx', 'Xs7ZdzSHLU9ftNJsii5fCeJhoRWSC32SQGzGQtePxNu', 'TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb'),
+('HOODx', 'XsvNBAYkrDRNhA7wPHQfX3ZUXZyZLdnCQDfHZ56bzpg', 'TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb'),
+('GOOGLx', 'XsCPL9dNWBMvFtTmwcCA5v3xWPSMEBCszbQdiLLq6aN', 'TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb')
+ON DUPLICATE KEY UPDATE program_id = COALESCE(program_id, VALUES(program_id));
 
 -- 创建 SPL 视图，系统集成需要从stable_coin表中获取symbol和mint_address
 CREATE OR REPLACE VIEW spl AS
 SELECT
   `symbol`,
-  `mint`
+  `mint`,
+  `program_id`
 FROM dummy;
 
 -- 创建 SPL Token Holder 表
@@ -53,12 +59,97 @@ CREATE TABLE IF NOT EXISTS holder (
     ui_amount DECIMAL(38,6) NOT NULL,
     ui_amount_string VARCHAR(255) NOT NULL,
     
+    -- 授权信息（未授权时 delegate 为 NULL，delegated_amount 为 0）
+    delegate VARCHAR(255) NULL,
+    delegated_amount DECIMAL(38,0) NOT NULL DEFAULT 0,
+    
+    -- Token-2022 账户信息
+    close_authority VARCHAR(255) NULL,
+    extensions JSON NULL,  -- RPC jsonParsed 返回的扩展列表，没有扩展时为 NULL
+    
     -- 时间戳
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    first_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,  -- 首次采集到该账户的时间，之后不再更新
     
     -- 索引
     UNIQUE KEY unique_holder_mint_pubkey (mint, pubkey),
     INDEX idx_mint (mint),
-    INDEX idx_pubkey (pubkey)
-) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;
\ No newline at end of file
+    INDEX idx_pubkey (pubkey),
+    INDEX idx_mint_first_seen (mint, first_seen_at),
+    INDEX idx_owner_mint (owner, mint),
+    INDEX idx_mint_ui_amount (mint, ui_amount)  -- 按mint查询并按余额排序
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;
+
+-- 旧版本创建的 holder 表没有授权信息列
+ALTER TABLE holder
+  ADD COLUMN IF NOT EXISTS delegate VARCHAR(255) NULL AFTER ui_amount_string,
+  ADD COLUMN IF NOT EXISTS delegated_amount DECIMAL(38,0) NOT NULL DEFAULT 0 AFTER delegate;
+
+-- 旧版本创建的 holder 表没有 Token-2022 账户信息列
+ALTER TABLE holder
+  ADD COLUMN IF NOT EXISTS close_authority VARCHAR(255) NULL AFTER delegated_amount,
+  ADD COLUMN IF NOT EXISTS extensions JSON NULL AFTER close_authority;
+
+-- 旧版本创建的 holder 表没有 first_seen_at 列，新增时已有记录取执行时间，回填为创建时间
+ALTER TABLE holder
+  ADD COLUMN IF NOT EXISTS first_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ADD INDEX IF NOT EXISTS idx_mint_first_seen (mint, first_seen_at);
+UPDATE holder SET first_seen_at = created_at WHERE created_at IS NOT NULL AND first_seen_at > created_at;
+
+-- 创建地址标签表（可选），用于标注交易所、程序、销毁地址等已知地址
+CREATE TABLE IF NOT EXISTS address_label (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    address VARCHAR(255) NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    category VARCHAR(50) NOT NULL,  -- exchange / program / burn / pool / other
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_address (address),
+    INDEX idx_category (category)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;
+
+-- 创建关注列表表（可选），address 为钱包地址(owner)或token账户地址(pubkey)
+CREATE TABLE IF NOT EXISTS watchlist (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    address VARCHAR(255) NOT NULL,
+    note VARCHAR(255) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_address (address)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;
+
+-- 创建Merkle快照根表（可选），启用 --snapshot_roots 时每个mint每次采集写入一条记录
+CREATE TABLE IF NOT EXISTS snapshot_root (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    mint VARCHAR(255) NOT NULL,
+    root CHAR(64) NOT NULL,  -- 十六进制编码的SHA-256 Merkle根
+    leaf_count BIGINT NOT NULL,
+    slot BIGINT UNSIGNED NULL,  -- 采集时RPC响应对应的slot
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_mint_created_at (mint, created_at)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;
+
+-- 创建SOL余额表（可选），启用 --collect_native_balance 时每次采集后更新持有者owner的SOL余额
+CREATE TABLE IF NOT EXISTS native_balance (
+    owner VARCHAR(255) NOT NULL PRIMARY KEY,
+    lamports BIGINT UNSIGNED NOT NULL,
+    slot BIGINT UNSIGNED NULL,  -- 查询时RPC节点数据对应的slot
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;
+
+-- 创建采集状态表，每个mint每次采集写入一条记录
+CREATE TABLE IF NOT EXISTS collection_status (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    mint VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,  -- success / failed
+    holder_count BIGINT NOT NULL DEFAULT 0,  -- 采集成功后余额大于0的持有者数
+    upserted_count INT NOT NULL DEFAULT 0,
+    skipped_count INT NOT NULL DEFAULT 0,
+    error_message TEXT NULL,
+    rpc_lagging TINYINT(1) NOT NULL DEFAULT 0,  -- 采集期间检测到RPC节点落后
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME NOT NULL,
+    INDEX idx_mint_started (mint, started_at),
+    INDEX idx_started (started_at)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;
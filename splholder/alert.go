@@ -0,0 +1,149 @@
+package splholder
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HolderDropAlert 持有者数量骤降告警，发送到webhook的JSON内容
+type HolderDropAlert struct {
+	Mint          string    `json:"mint"`
+	PreviousCount int64     `json:"previous_count"` // 上一次成功采集的持有者数
+	CurrentCount  int64     `json:"current_count"`
+	DropPct       float64   `json:"drop_pct"`      // 下降百分比
+	ThresholdPct  float64   `json:"threshold_pct"` // 触发告警的阈值百分比
+	AlertedAt     time.Time `json:"alerted_at"`
+}
+
+// HolderDropAlerter 比较相邻两次成功采集的持有者数，下降超过阈值时输出告警日志并可选地调用webhook。
+// 告警带有回滞：触发后该mint进入告警状态，持有者数回升到告警前数量的 (1 - 阈值/2) 以上才解除，
+// 避免数量在阈值附近波动时反复告警
+type HolderDropAlerter struct {
+	thresholdPct float64 // 全局阈值，0表示只对spl视图中配置了 holder_drop_alert_pct 的mint告警
+	webhookURL   string
+	httpClient   *http.Client
+
+	mu       sync.Mutex
+	alerting map[string]int64 // 处于告警状态的mint → 告警前的持有者数
+}
+
+// NewHolderDropAlerter 创建持有者数量骤降告警器，webhookURL 为空时只输出日志
+func NewHolderDropAlerter(thresholdPct float64, webhookURL string) *HolderDropAlerter {
+	return &HolderDropAlerter{
+		thresholdPct: thresholdPct,
+		webhookURL:   webhookURL,
+		httpClient:   &http.Client{Timeout: 5 * time.Second},
+		alerting:     make(map[string]int64),
+	}
+}
+
+// enabled 判断该mint是否需要检查持有者数量下降
+func (a *HolderDropAlerter) enabled(override sql.NullFloat64) bool {
+	if a == nil {
+		return false
+	}
+	if override.Valid {
+		return override.Float64 > 0
+	}
+	return a.thresholdPct > 0
+}
+
+// Check 根据本次采集的持有者数判断是否需要告警，override 为spl视图中该mint的阈值。
+// previous 为上一次成功采集的持有者数，没有历史记录时传 0。返回是否发出了新的告警
+func (a *HolderDropAlerter) Check(ctx context.Context, mint string, previous, current int64, override sql.NullFloat64) bool {
+	if a == nil {
+		return false
+	}
+	threshold := a.thresholdPct
+	if override.Valid {
+		threshold = override.Float64
+	}
+
+	a.mu.Lock()
+	if threshold <= 0 {
+		delete(a.alerting, mint)
+		a.mu.Unlock()
+		return false
+	}
+	if baseline, ok := a.alerting[mint]; ok {
+		if float64(current) >= float64(baseline)*(1-threshold/200) {
+			delete(a.alerting, mint)
+			logInfo("mint地址 %s 持有者数量已回升到 %d（告警前 %d），解除告警", mint, current, baseline)
+		}
+		a.mu.Unlock()
+		return false
+	}
+	if previous <= 0 || current >= previous {
+		a.mu.Unlock()
+		return false
+	}
+	dropPct := float64(previous-current) / float64(previous) * 100
+	if dropPct <= threshold {
+		a.mu.Unlock()
+		return false
+	}
+	a.alerting[mint] = previous
+	a.mu.Unlock()
+
+	alert := HolderDropAlert{
+		Mint:          mint,
+		PreviousCount: previous,
+		CurrentCount:  current,
+		DropPct:       dropPct,
+		ThresholdPct:  threshold,
+		AlertedAt:     time.Now(),
+	}
+	logError("持有者数量告警", fmt.Errorf("mint地址 %s 持有者数量从 %d 降至 %d，下降 %.1f%%，超过阈值 %.1f%%",
+		mint, previous, current, dropPct, threshold))
+	if a.webhookURL != "" {
+		if err := a.send(ctx, alert); err != nil {
+			logError("发送持有者数量告警", err)
+		}
+	}
+	return true
+}
+
+func (a *HolderDropAlerter) send(ctx context.Context, alert HolderDropAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return wrapError("序列化告警内容", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return wrapError("创建告警请求", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "solana-spl-holder/1.0")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return wrapError("请求告警webhook", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("告警webhook请求失败, 状态码: %d, 状态: %s", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+// 查询mint上一次成功采集的持有者数，没有成功记录时返回 0
+func lastSuccessfulHolderCount(db *sql.DB, mintAddress string) (int64, error) {
+	var count int64
+	err := db.QueryRow(`SELECT holder_count FROM collection_status
+		WHERE mint = ? AND status = ? ORDER BY started_at DESC, id DESC LIMIT 1`,
+		mintAddress, collectionStatusSuccess).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, wrapError("查询上次采集的持有者数量", err)
+	}
+	return count, nil
+}
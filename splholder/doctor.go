@@ -0,0 +1,100 @@
+package splholder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DoctorCheck 一项自检的结果
+type DoctorCheck struct {
+	Name     string
+	Critical bool // 失败时doctor以非零状态退出
+	Passed   bool
+	Detail   string
+	Duration time.Duration
+}
+
+// RunDoctorCheck 执行一项自检并计时
+func RunDoctorCheck(name string, critical bool, fn func() (string, error)) DoctorCheck {
+	start := time.Now()
+	detail, err := fn()
+	check := DoctorCheck{Name: name, Critical: critical, Passed: err == nil, Detail: detail, Duration: time.Since(start)}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+// RunDoctorChecks 依次检查数据库、表结构、mint配置和RPC节点，
+// 任一关键检查失败时后续依赖它的检查不再执行。config 需已通过校验
+func RunDoctorChecks(config *Config) []DoctorCheck {
+	var checks []DoctorCheck
+	var db *sql.DB
+	checks = append(checks, RunDoctorCheck("数据库连接", true, func() (string, error) {
+		var err error
+		db, err = openMariaDB(config.DBConnStr, config.DBStatementTimeout)
+		return "连接成功", err
+	}))
+	if db != nil {
+		defer db.Close()
+		schemaOK := true
+		for _, obj := range schemaObjects {
+			obj := obj
+			check := RunDoctorCheck(obj.displayName(), obj.Required, func() (string, error) {
+				exists, err := checkSchemaObject(db, obj)
+				if err != nil {
+					return "", err
+				}
+				if !exists {
+					return "", fmt.Errorf("%s", obj.MissingMessage)
+				}
+				return "存在", nil
+			})
+			if !check.Passed && obj.Required {
+				schemaOK = false
+			}
+			checks = append(checks, check)
+		}
+		if schemaOK {
+			checks = append(checks, RunDoctorCheck("mint配置", false, func() (string, error) {
+				mints, err := getAllMintAddresses(db)
+				if err != nil {
+					return "", err
+				}
+				if len(mints) == 0 {
+					return "", fmt.Errorf("spl视图中没有mint地址，不会采集任何数据")
+				}
+				return fmt.Sprintf("共 %d 个mint地址", len(mints)), nil
+			}))
+		}
+	}
+	if config.DBReadConnStr != "" {
+		checks = append(checks, RunDoctorCheck("只读副本连接", true, func() (string, error) {
+			replica, err := openMariaDB(config.DBReadConnStr, config.DBStatementTimeout)
+			if err != nil {
+				return "", err
+			}
+			replica.Close()
+			return "连接成功", nil
+		}))
+	}
+
+	checks = append(checks, RunDoctorCheck("RPC节点", true, func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		client := NewSolanaRPCClient(config.RPCURL, &http.Client{Timeout: 10 * time.Second})
+		var slot uint64
+		if err := client.Call(ctx, "getSlot", nil, &slot); err != nil {
+			return "", err
+		}
+		var health string
+		if err := client.Call(ctx, "getHealth", nil, &health); err != nil {
+			return "", fmt.Errorf("slot %d，但节点不健康: %v", slot, err)
+		}
+		return fmt.Sprintf("slot %d，health %s", slot, health), nil
+	}))
+	return checks
+}
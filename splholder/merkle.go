@@ -0,0 +1,328 @@
+package splholder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Merkle树的哈希规则（校验方需按相同规则计算）：
+//   - 叶子为余额大于0的 (pubkey, amount)，按pubkey解码后的32字节升序排列
+//   - 叶子哈希 = SHA256(0x00 || pubkey(32字节) || amount(8字节大端))
+//   - 内部节点哈希 = SHA256(0x01 || 左子节点 || 右子节点)
+//   - 某一层节点数为奇数时，最后一个节点原样进入上一层
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// SnapshotRoot 对应数据库中的 'snapshot_root' 表，记录某次采集后持有者余额的Merkle根
+type SnapshotRoot struct {
+	Mint      string    `json:"mint"`
+	Root      string    `json:"root"`       // 十六进制编码的Merkle根
+	LeafCount int       `json:"leaf_count"` // 余额大于0的账户数
+	Slot      *uint64   `json:"slot"`       // 采集时RPC响应对应的slot，未知时为null
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SnapshotProofStep Merkle证明中的一个兄弟节点，position 为该节点相对于当前节点的位置(left/right)
+type SnapshotProofStep struct {
+	Hash     string `json:"hash"`
+	Position string `json:"position"`
+}
+
+// SnapshotProof 基于当前入库余额生成的Merkle证明
+type SnapshotProof struct {
+	Mint      string              `json:"mint"`
+	Pubkey    string              `json:"pubkey"`
+	Amount    string              `json:"amount"`
+	LeafIndex int                 `json:"leaf_index"`
+	Leaf      string              `json:"leaf"`
+	Proof     []SnapshotProofStep `json:"proof"`
+	Root      string              `json:"root"` // 由当前入库余额计算的根
+	LeafCount int                 `json:"leaf_count"`
+	// 最近一次保存的快照根，为null表示尚未保存过；采集后余额有变化时与 root 不一致
+	Snapshot        *SnapshotRoot `json:"snapshot"`
+	MatchesSnapshot bool          `json:"matches_snapshot"`
+}
+
+// snapshotLeaf 参与Merkle树计算的一个账户
+type snapshotLeaf struct {
+	pubkey string
+	key    []byte // pubkey解码后的32字节
+	amount uint64
+}
+
+func merkleLeafHash(key []byte, amount uint64) []byte {
+	buf := make([]byte, 0, 1+len(key)+8)
+	buf = append(buf, merkleLeafPrefix)
+	buf = append(buf, key...)
+	buf = binary.BigEndian.AppendUint64(buf, amount)
+	sum := sha256.Sum256(buf)
+	return sum[:]
+}
+
+func merkleNodeHash(left, right []byte) []byte {
+	buf := make([]byte, 0, 1+len(left)+len(right))
+	buf = append(buf, merkleNodePrefix)
+	buf = append(buf, left...)
+	buf = append(buf, right...)
+	sum := sha256.Sum256(buf)
+	return sum[:]
+}
+
+// merkleLevels 自底向上计算每一层的节点，levels[0] 为叶子，最后一层只有根。leaves 不能为空
+func merkleLevels(leaves [][]byte) [][][]byte {
+	levels := [][][]byte{leaves}
+	for current := leaves; len(current) > 1; {
+		next := make([][]byte, 0, (len(current)+1)/2)
+		for i := 0; i < len(current); i += 2 {
+			if i+1 == len(current) {
+				next = append(next, current[i])
+				continue
+			}
+			next = append(next, merkleNodeHash(current[i], current[i+1]))
+		}
+		levels = append(levels, next)
+		current = next
+	}
+	return levels
+}
+
+// merkleProof 返回第 index 个叶子到根路径上的兄弟节点，被原样提升的层没有兄弟节点
+func merkleProof(levels [][][]byte, index int) []SnapshotProofStep {
+	proof := []SnapshotProofStep{}
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			position := "right"
+			if sibling < index {
+				position = "left"
+			}
+			proof = append(proof, SnapshotProofStep{Hash: hex.EncodeToString(level[sibling]), Position: position})
+		}
+		index /= 2
+	}
+	return proof
+}
+
+// 读取mint余额大于0的账户并按pubkey字节序排列。holder表的排序规则不区分大小写，不能依赖SQL排序
+func loadSnapshotLeaves(ctx context.Context, db *sql.DB, mintAddress string) ([]snapshotLeaf, error) {
+	rows, err := db.QueryContext(ctx, "SELECT pubkey, amount FROM holder WHERE mint = ? AND amount > 0", mintAddress)
+	if err != nil {
+		return nil, wrapError("查询持有者余额", err)
+	}
+	defer rows.Close()
+
+	var leaves []snapshotLeaf
+	for rows.Next() {
+		var leaf snapshotLeaf
+		var amountStr string
+		if err := rows.Scan(&leaf.pubkey, &amountStr); err != nil {
+			return nil, wrapError("扫描数据行", err)
+		}
+		if err := validateSolanaAddress(leaf.pubkey); err != nil {
+			return nil, err
+		}
+		leaf.key, _ = base58Decode(leaf.pubkey)
+		amount, ok := new(big.Int).SetString(amountStr, 10)
+		if !ok || !amount.IsUint64() {
+			return nil, fmt.Errorf("无效的amount(pubkey: %s): %s", leaf.pubkey, amountStr)
+		}
+		leaf.amount = amount.Uint64()
+		leaves = append(leaves, leaf)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历查询结果", err)
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i].key, leaves[j].key) < 0
+	})
+	return leaves, nil
+}
+
+func snapshotLeafHashes(leaves []snapshotLeaf) [][]byte {
+	hashes := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		hashes[i] = merkleLeafHash(leaf.key, leaf.amount)
+	}
+	return hashes
+}
+
+// storeSnapshotRoot 按当前入库余额计算mint的Merkle根并写入 snapshot_root 表，slot 为0表示未知。
+// 没有余额大于0的账户时不写入，返回nil
+func storeSnapshotRoot(ctx context.Context, db *sql.DB, mintAddress string, slot uint64) (*SnapshotRoot, error) {
+	leaves, err := loadSnapshotLeaves(ctx, db, mintAddress)
+	if err != nil {
+		return nil, err
+	}
+	if len(leaves) == 0 {
+		return nil, nil
+	}
+	levels := merkleLevels(snapshotLeafHashes(leaves))
+	snapshot := &SnapshotRoot{
+		Mint:      mintAddress,
+		Root:      hex.EncodeToString(levels[len(levels)-1][0]),
+		LeafCount: len(leaves),
+		CreatedAt: time.Now(),
+	}
+	if slot > 0 {
+		snapshot.Slot = &slot
+	}
+	_, err = db.ExecContext(ctx, "INSERT INTO snapshot_root (mint, root, leaf_count, slot, created_at) VALUES (?, ?, ?, ?, ?)",
+		snapshot.Mint, snapshot.Root, snapshot.LeafCount, snapshot.Slot, snapshot.CreatedAt)
+	if err != nil {
+		return nil, wrapError("保存Merkle快照根", err)
+	}
+	return snapshot, nil
+}
+
+// 查询mint最近一次保存的Merkle快照根
+func latestSnapshotRoot(db *sql.DB, mintAddress string) (*SnapshotRoot, error) {
+	snapshot := &SnapshotRoot{Mint: mintAddress}
+	var slot sql.NullInt64
+	err := db.QueryRow(`SELECT root, leaf_count, slot, created_at FROM snapshot_root
+		WHERE mint = ? ORDER BY created_at DESC, id DESC LIMIT 1`, mintAddress).
+		Scan(&snapshot.Root, &snapshot.LeafCount, &slot, &snapshot.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("mint %s 的Merkle快照根不存在", mintAddress)
+	}
+	if err != nil {
+		return nil, wrapError("查询Merkle快照根", err)
+	}
+	if slot.Valid {
+		s := uint64(slot.Int64)
+		snapshot.Slot = &s
+	}
+	return snapshot, nil
+}
+
+// 按当前入库余额为pubkey生成Merkle证明，并与最近一次保存的快照根比较
+func buildSnapshotProof(ctx context.Context, db *sql.DB, mintAddress, pubkey string) (*SnapshotProof, error) {
+	leaves, err := loadSnapshotLeaves(ctx, db, mintAddress)
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, leaf := range leaves {
+		if leaf.pubkey == pubkey {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("mint %s 中余额大于0的账户 %s 不存在", mintAddress, pubkey)
+	}
+
+	levels := merkleLevels(snapshotLeafHashes(leaves))
+	proof := &SnapshotProof{
+		Mint:      mintAddress,
+		Pubkey:    pubkey,
+		Amount:    fmt.Sprintf("%d", leaves[index].amount),
+		LeafIndex: index,
+		Leaf:      hex.EncodeToString(levels[0][index]),
+		Proof:     merkleProof(levels, index),
+		Root:      hex.EncodeToString(levels[len(levels)-1][0]),
+		LeafCount: len(leaves),
+	}
+	snapshot, err := latestSnapshotRoot(db, mintAddress)
+	if err != nil && !strings.Contains(err.Error(), "不存在") {
+		return nil, err
+	}
+	if snapshot != nil {
+		proof.Snapshot = snapshot
+		proof.MatchesSnapshot = snapshot.Root == proof.Root
+	}
+	return proof, nil
+}
+
+// 处理 GET /spls/{mint_address}/snapshot-root 请求，返回最近一次保存的Merkle快照根
+func handleSnapshotRoot(w http.ResponseWriter, r *http.Request, db *sql.DB, mintAddress string) {
+	if r.Method != http.MethodGet {
+		sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+			Success: false,
+			Error:   "只支持GET方法",
+		})
+		return
+	}
+
+	var snapshot *SnapshotRoot
+	err := retryRead(r.Context(), func() (err error) {
+		snapshot, err = latestSnapshotRoot(db, mintAddress)
+		return err
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "不存在") {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		logError("查询Merkle快照根", err)
+		sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "查询数据失败",
+		})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    snapshot,
+	})
+}
+
+// 处理 GET /spls/{mint_address}/snapshot-proof?pubkey= 请求，返回该账户的Merkle证明
+func handleSnapshotProof(w http.ResponseWriter, r *http.Request, db *sql.DB, mintAddress string) {
+	if r.Method != http.MethodGet {
+		sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+			Success: false,
+			Error:   "只支持GET方法",
+		})
+		return
+	}
+	pubkey := r.URL.Query().Get("pubkey")
+	if err := validateSolanaAddress(pubkey); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "pubkey: " + err.Error(),
+		})
+		return
+	}
+
+	var proof *SnapshotProof
+	err := retryRead(r.Context(), func() (err error) {
+		proof, err = buildSnapshotProof(r.Context(), db, mintAddress, pubkey)
+		return err
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "不存在") {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		logError("生成Merkle证明", err)
+		sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "查询数据失败",
+		})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    proof,
+	})
+}
@@ -0,0 +1,134 @@
+package splholder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MessagePack 响应的媒体类型
+const (
+	contentTypeMsgpack  = "application/msgpack"
+	contentTypeXMsgpack = "application/x-msgpack"
+)
+
+// 与JSON输出不同的类型按其JSON表示编码，保证 MessagePack 响应与JSON响应的结构一致：
+// 时间为RFC3339字符串，BigAmount 为十进制字符串，json.RawMessage 为解析后的值而不是二进制
+func init() {
+	msgpack.Register(time.Time{}, func(enc *msgpack.Encoder, v reflect.Value) error {
+		return enc.EncodeString(v.Interface().(time.Time).Format(time.RFC3339Nano))
+	}, nil)
+	msgpack.Register(BigAmount{}, func(enc *msgpack.Encoder, v reflect.Value) error {
+		return enc.EncodeString(v.Interface().(BigAmount).String())
+	}, nil)
+	msgpack.Register(json.RawMessage(nil), func(enc *msgpack.Encoder, v reflect.Value) error {
+		raw := v.Interface().(json.RawMessage)
+		if raw == nil {
+			return enc.EncodeNil()
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var generic interface{}
+		if err := decoder.Decode(&generic); err != nil {
+			return wrapError("解析JSON字段", err)
+		}
+		return enc.Encode(jsonNumbersToValues(generic))
+	}, nil)
+}
+
+// jsonNumbersToValues 把 json.Number 转换为整数或浮点数，其他值原样返回
+func jsonNumbersToValues(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, _ := x.Float64()
+		return f
+	case []interface{}:
+		for i := range x {
+			x[i] = jsonNumbersToValues(x[i])
+		}
+	case map[string]interface{}:
+		for k := range x {
+			x[k] = jsonNumbersToValues(x[k])
+		}
+	}
+	return v
+}
+
+// encodeMsgpack 把v编码为MessagePack并写入w。字段名、omitempty 和 "-" 遵循 json 标签，
+// 整数使用最短的编码，map按键排序。顶层值也经过 EncodeValue，使 time.Time 同样按上面注册的方式编码
+func encodeMsgpack(w io.Writer, v interface{}) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+	encoder.UseCompactInts(true)
+	encoder.SetSortMapKeys(true)
+	if v == nil {
+		return encoder.EncodeNil()
+	}
+	return encoder.EncodeValue(reflect.ValueOf(v))
+}
+
+// 判断请求的 Accept 头是否要求 MessagePack（q=0 表示拒绝）
+func acceptsMsgpack(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			if mediaType != contentTypeMsgpack && mediaType != contentTypeXMsgpack {
+				continue
+			}
+			if q := strings.TrimSpace(params["q"]); q == "0" || q == "0.0" || q == "0.00" || q == "0.000" {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// msgpackResponseWriter 标记该请求的响应需要以 MessagePack 编码
+type msgpackResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Unwrap 供 http.ResponseController 访问底层的 ResponseWriter
+func (w *msgpackResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// 沿 Unwrap 链查找 msgpackResponseWriter，判断是否以 MessagePack 编码响应
+func wantsMsgpack(w http.ResponseWriter) bool {
+	for {
+		switch v := w.(type) {
+		case *msgpackResponseWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// withMsgpack 请求带有 Accept: application/msgpack 时，sendJSONResponse 以 MessagePack
+// 编码相同结构的响应，默认仍为JSON
+func withMsgpack(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if acceptsMsgpack(r) {
+			w = &msgpackResponseWriter{ResponseWriter: w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
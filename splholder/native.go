@@ -0,0 +1,142 @@
+package splholder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// getMultipleAccounts 单次请求最多查询100个地址
+const nativeBalanceBatchSize = 100
+
+// SOL 的精度，1 SOL = 10^9 lamports
+const nativeSOLDecimals = 9
+
+// NativeBalance 对应数据库中的 'native_balance' 表，记录持有者owner地址的SOL余额
+type NativeBalance struct {
+	Owner     string    `json:"owner"`
+	Lamports  uint64    `json:"lamports"`
+	SOL       string    `json:"sol"`  // 按9位精度换算的十进制字符串
+	Slot      *uint64   `json:"slot"` // 查询时RPC节点数据对应的slot，未知时为null
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// storeNativeBalances 分批查询mint中余额大于0的持有者owner的SOL余额并写入 native_balance 表，返回更新的地址数。
+// 同一owner持有多个mint时每个mint采集后都会刷新一次
+func storeNativeBalances(ctx context.Context, db *sql.DB, client *SolanaRPCClient, mintAddress string) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT owner FROM holder WHERE mint = ? AND amount > 0", mintAddress)
+	if err != nil {
+		return 0, wrapError("查询持有者owner", err)
+	}
+	var owners []string
+	for rows.Next() {
+		var owner string
+		if err := rows.Scan(&owner); err != nil {
+			rows.Close()
+			return 0, wrapError("扫描数据行", err)
+		}
+		owners = append(owners, owner)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, wrapError("遍历查询结果", err)
+	}
+
+	updated := 0
+	for start := 0; start < len(owners); start += nativeBalanceBatchSize {
+		batch := owners[start:min(start+nativeBalanceBatchSize, len(owners))]
+		lamports, slot, err := client.GetMultipleAccountLamports(ctx, batch)
+		if err != nil {
+			return updated, wrapError("查询SOL余额", err)
+		}
+
+		placeholders := make([]string, len(batch))
+		args := make([]interface{}, 0, len(batch)*3)
+		for i, owner := range batch {
+			placeholders[i] = "(?, ?, ?)"
+			args = append(args, owner, lamports[i], slot)
+		}
+		_, err = db.ExecContext(ctx, "INSERT INTO native_balance (owner, lamports, slot) VALUES "+strings.Join(placeholders, ", ")+
+			" ON DUPLICATE KEY UPDATE lamports = VALUES(lamports), slot = VALUES(slot)", args...)
+		if err != nil {
+			return updated, wrapError("保存SOL余额", err)
+		}
+		updated += len(batch)
+	}
+	return updated, nil
+}
+
+// 查询owner最近一次采集的SOL余额
+func getNativeBalance(db *sql.DB, owner string) (*NativeBalance, error) {
+	balance := &NativeBalance{Owner: owner}
+	var slot sql.NullInt64
+	err := db.QueryRow("SELECT lamports, slot, updated_at FROM native_balance WHERE owner = ?", owner).
+		Scan(&balance.Lamports, &slot, &balance.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("地址 %s 的SOL余额不存在", owner)
+	}
+	if err != nil {
+		return nil, wrapError("查询SOL余额", err)
+	}
+	balance.SOL = formatUIAmount(new(big.Int).SetUint64(balance.Lamports), nativeSOLDecimals)
+	if slot.Valid && slot.Int64 > 0 {
+		s := uint64(slot.Int64)
+		balance.Slot = &s
+	}
+	return balance, nil
+}
+
+// 处理 GET /owners/{owner}/native-balance 请求，返回采集时保存的owner的SOL余额
+func handleNativeBalance(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/owners/"), "/native-balance")
+		if !ok || owner == "" || strings.Contains(owner, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+		if err := validateSolanaAddress(owner); err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "owner: " + err.Error(),
+			})
+			return
+		}
+
+		var balance *NativeBalance
+		err := retryRead(r.Context(), func() (err error) {
+			balance, err = getNativeBalance(db, owner)
+			return err
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), "不存在") {
+				sendJSONResponse(w, http.StatusNotFound, APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+			logError("查询SOL余额", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    balance,
+		})
+	}
+}
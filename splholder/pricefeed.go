@@ -0,0 +1,97 @@
+package splholder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PriceFeed 从外部价格源获取 mint→美元价格，并在TTL内缓存。
+// 价格源返回JSON对象，键为mint地址，值为价格，例如 {"Xs3e...": 1.23}
+type PriceFeed struct {
+	url        string
+	ttl        time.Duration
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	prices    map[string]float64
+	fetchedAt time.Time
+}
+
+// NewPriceFeed 创建价格源，url 为空时返回 nil 表示未启用
+func NewPriceFeed(url string, ttl time.Duration) *PriceFeed {
+	if url == "" {
+		return nil
+	}
+	return &PriceFeed{
+		url:        url,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Prices 返回缓存的价格表，缓存过期时重新获取。获取失败但存在旧缓存时继续使用旧价格，
+// 避免价格源短暂不可用影响查询
+func (f *PriceFeed) Prices(ctx context.Context) (map[string]float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.prices != nil && time.Since(f.fetchedAt) < f.ttl {
+		return f.prices, nil
+	}
+
+	prices, err := f.fetch(ctx)
+	if err != nil {
+		if f.prices != nil {
+			logError("刷新价格，继续使用缓存的价格", err)
+			return f.prices, nil
+		}
+		return nil, err
+	}
+	f.prices = prices
+	f.fetchedAt = time.Now()
+	return prices, nil
+}
+
+func (f *PriceFeed) fetch(ctx context.Context) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, wrapError("创建价格请求", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "solana-spl-holder/1.0")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, wrapError("请求价格源", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("价格源请求失败, 状态码: %d, 状态: %s", resp.StatusCode, resp.Status)
+	}
+
+	var prices map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&prices); err != nil {
+		return nil, wrapError("解析价格源响应", err)
+	}
+	if prices == nil {
+		prices = map[string]float64{}
+	}
+	return prices, nil
+}
+
+// annotateHolderValues 按 ui_amount * price 计算每个持有者的美元价值，没有价格的mint不设置
+func annotateHolderValues(holders []Holder, prices map[string]float64) {
+	for i := range holders {
+		price, ok := prices[holders[i].Mint]
+		if !ok {
+			continue
+		}
+		value := holders[i].UIAmount * price
+		holders[i].ValueUSD = &value
+	}
+}
@@ -0,0 +1,127 @@
+package splholder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// CollectionEventMintUpdated mint采集成功提交后发布的事件类型
+const CollectionEventMintUpdated = "mint.updated"
+
+// CollectionEvent 一个mint采集成功提交后发布的事件，下游系统据此刷新数据而不需要轮询API
+type CollectionEvent struct {
+	Type        string    `json:"type"`
+	RunID       string    `json:"run_id"`
+	Mint        string    `json:"mint"`
+	HolderCount *int64    `json:"holder_count"` // 提交后余额大于0的持有者数，记录采集状态失败时为null
+	Upserted    int       `json:"upserted"`
+	Skipped     int       `json:"skipped"`
+	Filtered    int       `json:"filtered"`
+	Slot        *uint64   `json:"slot"` // 该mint的RPC响应对应的slot，未知时为null
+	DurationMS  int64     `json:"duration_ms"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// CollectionPublisher 采集事件发布接口，实现需要支持并发调用。
+// 发布失败只记录日志，不影响采集结果，下游需要容忍丢失的事件
+type CollectionPublisher interface {
+	Publish(ctx context.Context, event CollectionEvent) error
+	Close() error
+}
+
+// noopPublisher 未配置消息队列时使用，不发布任何事件
+type noopPublisher struct{}
+
+func (noopPublisher) Publish(context.Context, CollectionEvent) error { return nil }
+func (noopPublisher) Close() error                                   { return nil }
+
+// NewCollectionPublisher 根据 brokerURL 的协议创建发布器：为空时不发布，nats:// 发布到NATS的 topic 主题
+func NewCollectionPublisher(brokerURL, topic string) (CollectionPublisher, error) {
+	if brokerURL == "" {
+		return noopPublisher{}, nil
+	}
+	u, err := url.Parse(brokerURL)
+	if err != nil {
+		return nil, wrapError("解析event_broker_url", err)
+	}
+	if topic == "" || strings.ContainsAny(topic, " \t\r\n") {
+		return nil, fmt.Errorf("event_topic不能为空或包含空白字符: %q", topic)
+	}
+	switch u.Scheme {
+	case "nats":
+		if u.Host == "" {
+			return nil, fmt.Errorf("event_broker_url缺少主机地址: %s", brokerURL)
+		}
+		return &natsPublisher{url: brokerURL, subject: topic}, nil
+	default:
+		return nil, fmt.Errorf("不支持的event_broker_url协议: %s，可选 nats", u.Scheme)
+	}
+}
+
+// natsPublisher 使用官方 nats.go 客户端发布事件。首次发布时建立连接，之后由客户端负责断线重连；
+// 首次连接失败时在下一次发布时重试
+type natsPublisher struct {
+	url     string // 用户名和密码从URL中读取，未指定端口时客户端使用4222
+	subject string
+
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+// natsTimeout 连接和每次发布等待服务端确认的超时时间
+const natsTimeout = 5 * time.Second
+
+// Publish 发布一个事件，并通过 Flush 确认服务端已处理（权限不足等错误会在此返回）
+func (p *natsPublisher) Publish(ctx context.Context, event CollectionEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return wrapError("序列化采集事件", err)
+	}
+
+	p.mu.Lock()
+	if p.conn == nil {
+		conn, err := nats.Connect(p.url, nats.Name("solana-spl-holder"), nats.Timeout(natsTimeout), nats.MaxReconnects(-1))
+		if err != nil {
+			p.mu.Unlock()
+			return wrapError("连接NATS", err)
+		}
+		p.conn = conn
+	}
+	conn := p.conn
+	p.mu.Unlock()
+
+	if err := conn.Publish(p.subject, payload); err != nil {
+		return wrapError("发送NATS消息", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, natsTimeout)
+	defer cancel()
+	if err := conn.FlushWithContext(ctx); err != nil {
+		return wrapError("等待NATS确认", err)
+	}
+	return nil
+}
+
+// Close 关闭与NATS的连接
+func (p *natsPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	return nil
+}
+
+// publishCollectionEvent 发布一个采集事件，失败只记录日志
+func publishCollectionEvent(ctx context.Context, publisher CollectionPublisher, event CollectionEvent) {
+	if err := publisher.Publish(ctx, event); err != nil {
+		logError(fmt.Sprintf("[run:%s] 发布mint地址 %s 的采集事件", event.RunID, event.Mint), err)
+	}
+}
@@ -0,0 +1,321 @@
+package splholder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// SPL Token 程序ID：经典 Token Program 和 Token-2022 (Token Extensions)
+const (
+	tokenProgramID     = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	token2022ProgramID = "TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb"
+)
+
+// Error 实现 error 接口，调用方可以通过 errors.As 取得RPC错误码
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("RPC调用失败, 代码: %d, 消息: %s", e.Code, e.Message)
+}
+
+// SolanaRPCClient Solana JSON-RPC 客户端，统一处理请求构造、请求头、HTTP状态码和RPC错误
+type SolanaRPCClient struct {
+	url        string
+	httpClient *http.Client
+	maxRetries int
+}
+
+// NewSolanaRPCClient 创建RPC客户端，超时和连接池由 httpClient 控制
+func NewSolanaRPCClient(url string, httpClient *http.Client) *SolanaRPCClient {
+	return &SolanaRPCClient{url: url, httpClient: httpClient}
+}
+
+// SetMaxRetries 设置网络错误和可重试状态码(429/500/502/503/504)的最大重试次数，默认为0不重试
+func (c *SolanaRPCClient) SetMaxRetries(n int) {
+	c.maxRetries = n
+}
+
+// rpcRetryBaseDelay RPC请求第一次重试前的等待时间，之后每次翻倍。测试中会调小
+var rpcRetryBaseDelay = 500 * time.Millisecond
+
+// isRetryableRPCStatus 限流和节点/网关的临时错误可以重试，其他状态码重试也不会成功
+func isRetryableRPCStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// rpcRetryDelay 第 attempt 次重试(从0开始)的等待时间：基础延迟按指数增长，再在 [d/2, d] 内随机抖动，
+// 避免多个worker同时重试
+func rpcRetryDelay(attempt int) time.Duration {
+	d := rpcRetryBaseDelay << attempt
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// doRPCWithRetry 发送请求，遇到网络错误或可重试的状态码时按指数退避重试，最多重试 maxRetries 次。
+// req 需要支持 GetBody（http.NewRequest 使用 bytes.Buffer 等请求体时会自动设置）以便重发。
+// 返回的响应状态码不一定是200，由调用方检查
+func doRPCWithRetry(ctx context.Context, client *http.Client, req *http.Request, maxRetries int) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("请求体不支持重发")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, wrapError("重建请求体", err)
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := client.Do(req)
+		var reason string
+		switch {
+		case err != nil:
+			// 上下文取消或超时不是节点的问题，重试没有意义
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			reason = err.Error()
+		case isRetryableRPCStatus(resp.StatusCode):
+			reason = fmt.Sprintf("状态码: %d", resp.StatusCode)
+		default:
+			return resp, nil
+		}
+		if attempt >= maxRetries {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		delay := rpcRetryDelay(attempt)
+		logInfo("RPC请求失败(%s)，%v后进行第%d次重试", reason, delay, attempt+1)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// ProgramAccountsOptions getProgramAccounts 的可选参数
+type ProgramAccountsOptions struct {
+	Encoding       string // jsonParsed 或 base64
+	WithContext    bool   // 要求节点返回数据对应的slot
+	MinContextSlot uint64 // 大于0时要求节点的数据不早于该slot，需要同时设置 WithContext
+	// 大于0时限制响应体的字节数，超过时返回 errRPCResponseTooLarge，避免异常节点的超大响应耗尽内存
+	MaxResponseBytes int64
+	// 不为nil时在解析前包装响应体，例如把原始响应同时写入归档；只对状态码为200的响应调用
+	WrapBody func(io.Reader) io.Reader
+	// 不为nil时记录请求和解析两个阶段的耗时
+	Timing *ProgramAccountsTiming
+}
+
+// ProgramAccountsTiming getProgramAccounts 各阶段耗时。响应体是边读边解析的，
+// Decode 包含读取响应体的网络时间
+type ProgramAccountsTiming struct {
+	Request time.Duration // 发送请求到收到响应头
+	Decode  time.Duration // 读取并解析响应体
+}
+
+// ProgramAccountsResult getProgramAccounts 的解析结果
+type ProgramAccountsResult struct {
+	Accounts    []ResultItem
+	ContextSlot uint64 // WithContext 时节点返回数据对应的slot
+}
+
+// post 发送一个JSON-RPC请求并检查HTTP状态码，调用方负责关闭响应体
+func (c *SolanaRPCClient) post(ctx context.Context, method string, params []interface{}) (*http.Response, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	reqBodyBytes, err := json.Marshal(RPCRequest{Jsonrpc: "2.0", ID: "1", Method: method, Params: params})
+	if err != nil {
+		return nil, wrapError("序列化请求体", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewBuffer(reqBodyBytes))
+	if err != nil {
+		return nil, wrapError("创建HTTP请求", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "solana-spl-holder/1.0")
+
+	resp, err := doRPCWithRetry(ctx, c.httpClient, req, c.maxRetries)
+	if err != nil {
+		return nil, wrapError("执行HTTP请求", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP请求失败, 状态码: %d, 状态: %s", resp.StatusCode, resp.Status)
+	}
+	return resp, nil
+}
+
+// Call 调用一个JSON-RPC方法并把result解析到out中，RPC返回错误时错误类型为 *RPCError
+func (c *SolanaRPCClient) Call(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	resp, err := c.post(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rpcResponse struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResponse); err != nil {
+		return wrapError("解析JSON响应", err)
+	}
+	if rpcResponse.Error != nil {
+		return rpcResponse.Error
+	}
+	if err := json.Unmarshal(rpcResponse.Result, out); err != nil {
+		return wrapError("解析RPC结果", err)
+	}
+	return nil
+}
+
+// GetProgramAccounts 调用 getProgramAccounts 并流式解析返回的账户。RPC返回错误时错误类型为 *RPCError，
+// result 为 null 时返回 errNullRPCResult
+func (c *SolanaRPCClient) GetProgramAccounts(ctx context.Context, programID string, filters []map[string]interface{}, opts ProgramAccountsOptions) (*ProgramAccountsResult, error) {
+	config := map[string]interface{}{
+		"encoding": opts.Encoding,
+		"filters":  filters,
+	}
+	if opts.WithContext {
+		config["withContext"] = true
+		if opts.MinContextSlot > 0 {
+			config["minContextSlot"] = opts.MinContextSlot
+		}
+	}
+
+	var stageStart time.Time
+	if opts.Timing != nil {
+		stageStart = time.Now()
+	}
+	resp, err := c.post(ctx, "getProgramAccounts", []interface{}{programID, config})
+	if opts.Timing != nil {
+		opts.Timing.Request = time.Since(stageStart)
+		stageStart = time.Now()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body io.Reader = resp.Body
+	if opts.MaxResponseBytes > 0 {
+		body = &limitedBody{r: io.LimitReader(resp.Body, opts.MaxResponseBytes+1), limit: opts.MaxResponseBytes}
+	}
+	if opts.WrapBody != nil {
+		body = opts.WrapBody(body)
+	}
+	decoded, err := decodeProgramAccountsResponse(body, opts.WithContext)
+	if opts.Timing != nil {
+		opts.Timing.Decode = time.Since(stageStart)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errRPCResponseDecode, err)
+	}
+	if decoded.Error != nil {
+		return nil, decoded.Error
+	}
+	if !decoded.HasResult {
+		return nil, errNullRPCResult
+	}
+	return &ProgramAccountsResult{Accounts: decoded.Result, ContextSlot: decoded.ContextSlot}, nil
+}
+
+// errRPCResponseDecode getProgramAccounts 响应不是完整的JSON（截断、格式错误或超过长度限制），
+// 与RPC返回的错误区分，采集周期据此统计响应无法解析的mint数
+var errRPCResponseDecode = errors.New("解析JSON响应")
+
+// errRPCResponseTooLarge RPC响应体超过 ProgramAccountsOptions.MaxResponseBytes
+var errRPCResponseTooLarge = errors.New("RPC响应体超过最大长度")
+
+// limitedBody 最多返回 limit 字节，底层多读到1个字节即返回 errRPCResponseTooLarge，
+// 与 io.LimitReader 单独使用时返回 EOF 不同，调用方可以区分截断和超限
+type limitedBody struct {
+	r     io.Reader // io.LimitReader(body, limit+1)
+	read  int64
+	limit int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		// 超出部分不交给调用方，解码器不会把截断的数据当作完整响应
+		n -= int(l.read - l.limit)
+		return n, fmt.Errorf("%w(%d字节)，可通过 rpc_max_response_bytes 调整", errRPCResponseTooLarge, l.limit)
+	}
+	return n, err
+}
+
+// GetAccountData 以 base64 编码调用 getAccountInfo，返回账户的原始数据，账户不存在时返回 nil
+func (c *SolanaRPCClient) GetAccountData(ctx context.Context, address string) ([]byte, error) {
+	var result struct {
+		Value *struct {
+			Data Data `json:"data"`
+		} `json:"value"`
+	}
+	err := c.Call(ctx, "getAccountInfo", []interface{}{
+		address,
+		map[string]interface{}{"encoding": RPCEncodingBase64},
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Value == nil {
+		return nil, nil
+	}
+	return result.Value.Data.Raw, nil
+}
+
+// GetMultipleAccountLamports 调用 getMultipleAccounts 查询一批地址的SOL余额(lamports)，不返回账户数据。
+// 结果与 addresses 一一对应，账户不存在时为0；同时返回节点数据对应的slot
+func (c *SolanaRPCClient) GetMultipleAccountLamports(ctx context.Context, addresses []string) ([]uint64, uint64, error) {
+	var result struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value []*struct {
+			Lamports uint64 `json:"lamports"`
+		} `json:"value"`
+	}
+	err := c.Call(ctx, "getMultipleAccounts", []interface{}{
+		addresses,
+		map[string]interface{}{
+			"encoding":  RPCEncodingBase64,
+			"dataSlice": map[string]int{"offset": 0, "length": 0},
+		},
+	}, &result)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(result.Value) != len(addresses) {
+		return nil, 0, fmt.Errorf("getMultipleAccounts返回 %d 个账户，请求了 %d 个", len(result.Value), len(addresses))
+	}
+	lamports := make([]uint64, len(addresses))
+	for i, account := range result.Value {
+		if account != nil {
+			lamports[i] = account.Lamports
+		}
+	}
+	return lamports, result.Context.Slot, nil
+}
@@ -0,0 +1,391 @@
+package splholder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Server 持有数据库连接和采集状态，提供HTTP处理器和后台采集任务
+type Server struct {
+	config   *Config
+	store    *Storage
+	registry *CollectionRegistry
+	monitor  *RPCHealthMonitor
+	workers  *WorkerLimiter
+	requests *RequestTracker
+	prices   *PriceFeed
+	readOnly *ReadOnlyMode
+	alerts   *HolderDropAlerter
+	pruner   *HistoryPruner
+	events   CollectionPublisher
+	handler  http.Handler
+}
+
+// NewServer 校验配置、连接数据库并检查表结构，返回可以嵌入到其他程序中的服务。
+// 使用完毕后需要调用 Close 关闭数据库连接
+func NewServer(config *Config) (*Server, error) {
+	if err := config.Validate(); err != nil {
+		return nil, wrapError("配置验证", err)
+	}
+
+	db, err := initMariaDB(config.DBConnStr, config.DBStatementTimeout)
+	if err != nil {
+		return nil, wrapError("数据库初始化", err)
+	}
+	var replica *sql.DB
+	if config.DBReadConnStr != "" {
+		logInfo("使用只读副本处理查询请求")
+		replica, err = initMariaDB(config.DBReadConnStr, config.DBStatementTimeout)
+		if err != nil {
+			db.Close()
+			return nil, wrapError("只读副本初始化", err)
+		}
+	}
+	return NewServerWithDB(config, db, replica), nil
+}
+
+// NewServerWithDB 使用已打开的数据库连接创建服务，不做配置校验和表结构检查。
+// replica 为 nil 时查询也使用 primary，主要用于测试和自行管理连接池的调用方
+func NewServerWithDB(config *Config, primary, replica *sql.DB) *Server {
+	s := &Server{
+		config:   config,
+		store:    newStorage(primary, replica),
+		registry: newCollectionRegistry(),
+		monitor:  &RPCHealthMonitor{},
+		workers:  NewWorkerLimiter(config.MaxConcurrentWorkers),
+		requests: NewRequestTracker(config.SlowRequestThreshold),
+		readOnly: NewReadOnlyMode(config.ReadOnly),
+		prices:   NewPriceFeed(config.PriceFeedURL, time.Duration(config.PriceCacheTTL)*time.Second),
+		alerts:   NewHolderDropAlerter(config.HolderDropAlertPct, config.HolderDropAlertWebhook),
+		pruner:   NewHistoryPruner(config.HistoryRetentionDays),
+		events:   noopPublisher{},
+	}
+	if publisher, err := NewCollectionPublisher(config.EventBrokerURL, config.EventTopic); err != nil {
+		logError("创建采集事件发布器，不发布采集事件", err)
+	} else {
+		s.events = publisher
+	}
+	s.handler = s.routes()
+	return s
+}
+
+// SetPublisher 替换采集事件发布器，嵌入方可以接入 NATS 以外的消息队列。需要在 RunCollector 之前调用，
+// 原发布器会被关闭
+func (s *Server) SetPublisher(publisher CollectionPublisher) {
+	if publisher == nil {
+		publisher = noopPublisher{}
+	}
+	s.events.Close()
+	s.events = publisher
+}
+
+// Handler 返回包含全部API路由的HTTP处理器
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Close 关闭采集事件发布器和数据库连接
+func (s *Server) Close() error {
+	s.events.Close()
+	return s.store.Close()
+}
+
+// RunCollector 运行定时采集任务，以及按配置启用的RPC节点探测和孤立记录清理任务，
+// 阻塞直到ctx被取消
+func (s *Server) RunCollector(ctx context.Context) {
+	// 启动RPC节点健康探测任务（可选）
+	if s.config.RPCProbeInterval > 0 {
+		go startRPCProbe(ctx, s.config, s.monitor)
+	}
+
+	// 启动孤立Holder定时清理任务（可选）
+	if s.config.OrphanCleanupInterval > 0 {
+		go startOrphanCleanup(ctx, time.Duration(s.config.OrphanCleanupInterval)*time.Second, s.store.Writer())
+	}
+
+	// 启动采集记录过期清理任务（可选）
+	if s.config.HistoryRetentionDays > 0 {
+		go startHistoryPrune(ctx, s.store.Writer(), s.pruner, s.readOnly)
+	}
+
+	startWorker(ctx, s.config, s.store.Writer(), s.registry, s.monitor, s.workers, s.readOnly, s.alerts, s.events)
+}
+
+// CollectOnce 立即执行一个采集周期并等待完成，适合由 CronJob 等外部调度器驱动的场景。
+// 失败的mint比例超过 Config.MaxFailureRatio，或活跃的采集数量已达 Config.MaxConcurrentWorkers 时返回错误
+func (s *Server) CollectOnce(ctx context.Context) error {
+	if !s.workers.TryAcquire() {
+		return fmt.Errorf("活跃的采集goroutine已达上限 %d", s.workers.Limit())
+	}
+	defer s.workers.Release()
+	return worker(ctx, s.config, s.store.Writer(), s.registry, s.monitor, s.readOnly, s.alerts, s.events)
+}
+
+// 注册全部API路由
+func (s *Server) routes() http.Handler {
+	config, store, registry := s.config, s.store, s.registry
+	mux := http.NewServeMux()
+
+	// 根路径 - API文档
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(getAPIDocumentation()))
+	})
+
+	mux.HandleFunc("/holders", withCacheControl(config.CacheControlMaxAge, apiHandlerMariaDB(store.Reader(), config.MaxOffset, registry, s.prices, config.ExcludedCategories)))
+	mux.HandleFunc("/holders/tiers", handleHolderTiers(store.Reader(), config.ExcludedCategories))
+	mux.HandleFunc("/holders/histogram", handleHolderHistogram(store.Reader(), config.ExcludedCategories))
+	mux.HandleFunc("/holders/mints", handleHolderMints(store.Reader()))
+	mux.HandleFunc("/holders/stats", handleHolderStats(store.Reader(), config.ExcludedCategories))
+	mux.HandleFunc("/holders/new", handleNewHolders(store.Reader(), config.MaxOffset))
+	mux.HandleFunc("/holders/stream", handleHolderStream(store.Reader()))
+	mux.HandleFunc("/holders/overlap", handleHolderOverlap(store.Reader(), config.MaxOffset))
+	mux.HandleFunc("/owners/multi-holders", handleMultiTokenOwners(store.Reader(), config.MaxOffset, config.ExcludedCategories))
+	mux.HandleFunc("/owners/", handleNativeBalance(store.Reader()))
+
+	// 导出全部Token配置
+	mux.HandleFunc("/spls/export", handleSPLExport(store.Reader()))
+
+	// 指定Token的持有者列表 (支持 /spls/{mint_address}/holders)
+	mux.HandleFunc("/spls/", withCacheControl(config.CacheControlMaxAge, handleSPLHolders(store.Reader(), config.MaxOffset, registry, s.prices, config.ExcludedCategories)))
+
+	// Holder状态更新路由 (支持 /holders/{mint_address}/{pubkey})
+	mux.HandleFunc("/holders/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			handleUpdateHolderState(store.Writer(), registry)(w, r)
+		default:
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+		}
+	})
+
+	// 地址标签管理路由 (支持 /labels 与 /labels/{address})
+	mux.HandleFunc("/labels", handleAddressLabels(store, config.MaxOffset, registry))
+	mux.HandleFunc("/labels/", handleAddressLabel(store, registry))
+
+	// 关注列表路由 (支持 /watchlist、/watchlist/{address} 与 /watchlist/balances)
+	mux.HandleFunc("/watchlist", handleWatchlist(store, config.MaxOffset))
+	mux.HandleFunc("/watchlist/", handleWatchlistEntry(store))
+	mux.HandleFunc("/watchlist/balances", handleWatchlistBalances(store.Reader()))
+
+	// 管理接口 - 清理孤立Holder记录
+	mux.HandleFunc("/admin/cleanup-orphans", handleCleanupOrphans(store.Writer(), registry))
+
+	// 管理接口 - 检查并回填ui_amount_string
+	mux.HandleFunc("/admin/recompute-ui-amount", handleCheckUIAmount(store.Writer()))
+
+	// 管理接口 - 中止指定mint正在进行的采集
+	mux.HandleFunc("/admin/abort-collection", handleAbortCollection(registry))
+
+	// 管理接口 - 查询和切换只读维护模式
+	mux.HandleFunc("/admin/read-only", handleReadOnlyMode(s.readOnly))
+
+	// 数据一致性核对
+	mux.HandleFunc("/admin/verify", handleVerifyHolders(config, store.Reader()))
+
+	// 采集状态路由 (支持 /status/collections 与 /status/collections/{mint_address}/trend)
+	mux.HandleFunc("/status", handleStatus(config, s.monitor, s.pruner))
+	mux.HandleFunc("/status/collections", handleCollectionStatus(store.Reader()))
+	mux.HandleFunc("/status/collections/", handleHolderCountTrend(store.Reader()))
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"status":         "healthy",
+				"version":        "1.0.0",
+				"build_time":     BuildTime,
+				"git_commit":     GitCommit,
+				"bin_name":       "solana-spl-holder",
+				"active_workers": s.workers.Active(),
+				"read_only":      s.readOnly.Enabled(),
+			},
+		})
+	})
+
+	// 采集goroutine和HTTP请求指标 (Prometheus文本格式)
+	mux.HandleFunc("/metrics", handleMetrics(s.workers, s.requests))
+
+	return withRequestTracking(s.requests, withPrettyJSON(withMsgpack(withReadOnly(s.readOnly, mux))))
+}
+
+// 输出启动配置
+func logConfig(config *Config) {
+	logInfo("RPC URL: %s", config.RPCURL)
+	logInfo("RPC编码: %s", config.RPCEncoding)
+	logInfo("RPC最大重试次数: %d", config.RPCMaxRetries)
+	if len(config.CollectStates) > 0 {
+		logInfo("采集账户状态: %s", strings.Join(config.CollectStates, ", "))
+	}
+	logInfo("采集间隔: %v", config.CollectionInterval())
+	logInfo("监听端口: %d", config.ListenPort)
+	if config.TLSCertFile != "" {
+		logInfo("启用TLS(HTTP/2): 证书 %s", config.TLSCertFile)
+	}
+	if config.OrphanCleanupInterval > 0 {
+		logInfo("孤立Holder清理间隔: %d秒", config.OrphanCleanupInterval)
+	}
+	if config.HistoryRetentionDays > 0 {
+		logInfo("采集记录保留天数: %d", config.HistoryRetentionDays)
+	}
+	if config.SlowRequestThreshold > 0 {
+		logInfo("慢请求日志阈值: %v", config.SlowRequestThreshold)
+	}
+	if config.CollectionAtomicity == CollectionAtomicityStrict {
+		logInfo("采集事务模式: strict，任意账户写入失败时回滚该mint的整个事务")
+	}
+	if config.EnforceMinSlot {
+		logInfo("启用minContextSlot检查，拒绝落后RPC节点返回的旧快照")
+	}
+	if config.ProfileCollection {
+		logInfo("启用采集分阶段耗时日志")
+	}
+	if config.SnapshotRoots {
+		logInfo("启用Merkle快照根，采集后保存到snapshot_root表")
+	}
+	if config.CollectNativeBalance {
+		logInfo("启用SOL余额采集，采集后保存持有者owner的SOL余额到native_balance表")
+	}
+	if len(config.ExcludedCategories) > 0 {
+		logInfo("exclude_known=true 时排除的地址标签分类: %s", strings.Join(config.ExcludedCategories, ", "))
+	}
+	if config.ArchiveDir != "" {
+		logInfo("原始RPC响应归档目录: %s (保留%d天, 每个mint最多%d个文件, 0表示不限制)", config.ArchiveDir, config.ArchiveRetentionDays, config.ArchiveMaxFiles)
+	}
+	if config.ReadOnly {
+		logInfo("以只读维护模式启动：拒绝写操作并暂停数据采集")
+	}
+	if config.PriceFeedURL != "" {
+		logInfo("价格源: %s (缓存%d秒)", config.PriceFeedURL, config.PriceCacheTTL)
+	}
+	if config.EventBrokerURL != "" {
+		brokerURL := config.EventBrokerURL
+		if u, err := url.Parse(brokerURL); err == nil {
+			brokerURL = u.Redacted()
+		}
+		logInfo("采集事件发布到: %s (主题: %s)", brokerURL, config.EventTopic)
+	}
+	if config.MaxConcurrentWorkers > 0 {
+		logInfo("同时运行的采集goroutine上限: %d", config.MaxConcurrentWorkers)
+	}
+	logInfo("同时采集的mint数: %d", max(config.FetchConcurrency, 1))
+	if config.KeepTopN > 0 {
+		logInfo("每个mint只保留前 %d 名持有者", config.KeepTopN)
+	}
+	if config.MinUIAmount != "" {
+		logInfo("余额低于 %s 的账户不入库", config.MinUIAmount)
+	}
+	if config.HolderDropAlertPct > 0 {
+		logInfo("持有者数量下降超过 %.1f%% 时告警", config.HolderDropAlertPct)
+	}
+	if config.MaxOffset > 0 {
+		logInfo("最大分页偏移量: %d", config.MaxOffset)
+	}
+	if config.CacheControlMaxAge > 0 {
+		logInfo("查询响应 Cache-Control max-age: %d秒", config.CacheControlMaxAge)
+		if time.Duration(config.CacheControlMaxAge)*time.Second > config.CollectionInterval() {
+			logInfo("警告: Cache-Control max-age 大于采集间隔，客户端可能读到过期数据")
+		}
+	}
+	if config.DBStatementTimeout > 0 {
+		logInfo("数据库语句执行超时: %d秒", config.DBStatementTimeout)
+	}
+}
+
+// Run 启动采集任务和HTTP服务并阻塞运行，直到收到SIGINT/SIGTERM信号或ctx被取消后
+// 优雅关闭。嵌入到其他进程或由上层通过context管理生命周期时直接调用该函数
+func Run(ctx context.Context, config *Config) error {
+	if err := config.Validate(); err != nil {
+		return wrapError("配置验证", err)
+	}
+
+	logInfo("=== Solana SPL 持有者查询工具启动 ===")
+	logConfig(config)
+
+	s, err := NewServer(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			logError("关闭数据库连接", err)
+		}
+	}()
+
+	// 收到中断信号或上层ctx被取消时都触发优雅关闭
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// 启动后台数据采集任务
+	go s.RunCollector(ctx)
+
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", config.ListenPort),
+		Handler:      s.Handler(),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// 启动HTTP服务器
+	serverErr := make(chan error, 1)
+	go func() {
+		// 配置证书时直接终止TLS，net/http 在TLS连接上自动协商HTTP/2
+		scheme := "http"
+		if config.TLSCertFile != "" {
+			scheme = "https"
+		}
+		logInfo("HTTP服务器启动，监听端口: %d", config.ListenPort)
+		logInfo("API端点: %s://localhost:%d/holders", scheme, config.ListenPort)
+		logInfo("健康检查: %s://localhost:%d/health", scheme, config.ListenPort)
+		var err error
+		if config.TLSCertFile != "" {
+			err = server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			serverErr <- err
+		}
+	}()
+
+	logInfo("=== 服务启动完成，等待信号... ===")
+	select {
+	case <-ctx.Done(): // 阻塞直到接收到信号或上层取消
+		logInfo("收到关闭信号，开始优雅关闭...")
+	case err := <-serverErr:
+		cancel()
+		return wrapError("HTTP服务器启动", err)
+	}
+
+	// 触发worker和其他goroutine的关闭
+	cancel()
+
+	// 创建一个有超时的上下文用于关闭HTTP服务器
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logError("HTTP服务器关闭", err)
+	} else {
+		logInfo("HTTP服务器已优雅关闭")
+	}
+
+	logInfo("=== 应用已成功关闭 ===")
+	return nil
+}
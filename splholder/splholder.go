@@ -0,0 +1,6506 @@
+// Package splholder 定期从 Solana RPC 采集 SPL Token 持有者数据写入 MariaDB，
+// 并提供查询和管理的 HTTP API。命令行入口见 server/main.go，也可以通过
+// NewServer 嵌入到其他程序中
+package splholder
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// 构建信息变量（在构建时通过 -ldflags 注入，见 Makefile）
+var (
+	BuildTime = "unknown"
+	GitCommit = "unknown"
+)
+
+// 全局日志记录器
+var (
+	logger   = log.New(os.Stdout, "[solana-spl-holder] ", log.LstdFlags|log.Lshortfile)
+	errorLog = log.New(os.Stderr, "[ERROR] ", log.LstdFlags|log.Lshortfile)
+	infoLog  = log.New(os.Stdout, "[INFO] ", log.LstdFlags)
+	debugLog = log.New(os.Stdout, "[DEBUG] ", log.LstdFlags)
+)
+
+// 采集周期ID在context中的键
+type runIDKey struct{}
+
+// 生成采集周期ID（UUID v4），用于关联同一周期内的日志
+func newRunID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// 将采集周期ID附加到context，采集过程中的日志通过 runIDFromContext 读取
+func withRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// 读取context中的采集周期ID，不在采集周期中时返回 "-"
+func runIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(runIDKey{}).(string); ok {
+		return id
+	}
+	return "-"
+}
+
+// collectionProfile 单个mint一次采集的分阶段耗时，启用 profile_collection 时记录
+type collectionProfile struct {
+	RPC      ProgramAccountsTiming
+	DBUpsert time.Duration // 开始事务到提交
+}
+
+type collectionProfileKey struct{}
+
+// 把分阶段耗时记录挂到context上，getProgramAccounts 的耗时由 fetchProgramAccounts 写入
+func withCollectionProfile(ctx context.Context, p *collectionProfile) context.Context {
+	return context.WithValue(ctx, collectionProfileKey{}, p)
+}
+
+// 读取context中的分阶段耗时记录，未启用 profile_collection 时返回nil
+func collectionProfileFromContext(ctx context.Context) *collectionProfile {
+	p, _ := ctx.Value(collectionProfileKey{}).(*collectionProfile)
+	return p
+}
+
+// 错误包装函数
+func wrapError(operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", operation, err)
+}
+
+// 日志辅助函数
+func logError(operation string, err error) {
+	if err != nil {
+		errorLog.Printf("%s: %v", operation, err)
+	}
+}
+
+func logInfo(format string, args ...interface{}) {
+	infoLog.Printf(format, args...)
+}
+
+func logDebug(format string, args ...interface{}) {
+	debugLog.Printf(format, args...)
+}
+
+// =================================================================
+// 1. 数据结构定义 (用于JSON解析和数据库映射)
+// =================================================================
+
+// RPCRequest 定义了发送到 Solana RPC 的请求体结构
+type RPCRequest struct {
+	Jsonrpc string        `json:"jsonrpc"`
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// RPCResponse 定义了从 Solana RPC 返回的响应体结构
+type RPCResponse struct {
+	Jsonrpc string       `json:"jsonrpc"`
+	ID      string       `json:"id"`
+	Result  []ResultItem `json:"result"`
+	Error   *RPCError    `json:"error,omitempty"`
+}
+
+// RPC节点的数据落后于请求的 minContextSlot 时返回的错误码
+const rpcErrMinContextSlotNotReached = -32016
+
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ResultItem 对应响应中 result 数组的每个元素
+type ResultItem struct {
+	Pubkey  string  `json:"pubkey"`
+	Account Account `json:"account"`
+}
+
+// Account 对应账户信息
+type Account struct {
+	Lamports uint64 `json:"lamports"`
+	Data     Data   `json:"data"`
+	Owner    string `json:"owner"`
+}
+
+// Data 对应账户数据。jsonParsed 编码时为解析后的对象，
+// base64 编码时为 ["<数据>", "base64"] 数组，解码后的原始字节存放在 Raw 中
+type Data struct {
+	Parsed Parsed `json:"parsed"`
+	Raw    []byte `json:"-"`
+}
+
+// UnmarshalJSON 同时支持对象和 [数据, 编码] 数组两种格式
+func (d *Data) UnmarshalJSON(b []byte) error {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var arr []string
+		if err := json.Unmarshal(trimmed, &arr); err != nil {
+			return wrapError("解析账户数据数组", err)
+		}
+		if len(arr) != 2 {
+			return fmt.Errorf("账户数据数组长度应为2，实际为%d", len(arr))
+		}
+		if arr[1] != RPCEncodingBase64 {
+			return fmt.Errorf("不支持的账户数据编码: %s", arr[1])
+		}
+		raw, err := base64.StdEncoding.DecodeString(arr[0])
+		if err != nil {
+			return wrapError("解码base64账户数据", err)
+		}
+		d.Raw = raw
+		return nil
+	}
+
+	type plainData Data
+	return json.Unmarshal(b, (*plainData)(d))
+}
+
+// Parsed 对应解析后的数据
+type Parsed struct {
+	Info Info   `json:"info"`
+	Type string `json:"type"`
+}
+
+// Info 包含详细的代币信息
+type Info struct {
+	Mint            string          `json:"mint"`
+	IsNative        bool            `json:"isNative"`
+	Owner           string          `json:"owner"`
+	State           string          `json:"state"`
+	TokenAmount     TokenAmount     `json:"tokenAmount"`
+	Delegate        string          `json:"delegate,omitempty"`        // 被授权代为转账的地址，未授权时为空
+	DelegatedAmount *TokenAmount    `json:"delegatedAmount,omitempty"` // 授权额度，未授权时为 nil
+	CloseAuthority  string          `json:"closeAuthority,omitempty"`  // 可关闭该账户的地址，未设置时为空
+	Extensions      json.RawMessage `json:"extensions,omitempty"`      // Token-2022 扩展，仅 jsonParsed 编码下由RPC解析返回
+}
+
+// Token-2022 扩展的JSON，没有扩展时为 NULL
+func (info Info) extensionsValue() sql.NullString {
+	ext := strings.TrimSpace(string(info.Extensions))
+	if ext == "" || ext == "null" || ext == "[]" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: ext, Valid: true}
+}
+
+// 授权额度的原始数量，未授权时为0
+func (info Info) delegatedRawAmount() BigAmount {
+	if info.Delegate == "" || info.DelegatedAmount == nil {
+		return BigAmount{}
+	}
+	return info.DelegatedAmount.Amount
+}
+
+// BigAmount 以 big.Int 存储的代币原始数量，JSON 中同时兼容数字和字符串两种格式，
+// 避免大数在 float64 中丢失精度
+type BigAmount struct {
+	i *big.Int
+}
+
+// Int 返回底层的 big.Int（零值时返回 0）
+func (a BigAmount) Int() *big.Int {
+	if a.i == nil {
+		return new(big.Int)
+	}
+	return a.i
+}
+
+func (a BigAmount) String() string {
+	return a.Int().String()
+}
+
+// UnmarshalJSON 支持 "123"、123 以及 null
+func (a *BigAmount) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		a.i = nil
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return wrapError("解析金额字符串", err)
+		}
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("无效的金额: %s", s)
+	}
+	a.i = v
+	return nil
+}
+
+// MarshalJSON 统一输出为字符串，与 RPC 返回格式保持一致
+func (a BigAmount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// Value 实现 driver.Valuer，写入 DECIMAL 列时使用十进制字符串
+func (a BigAmount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// TokenAmount 包含代币数量信息
+type TokenAmount struct {
+	Amount         BigAmount `json:"amount"`
+	Decimals       int       `json:"decimals"`
+	UIAmount       float64   `json:"uiAmount"`
+	UIAmountString string    `json:"uiAmountString"`
+}
+
+// Holder 对应数据库中的 'holder' 表结构
+type Holder struct {
+	ID              int64           `json:"id"`
+	Mint            string          `json:"mint"`
+	Pubkey          string          `json:"pubkey"`
+	Lamports        uint64          `json:"lamports"`
+	IsNative        bool            `json:"isNative"`
+	Owner           string          `json:"owner"`
+	State           string          `json:"state"`
+	Decimals        int             `json:"decimals"`
+	Amount          string          `json:"amount"`
+	UIAmount        float64         `json:"uiAmount"`
+	UIAmountString  string          `json:"uiAmountString"`
+	Delegate        string          `json:"delegate,omitempty"`        // 被授权代为转账的地址，未授权时省略
+	DelegatedAmount string          `json:"delegatedAmount,omitempty"` // 授权额度(原始数量)，未授权时省略
+	CloseAuthority  string          `json:"closeAuthority,omitempty"`  // 仅在 include_extensions=true 时返回
+	Extensions      json.RawMessage `json:"extensions,omitempty"`      // 仅在 include_extensions=true 时返回
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+	FirstSeenAt     *time.Time      `json:"firstSeenAt,omitempty"`   // 仅 /holders/new 返回
+	Label           string          `json:"label,omitempty"`         // 仅在 include_labels=true 时返回
+	LabelCategory   string          `json:"labelCategory,omitempty"` // 仅在 include_labels=true 时返回
+	ValueUSD        *float64        `json:"value_usd,omitempty"`     // 仅在 include_value=true 且价格源有该mint价格时返回
+}
+
+// 设置授权信息，delegate 为 NULL 时两者都不返回
+func (h *Holder) setDelegate(delegate sql.NullString, delegatedAmount string) {
+	if delegate.Valid && delegate.String != "" {
+		h.Delegate = delegate.String
+		h.DelegatedAmount = delegatedAmount
+	}
+}
+
+// AddressLabel 对应数据库中的 'address_label' 表结构
+type AddressLabel struct {
+	ID        int64     `json:"id"`
+	Address   string    `json:"address"`
+	Label     string    `json:"label"`
+	Category  string    `json:"category"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AddressLabelRequest 创建/更新地址标签的请求结构
+type AddressLabelRequest struct {
+	Address  string `json:"address"`
+	Label    string `json:"label" validate:"required"`
+	Category string `json:"category" validate:"required"`
+}
+
+// 地址标签支持的分类
+var validLabelCategories = []string{"exchange", "program", "burn", "pool", "other"}
+
+// 验证地址标签请求（更新时地址来自URL路径，不校验请求体中的address）
+func (req *AddressLabelRequest) Validate(requireAddress bool) error {
+	if requireAddress && req.Address == "" {
+		return fmt.Errorf("address不能为空")
+	}
+	if req.Label == "" {
+		return fmt.Errorf("label不能为空")
+	}
+	for _, category := range validLabelCategories {
+		if req.Category == category {
+			return nil
+		}
+	}
+	return fmt.Errorf("category必须是以下值之一: %v", validLabelCategories)
+}
+
+// ParseLabelCategories 解析逗号分隔的地址标签分类列表，用于 excluded_label_categories，空字符串表示不排除
+func ParseLabelCategories(raw string) ([]string, error) {
+	var categories []string
+	for _, part := range strings.Split(raw, ",") {
+		category := strings.ToLower(strings.TrimSpace(part))
+		if category == "" {
+			continue
+		}
+		valid := false
+		for _, c := range validLabelCategories {
+			if c == category {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("无效的地址标签分类: %s，可选值: %s", part, strings.Join(validLabelCategories, ", "))
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// 解析 exclude_known 参数，返回需要排除的地址标签分类；未指定时返回nil，未配置排除分类时返回错误
+func parseExcludeKnown(r *http.Request, excludedCategories []string) ([]string, error) {
+	if r.URL.Query().Get("exclude_known") != "true" {
+		return nil, nil
+	}
+	if len(excludedCategories) == 0 {
+		return nil, fmt.Errorf("未配置excluded_label_categories，不支持exclude_known")
+	}
+	return excludedCategories, nil
+}
+
+// 排除已知地址的SQL条件：Token账户地址(pubkey)或钱包地址(owner)在 address_label 中且分类属于 categories。
+// table 为holder表名或别名，categories 不能为空
+func knownAddressCondition(table string, categories []string) (string, []interface{}) {
+	args := make([]interface{}, len(categories))
+	for i, category := range categories {
+		args[i] = category
+	}
+	return "NOT EXISTS (SELECT 1 FROM address_label x WHERE x.address IN (" + table + ".owner, " + table + ".pubkey)" +
+		" AND x.category IN (?" + strings.Repeat(", ?", len(categories)-1) + "))", args
+}
+
+// HolderUpdateRequest 更新Holder状态的请求结构
+type HolderUpdateRequest struct {
+	State string `json:"state" validate:"required"`
+}
+
+// 验证Holder更新请求
+func (req *HolderUpdateRequest) Validate() error {
+	validStates := []string{"uninitialized", "initialized", "frozen"}
+	for _, validState := range validStates {
+		if req.State == validState {
+			return nil
+		}
+	}
+	return fmt.Errorf("state必须是以下值之一: %v", validStates)
+}
+
+// 查询spl表所有mint
+func getAllMintAddresses(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT mint FROM spl")
+	if err != nil {
+		return nil, wrapError("查询mint列表", err)
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var mint string
+		if err := rows.Scan(&mint); err != nil {
+			return nil, wrapError("扫描mint", err)
+		}
+		result = append(result, mint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历查询结果", err)
+	}
+
+	logInfo("成功获取到 %d 个mint地址", len(result))
+	return result, nil
+}
+
+// MintOptions 从spl视图可选列中读取的单个mint采集配置
+type MintOptions struct {
+	RPCFilters json.RawMessage // rpc_filters列，为空时使用默认过滤器
+	KeepTopN   sql.NullInt64   // keep_top_n列，NULL时使用全局 --keep_top_n
+	// holder_drop_alert_pct列，NULL时使用全局 --holder_drop_alert_pct
+	HolderDropAlertPct sql.NullFloat64
+	ProgramID          string // program_id列，为空时使用 Token-2022（与未支持该列的旧版本一致）
+}
+
+// tokenProgram 返回采集该mint时 getProgramAccounts 查询的程序，只支持经典 Token Program 和 Token-2022
+func (o MintOptions) tokenProgram() (string, error) {
+	switch o.ProgramID {
+	case "":
+		return token2022ProgramID, nil
+	case tokenProgramID, token2022ProgramID:
+		return o.ProgramID, nil
+	}
+	return "", fmt.Errorf("不支持的program_id: %s，可选值: %s、%s", o.ProgramID, tokenProgramID, token2022ProgramID)
+}
+
+// 读取spl视图中各mint的可选采集配置（rpc_filters、keep_top_n、holder_drop_alert_pct、program_id列），
+// 视图中没有这些列时返回空map
+func getMintOptions(db *sql.DB) (map[string]MintOptions, error) {
+	var cols []string
+	found := false
+	for _, col := range []string{"rpc_filters", "keep_top_n", "holder_drop_alert_pct", "program_id"} {
+		exists, err := checkColumnExists(db, "spl", col)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			cols = append(cols, col)
+			found = true
+		} else {
+			cols = append(cols, "NULL")
+		}
+	}
+	options := make(map[string]MintOptions)
+	if !found {
+		return options, nil
+	}
+
+	rows, err := db.Query("SELECT mint, " + strings.Join(cols, ", ") + " FROM spl")
+	if err != nil {
+		return nil, wrapError("查询mint采集配置", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mint string
+		var filters, programID sql.NullString
+		var opts MintOptions
+		if err := rows.Scan(&mint, &filters, &opts.KeepTopN, &opts.HolderDropAlertPct, &programID); err != nil {
+			return nil, wrapError("扫描mint采集配置", err)
+		}
+		if filters.String != "" {
+			opts.RPCFilters = json.RawMessage(filters.String)
+		}
+		opts.ProgramID = strings.TrimSpace(programID.String)
+		options[mint] = opts
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历查询结果", err)
+	}
+	return options, nil
+}
+
+// 生成getProgramAccounts的filters参数。未配置覆盖时使用标准的mint字段(偏移量0)过滤；
+// 覆盖配置必须是JSON数组，元素为 {"memcmp": {"offset": n, "bytes": "..."}} 或 {"dataSize": n}，
+// 且至少包含一个匹配该mint地址的memcmp过滤器，避免采集到其他mint的账户
+func buildProgramAccountsFilters(mintAddress string, override json.RawMessage) ([]map[string]interface{}, error) {
+	if len(override) == 0 {
+		return []map[string]interface{}{
+			{
+				"memcmp": map[string]interface{}{
+					"offset": 0, // `mint` 字段的偏移量是 0
+					"bytes":  mintAddress,
+				},
+			},
+		}, nil
+	}
+
+	var raw []struct {
+		Memcmp *struct {
+			Offset *int   `json:"offset"`
+			Bytes  string `json:"bytes"`
+		} `json:"memcmp"`
+		DataSize *int `json:"dataSize"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(override))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("rpc_filters不是有效的过滤器数组: %v", err)
+	}
+	if len(raw) == 0 || len(raw) > 4 {
+		return nil, fmt.Errorf("rpc_filters必须包含1-4个过滤器")
+	}
+
+	filters := make([]map[string]interface{}, 0, len(raw))
+	matchesMint := false
+	for i, f := range raw {
+		switch {
+		case f.Memcmp != nil && f.DataSize == nil:
+			if f.Memcmp.Offset == nil || *f.Memcmp.Offset < 0 || f.Memcmp.Bytes == "" {
+				return nil, fmt.Errorf("rpc_filters第%d个memcmp过滤器需要非负的offset和非空的bytes", i+1)
+			}
+			if f.Memcmp.Bytes == mintAddress {
+				matchesMint = true
+			}
+			filters = append(filters, map[string]interface{}{
+				"memcmp": map[string]interface{}{
+					"offset": *f.Memcmp.Offset,
+					"bytes":  f.Memcmp.Bytes,
+				},
+			})
+		case f.DataSize != nil && f.Memcmp == nil:
+			if *f.DataSize <= 0 {
+				return nil, fmt.Errorf("rpc_filters第%d个dataSize过滤器必须为正数", i+1)
+			}
+			filters = append(filters, map[string]interface{}{"dataSize": *f.DataSize})
+		default:
+			return nil, fmt.Errorf("rpc_filters第%d个过滤器必须且只能包含memcmp或dataSize之一", i+1)
+		}
+	}
+	if !matchesMint {
+		return nil, fmt.Errorf("rpc_filters必须包含bytes为该mint地址的memcmp过滤器")
+	}
+	return filters, nil
+}
+
+// SPL Token 精度的合理上限。超出范围的精度来自异常的RPC响应，
+// 入库后会导致 ui_amount 等格式化结果失去意义
+const maxTokenDecimals = 18
+
+// 检查代币精度是否在 0-18 之间
+func validateDecimals(decimals int) error {
+	if decimals < 0 || decimals > maxTokenDecimals {
+		return fmt.Errorf("代币精度%d超出范围(0-%d)", decimals, maxTokenDecimals)
+	}
+	return nil
+}
+
+// holder表upsert语句的三个部分，单行写入为 holderUpsertInsert + holderUpsertRow + holderUpsertOnDuplicate，
+// 多行写入时重复 holderUpsertRow。
+// first_seen_at 只在首次插入时写入，更新时保持不变。
+// ON DUPLICATE KEY UPDATE 的赋值按从左到右执行，updated_at 必须放在最前面，
+// 以便与更新前的值比较：数据未变化时保留原 updated_at，该行也不会被实际写入
+const (
+	holderUpsertInsert = `INSERT INTO holder (
+		mint, pubkey, lamports, is_native, owner, state, decimals, amount, ui_amount, ui_amount_string, delegate, delegated_amount, close_authority, extensions, created_at, updated_at, first_seen_at
+	) VALUES `
+	holderUpsertRow = `(
+		?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+	)`
+	holderUpsertOnDuplicate = ` ON DUPLICATE KEY UPDATE
+		updated_at = IF(
+			lamports <=> VALUES(lamports) AND
+			is_native <=> VALUES(is_native) AND
+			owner <=> VALUES(owner) AND
+			state <=> VALUES(state) AND
+			decimals <=> VALUES(decimals) AND
+			amount <=> VALUES(amount) AND
+			ui_amount <=> VALUES(ui_amount) AND
+			ui_amount_string <=> VALUES(ui_amount_string) AND
+			delegate <=> VALUES(delegate) AND
+			delegated_amount <=> VALUES(delegated_amount) AND
+			close_authority <=> VALUES(close_authority) AND
+			extensions <=> VALUES(extensions),
+			updated_at, CURRENT_TIMESTAMP
+		),
+		lamports = VALUES(lamports),
+		is_native = VALUES(is_native),
+		owner = VALUES(owner),
+		state = VALUES(state),
+		decimals = VALUES(decimals),
+		amount = VALUES(amount),
+		ui_amount = VALUES(ui_amount),
+		ui_amount_string = VALUES(ui_amount_string),
+		delegate = VALUES(delegate),
+		delegated_amount = VALUES(delegated_amount),
+		close_authority = VALUES(close_authority),
+		extensions = VALUES(extensions);`
+)
+
+// 校验账户并生成 holderUpsertRow 对应的参数
+func holderUpsertArgs(mintAddress string, item ResultItem) ([]interface{}, error) {
+	// 数据验证
+	if mintAddress == "" {
+		return nil, fmt.Errorf("mint地址不能为空")
+	}
+	if item.Pubkey == "" {
+		return nil, fmt.Errorf("pubkey不能为空")
+	}
+
+	info := item.Account.Data.Parsed.Info
+	if err := validateDecimals(info.TokenAmount.Decimals); err != nil {
+		return nil, err
+	}
+	// 部分RPC节点的响应省略 uiAmountString，按 amount 和 decimals 计算，与 RPC 返回的格式一致
+	uiAmountString := info.TokenAmount.UIAmountString
+	if uiAmountString == "" {
+		uiAmountString = formatUIAmount(info.TokenAmount.Amount.Int(), info.TokenAmount.Decimals)
+	}
+	return []interface{}{
+		mintAddress,
+		item.Pubkey,
+		item.Account.Lamports,
+		info.IsNative,
+		info.Owner,
+		info.State,
+		info.TokenAmount.Decimals,
+		info.TokenAmount.Amount,
+		info.TokenAmount.UIAmount,
+		uiAmountString,
+		sql.NullString{String: info.Delegate, Valid: info.Delegate != ""},
+		info.delegatedRawAmount(),
+		sql.NullString{String: info.CloseAuthority, Valid: info.CloseAuthority != ""},
+		info.extensionsValue(),
+	}, nil
+}
+
+// MariaDB插入/更新
+func upsertHolderMariaDB(dbOrTx interface{}, mintAddress string, item ResultItem) error {
+	args, err := holderUpsertArgs(mintAddress, item)
+	if err != nil {
+		return err
+	}
+
+	var execFn func(string, ...interface{}) (sql.Result, error)
+	switch v := dbOrTx.(type) {
+	case *sql.DB:
+		execFn = v.Exec
+	case *sql.Tx:
+		execFn = v.Exec
+	default:
+		return fmt.Errorf("无效的数据库连接类型")
+	}
+
+	if _, err := execFn(holderUpsertInsert+holderUpsertRow+holderUpsertOnDuplicate, args...); err != nil {
+		return wrapError(fmt.Sprintf("更新持有者数据(pubkey: %s)", item.Pubkey), err)
+	}
+	return nil
+}
+
+// 检查表是否存在
+func checkTableExists(db *sql.DB, tableName string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", tableName).Scan(&count)
+	if err != nil {
+		return false, wrapError(fmt.Sprintf("检查表%s是否存在", tableName), err)
+	}
+	return count > 0, nil
+}
+
+// 检查视图是否存在
+func checkViewExists(db *sql.DB, viewName string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.views WHERE table_schema = DATABASE() AND table_name = ?", viewName).Scan(&count)
+	if err != nil {
+		return false, wrapError(fmt.Sprintf("检查视图%s是否存在", viewName), err)
+	}
+	return count > 0, nil
+}
+
+// 检查表或视图中是否存在指定列
+func checkColumnExists(db *sql.DB, tableName, columnName string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?"
+	if err := db.QueryRow(query, tableName, columnName).Scan(&count); err != nil {
+		return false, wrapError(fmt.Sprintf("检查%s.%s列是否存在", tableName, columnName), err)
+	}
+	return count > 0, nil
+}
+
+// 检查索引是否存在
+func checkIndexExists(db *sql.DB, tableName, indexName string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?"
+	if err := db.QueryRow(query, tableName, indexName).Scan(&count); err != nil {
+		return false, wrapError(fmt.Sprintf("检查%s.%s索引是否存在", tableName, indexName), err)
+	}
+	return count > 0, nil
+}
+
+// 检查连接字符串中扫描时间字段所需的参数。缺少 parseTime=True 时 created_at 等
+// DATETIME 列无法扫描为 time.Time，只能在查询时得到难以理解的驱动错误，因此启动时直接报错；
+// 缺少 loc 时驱动按UTC解析时间，只输出警告
+func checkDSNTimeOptions(connStr string) error {
+	cfg, err := mysql.ParseDSN(connStr)
+	if err != nil {
+		return wrapError("解析数据库连接字符串", err)
+	}
+	if !cfg.ParseTime {
+		return fmt.Errorf("数据库连接字符串缺少 parseTime=True，时间字段无法解析，请在连接字符串中添加 parseTime=True&loc=Local")
+	}
+	hasLoc := false
+	if i := strings.Index(connStr, "?"); i >= 0 {
+		for _, param := range strings.Split(connStr[i+1:], "&") {
+			if strings.HasPrefix(param, "loc=") {
+				hasLoc = true
+				break
+			}
+		}
+	}
+	if !hasLoc {
+		logInfo("警告: 数据库连接字符串未指定loc参数，时间字段将按UTC解析，与数据库时区不一致时请添加 loc=Local")
+	}
+	return nil
+}
+
+// 在连接字符串中设置语句执行超时(秒)。驱动会在每个新建连接上执行
+// SET max_statement_time，由 MariaDB 服务端终止超时的查询
+func withStatementTimeout(connStr string, timeoutSeconds int) (string, error) {
+	if timeoutSeconds <= 0 {
+		return connStr, nil
+	}
+	cfg, err := mysql.ParseDSN(connStr)
+	if err != nil {
+		return "", wrapError("解析数据库连接字符串", err)
+	}
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["max_statement_time"] = strconv.Itoa(timeoutSeconds)
+	return cfg.FormatDSN(), nil
+}
+
+// schemaObject 服务依赖的表或视图
+type schemaObject struct {
+	Name           string
+	Column         string // 非空时检查表Name中是否存在该列
+	Index          string // 非空时检查表Name中是否存在该索引
+	IsView         bool
+	Required       bool // 缺失时服务无法启动
+	MissingMessage string
+}
+
+// 启动时检查的表和视图
+var schemaObjects = []schemaObject{
+	{Name: "spl", IsView: true, Required: true, MissingMessage: "spl视图不存在，请先创建spl视图"},
+	{Name: "holder", Required: true, MissingMessage: "holder表不存在，请先创建holder表"},
+	{Name: "holder", Column: "first_seen_at", Required: true, MissingMessage: "holder表缺少first_seen_at列，请参考setup/README.md执行迁移"},
+	{Name: "holder", Column: "delegate", Required: true, MissingMessage: "holder表缺少delegate列，请参考setup/README.md执行迁移"},
+	{Name: "holder", Column: "delegated_amount", Required: true, MissingMessage: "holder表缺少delegated_amount列，请参考setup/README.md执行迁移"},
+	{Name: "holder", Column: "close_authority", Required: true, MissingMessage: "holder表缺少close_authority列，请参考setup/README.md执行迁移"},
+	{Name: "holder", Column: "extensions", Required: true, MissingMessage: "holder表缺少extensions列，请参考setup/README.md执行迁移"},
+	// 缺少复合索引时查询仍然正确，只是按mint查询并按余额排序时需要全量排序
+	{Name: "holder", Index: "idx_mint_ui_amount", MissingMessage: "holder表缺少idx_mint_ui_amount索引，按mint查询并按余额排序时性能较差，请参考setup/README.md添加"},
+	{Name: "collection_status", Required: true, MissingMessage: "collection_status表不存在，请先执行setup/init_database.sql创建该表"},
+	// 缺少program_id列时全部mint按Token-2022采集，与旧版本一致
+	{Name: "spl", Column: "program_id", MissingMessage: "spl视图没有program_id列，全部mint按Token-2022采集，经典Token Program的mint请参考setup/README.md添加该列"},
+	// address_label表为可选表，缺失时仅标签相关功能不可用
+	{Name: "address_label", MissingMessage: "address_label表不存在，地址标签功能不可用"},
+	{Name: "watchlist", MissingMessage: "watchlist表不存在，关注列表功能不可用"},
+	{Name: "snapshot_root", MissingMessage: "snapshot_root表不存在，Merkle快照功能不可用"},
+	{Name: "native_balance", MissingMessage: "native_balance表不存在，SOL余额采集功能不可用"},
+}
+
+// 日志和自检输出中使用的名称
+func (obj schemaObject) displayName() string {
+	if obj.Column != "" {
+		return obj.Name + "." + obj.Column
+	}
+	if obj.Index != "" {
+		return obj.Name + "(" + obj.Index + ")"
+	}
+	return obj.Name
+}
+
+// 检查表、视图或列是否存在
+func checkSchemaObject(db *sql.DB, obj schemaObject) (bool, error) {
+	if obj.Column != "" {
+		return checkColumnExists(db, obj.Name, obj.Column)
+	}
+	if obj.Index != "" {
+		return checkIndexExists(db, obj.Name, obj.Index)
+	}
+	if obj.IsView {
+		return checkViewExists(db, obj.Name)
+	}
+	return checkTableExists(db, obj.Name)
+}
+
+// 打开数据库连接池并测试连接
+func openMariaDB(connStr string, statementTimeout int) (*sql.DB, error) {
+	if connStr == "" {
+		return nil, fmt.Errorf("数据库连接字符串不能为空")
+	}
+	if err := checkDSNTimeOptions(connStr); err != nil {
+		return nil, err
+	}
+
+	connStr, err := withStatementTimeout(connStr, statementTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("mysql", connStr)
+	if err != nil {
+		return nil, wrapError("打开数据库连接", err)
+	}
+
+	// 设置连接池参数
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, wrapError("数据库连接测试", err)
+	}
+	return db, nil
+}
+
+// MariaDB初始化
+func initMariaDB(connStr string, statementTimeout int) (*sql.DB, error) {
+	logInfo("正在连接数据库...")
+	db, err := openMariaDB(connStr, statementTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	logInfo("数据库连接成功")
+
+	for _, obj := range schemaObjects {
+		exists, err := checkSchemaObject(db, obj)
+		if err != nil {
+			db.Close()
+			return nil, wrapError(fmt.Sprintf("检查%s是否存在", obj.displayName()), err)
+		}
+		if exists {
+			continue
+		}
+		if obj.Required {
+			db.Close()
+			return nil, fmt.Errorf("数据库检查失败: %s", obj.MissingMessage)
+		}
+		logInfo("%s", obj.MissingMessage)
+	}
+
+	logInfo("数据库表和视图检查完成")
+	return db, nil
+}
+
+// Storage 持有主库和只读副本两个连接池，写操作（采集入库、状态更新等）走主库，
+// 查询类请求走只读副本；未配置副本时读写都使用主库
+type Storage struct {
+	primary *sql.DB
+	replica *sql.DB
+}
+
+func newStorage(primary, replica *sql.DB) *Storage {
+	return &Storage{primary: primary, replica: replica}
+}
+
+// Writer 返回写操作使用的主库连接池
+func (s *Storage) Writer() *sql.DB {
+	return s.primary
+}
+
+// Reader 返回查询使用的连接池，未配置只读副本时返回主库
+func (s *Storage) Reader() *sql.DB {
+	if s.replica != nil {
+		return s.replica
+	}
+	return s.primary
+}
+
+// 只读查询遇到连接级错误时的重试等待时间
+const readRetryDelay = 200 * time.Millisecond
+
+// 判断是否为连接级错误：数据库重启或连接池中的连接被服务端断开。database/sql 会丢弃出错的连接，
+// 重试时换用新连接
+func isConnError(err error) bool {
+	return errors.Is(err, sql.ErrConnDone) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn)
+}
+
+// retryRead 执行只读查询，遇到连接级错误时等待片刻后重试一次。只用于查询，写操作可能已经在
+// 服务端生效，重试会重复执行
+func retryRead(ctx context.Context, read func() error) error {
+	err := read()
+	if err == nil || !isConnError(err) {
+		return err
+	}
+	logInfo("查询遇到连接错误，%v后重试: %v", readRetryDelay, err)
+	select {
+	case <-ctx.Done():
+		return err
+	case <-time.After(readRetryDelay):
+	}
+	return read()
+}
+
+// Close 关闭所有连接池
+func (s *Storage) Close() error {
+	var firstErr error
+	if s.replica != nil {
+		if err := s.replica.Close(); err != nil {
+			firstErr = wrapError("关闭只读副本连接", err)
+		}
+	}
+	if err := s.primary.Close(); err != nil && firstErr == nil {
+		firstErr = wrapError("关闭主库连接", err)
+	}
+	return firstErr
+}
+
+// API响应结构
+type APIResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Total   int         `json:"total,omitempty"`
+	Page    int         `json:"page,omitempty"`
+	Limit   int         `json:"limit,omitempty"`
+
+	// 查询的mint正在采集中（事务尚未提交），返回的数据可能即将变化
+	CollectionInProgress bool `json:"collection_in_progress,omitempty"`
+}
+
+// 校验请求体的Content-Type为application/json，否则返回415
+func requireJSONContentType(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		sendJSONResponse(w, http.StatusUnsupportedMediaType, APIResponse{
+			Success: false,
+			Error:   "Content-Type必须是application/json",
+		})
+		return false
+	}
+	return true
+}
+
+// 校验分页偏移量不超过上限，maxOffset为0表示不限制；超出时返回400
+func checkMaxOffset(w http.ResponseWriter, offset, maxOffset int) bool {
+	if maxOffset > 0 && offset > maxOffset {
+		sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("分页偏移量 %d 超过上限 %d，深度分页开销较大，请增加过滤条件缩小查询范围", offset, maxOffset),
+		})
+		return false
+	}
+	return true
+}
+
+// 列表接口的默认和最大每页数量
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 1000
+)
+
+// parsePagination 解析列表接口的 page 和 limit 参数：未指定或小于1时使用默认值，
+// limit 超过 maxPageLimit 时按上限处理，不是整数时返回错误
+func parsePagination(r *http.Request) (page, limit int, err error) {
+	query := r.URL.Query()
+	page, limit = 1, defaultPageLimit
+	if raw := query.Get("page"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, 0, fmt.Errorf("page必须是整数")
+		}
+		if n > 1 {
+			page = n
+		}
+	}
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, 0, fmt.Errorf("limit必须是整数")
+		}
+		if n > 0 {
+			limit = n
+		}
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit // 限制最大查询数量
+	}
+	// 防止 (page-1)*limit 溢出为负数的偏移量
+	if page-1 > math.MaxInt32/limit {
+		return 0, 0, fmt.Errorf("page超出范围")
+	}
+	return page, limit, nil
+}
+
+// 判断请求是否带有 Prefer: return=minimal
+func preferReturnMinimal(r *http.Request) bool {
+	for _, value := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), "return=minimal") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 发送写操作的成功响应。请求带有 Prefer: return=minimal 时返回204且不回显记录
+func sendMutationResponse(w http.ResponseWriter, r *http.Request, statusCode int, response APIResponse) {
+	if preferReturnMinimal(r) {
+		w.Header().Set("Preference-Applied", "return=minimal")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	sendJSONResponse(w, statusCode, response)
+}
+
+// 发送JSON响应，请求要求 MessagePack 时以相同的结构编码为 MessagePack
+func sendJSONResponse(w http.ResponseWriter, statusCode int, response APIResponse) {
+	if wantsMsgpack(w) {
+		w.Header().Set("Content-Type", contentTypeMsgpack)
+		w.WriteHeader(statusCode)
+		if err := encodeMsgpack(w, response); err != nil {
+			logError("编码MessagePack响应", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	encoder := json.NewEncoder(w)
+	if wantsPrettyJSON(w) {
+		encoder.SetIndent("", "  ")
+	}
+	encoder.Encode(response)
+}
+
+// prettyResponseWriter 标记该请求的JSON响应需要缩进输出
+type prettyResponseWriter struct {
+	http.ResponseWriter
+	pretty bool
+}
+
+// Unwrap 供 http.ResponseController 访问底层的 ResponseWriter
+func (w *prettyResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// 沿 Unwrap 链查找 prettyResponseWriter，判断是否需要缩进输出
+func wantsPrettyJSON(w http.ResponseWriter) bool {
+	for {
+		switch v := w.(type) {
+		case *prettyResponseWriter:
+			return v.pretty
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// cacheControlResponseWriter 在成功响应写出状态码前设置 Cache-Control
+type cacheControlResponseWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (w *cacheControlResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if statusCode == http.StatusOK {
+			w.Header().Set("Cache-Control", w.value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *cacheControlResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap 供 http.ResponseController 访问底层的 ResponseWriter
+func (w *cacheControlResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withCacheControl 为GET/HEAD请求的200响应设置 Cache-Control: public, max-age=N，
+// 写操作和错误响应不设置缓存头；maxAge为0时不做处理
+func withCacheControl(maxAge int, next http.HandlerFunc) http.HandlerFunc {
+	if maxAge <= 0 {
+		return next
+	}
+	value := fmt.Sprintf("public, max-age=%d", maxAge)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			w = &cacheControlResponseWriter{ResponseWriter: w, value: value}
+		}
+		next(w, r)
+	}
+}
+
+// withPrettyJSON 请求带有 ?pretty=true 时，sendJSONResponse 以缩进格式输出JSON，默认保持紧凑输出
+func withPrettyJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pretty") == "true" {
+			w = &prettyResponseWriter{ResponseWriter: w, pretty: true}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 更新Holder状态
+func updateHolderState(db *sql.DB, mintAddress, pubkey, state string) (*Holder, error) {
+	// 检查记录是否存在
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM holder WHERE mint = ? AND pubkey = ?)", mintAddress, pubkey).Scan(&exists)
+	if err != nil {
+		return nil, wrapError("检查Holder记录是否存在", err)
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("mint为 %s 且 pubkey为 %s 的Holder记录不存在", mintAddress, pubkey)
+	}
+
+	// 更新状态
+	_, err = db.Exec("UPDATE holder SET state = ?, updated_at = CURRENT_TIMESTAMP WHERE mint = ? AND pubkey = ?", state, mintAddress, pubkey)
+	if err != nil {
+		return nil, wrapError("更新Holder状态", err)
+	}
+
+	// 查询更新后的记录
+	var holder Holder
+	var delegate sql.NullString
+	var delegatedAmount string
+	err = db.QueryRow(`
+		SELECT id, mint, pubkey, lamports, is_native, owner, state, decimals, 
+		       amount, ui_amount, ui_amount_string, delegate, delegated_amount, created_at, updated_at 
+		FROM holder 
+		WHERE mint = ? AND pubkey = ?
+	`, mintAddress, pubkey).Scan(
+		&holder.ID, &holder.Mint, &holder.Pubkey, &holder.Lamports,
+		&holder.IsNative, &holder.Owner, &holder.State, &holder.Decimals,
+		&holder.Amount, &holder.UIAmount, &holder.UIAmountString,
+		&delegate, &delegatedAmount,
+		&holder.CreatedAt, &holder.UpdatedAt,
+	)
+	if err != nil {
+		return nil, wrapError("查询更新后的Holder记录", err)
+	}
+	holder.setDelegate(delegate, delegatedAmount)
+
+	return &holder, nil
+}
+
+// 删除mint已不在spl视图中的孤立Holder记录，返回删除的行数
+func deleteOrphanHolders(db *sql.DB) (int64, error) {
+	// spl视图为空时通常是集成数据异常，此时跳过清理，避免误删全部持有者数据
+	var splCount int
+	if err := db.QueryRow("SELECT COUNT(*) FROM spl").Scan(&splCount); err != nil {
+		return 0, wrapError("查询spl记录数", err)
+	}
+	if splCount == 0 {
+		return 0, fmt.Errorf("spl视图中没有记录，跳过孤立Holder清理")
+	}
+
+	result, err := db.Exec("DELETE FROM holder WHERE NOT EXISTS (SELECT 1 FROM spl WHERE spl.mint = holder.mint)")
+	if err != nil {
+		return 0, wrapError("删除孤立Holder记录", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, wrapError("获取删除行数", err)
+	}
+	return deleted, nil
+}
+
+// 处理清理孤立Holder记录的HTTP请求
+func handleCleanupOrphans(db *sql.DB, registry *CollectionRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+			return
+		}
+
+		deleted, err := deleteOrphanHolders(db)
+		if err != nil {
+			logError("清理孤立Holder记录", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "Failed to cleanup orphan holders",
+			})
+			return
+		}
+
+		logInfo("手动清理孤立Holder记录完成，删除 %d 条记录", deleted)
+		if deleted > 0 {
+			registry.resetCounts("")
+		}
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    map[string]int64{"deleted": deleted},
+		})
+	}
+}
+
+// UIAmountMismatch ui_amount_string 与 amount/decimals 计算值不一致的记录
+type UIAmountMismatch struct {
+	Mint     string `json:"mint"`
+	Pubkey   string `json:"pubkey"`
+	Stored   string `json:"stored"`
+	Expected string `json:"expected"`
+}
+
+// UIAmountCheckReport ui_amount_string 一致性检查结果
+type UIAmountCheckReport struct {
+	Scanned      int64              `json:"scanned"`
+	Inconsistent int64              `json:"inconsistent"`
+	Fixed        int64              `json:"fixed"`
+	Samples      []UIAmountMismatch `json:"samples"` // 最多返回前20条不一致记录
+}
+
+const (
+	uiAmountCheckBatchSize  = 1000
+	uiAmountCheckMaxSamples = 20
+)
+
+// 按 amount/decimals 重新计算 ui_amount_string 并与存储值比较，fix为true时回填正确值。
+// 按id分批扫描，避免长时间持有大结果集
+func checkUIAmountStrings(db *sql.DB, mintAddress string, fix bool) (*UIAmountCheckReport, error) {
+	report := &UIAmountCheckReport{Samples: []UIAmountMismatch{}}
+	var lastID int64
+	for {
+		query := "SELECT id, mint, pubkey, amount, decimals, ui_amount_string FROM holder WHERE id > ?"
+		args := []interface{}{lastID}
+		if mintAddress != "" {
+			query += " AND mint = ?"
+			args = append(args, mintAddress)
+		}
+		query += " ORDER BY id LIMIT ?"
+		args = append(args, uiAmountCheckBatchSize)
+
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return nil, wrapError("查询持有者数据", err)
+		}
+		var fixes []UIAmountMismatch
+		var fixIDs []int64
+		batch := 0
+		for rows.Next() {
+			var id int64
+			var m UIAmountMismatch
+			var amountStr string
+			var decimals int
+			if err := rows.Scan(&id, &m.Mint, &m.Pubkey, &amountStr, &decimals, &m.Stored); err != nil {
+				rows.Close()
+				return nil, wrapError("扫描数据行", err)
+			}
+			batch++
+			lastID = id
+			amount, ok := new(big.Int).SetString(amountStr, 10)
+			if !ok {
+				rows.Close()
+				return nil, fmt.Errorf("无效的amount(id: %d): %s", id, amountStr)
+			}
+			m.Expected = formatUIAmount(amount, decimals)
+			if m.Expected == m.Stored {
+				continue
+			}
+			report.Inconsistent++
+			if len(report.Samples) < uiAmountCheckMaxSamples {
+				report.Samples = append(report.Samples, m)
+			}
+			if fix {
+				fixes = append(fixes, m)
+				fixIDs = append(fixIDs, id)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, wrapError("遍历查询结果", err)
+		}
+		report.Scanned += int64(batch)
+
+		for i, m := range fixes {
+			if _, err := db.Exec("UPDATE holder SET ui_amount_string = ? WHERE id = ?", m.Expected, fixIDs[i]); err != nil {
+				return nil, wrapError(fmt.Sprintf("回填ui_amount_string(pubkey: %s)", m.Pubkey), err)
+			}
+			report.Fixed++
+		}
+
+		if batch < uiAmountCheckBatchSize {
+			break
+		}
+	}
+	return report, nil
+}
+
+// 处理ui_amount_string一致性检查的HTTP请求
+func handleCheckUIAmount(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+			return
+		}
+
+		query := r.URL.Query()
+		fix := query.Get("fix") == "true"
+		report, err := checkUIAmountStrings(db, query.Get("mint"), fix)
+		if err != nil {
+			logError("检查ui_amount_string一致性", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "Failed to check ui_amount_string",
+			})
+			return
+		}
+
+		logInfo("ui_amount_string一致性检查完成，扫描 %d 条，不一致 %d 条，回填 %d 条", report.Scanned, report.Inconsistent, report.Fixed)
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    report,
+		})
+	}
+}
+
+// 处理更新Holder状态的HTTP请求
+func handleUpdateHolderState(db *sql.DB, registry *CollectionRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+			return
+		}
+
+		// 从URL路径中提取mint_address和pubkey
+		path := r.URL.Path
+		parts := strings.Split(strings.Trim(path, "/"), "/")
+		if len(parts) < 3 {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "Missing mint_address or pubkey",
+			})
+			return
+		}
+
+		mintAddress := parts[len(parts)-2]
+		pubkey := parts[len(parts)-1]
+		if mintAddress == "" || pubkey == "" {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "Invalid mint_address or pubkey",
+			})
+			return
+		}
+		// 先校验地址格式，区分输入错误和记录确实不存在(404)
+		if err := validateSolanaAddress(mintAddress); err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "mint_address格式错误: " + err.Error(),
+			})
+			return
+		}
+		if err := validateSolanaAddress(pubkey); err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "pubkey格式错误: " + err.Error(),
+			})
+			return
+		}
+
+		// 解析请求体
+		var req HolderUpdateRequest
+		if !requireJSONContentType(w, r) {
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logError("Failed to decode request body", err)
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "Invalid JSON format",
+			})
+			return
+		}
+
+		// 验证请求
+		if err := req.Validate(); err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		// 更新Holder状态
+		holder, err := updateHolderState(db, mintAddress, pubkey, req.State)
+		if err != nil {
+			logError("Failed to update holder state", err)
+			if strings.Contains(err.Error(), "不存在") {
+				sendJSONResponse(w, http.StatusNotFound, APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+			} else {
+				sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+					Success: false,
+					Error:   "Failed to update holder state",
+				})
+			}
+			return
+		}
+		// 状态变化影响按 state 过滤的总数
+		registry.resetCounts(mintAddress)
+
+		// 返回成功响应
+		sendMutationResponse(w, r, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    holder,
+		})
+	}
+}
+
+// 查询单个地址标签
+func getAddressLabel(db *sql.DB, address string) (*AddressLabel, error) {
+	var label AddressLabel
+	err := db.QueryRow(`
+		SELECT id, address, label, category, created_at, updated_at
+		FROM address_label
+		WHERE address = ?
+	`, address).Scan(&label.ID, &label.Address, &label.Label, &label.Category, &label.CreatedAt, &label.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("地址 %s 的标签不存在", address)
+	}
+	if err != nil {
+		return nil, wrapError("查询地址标签", err)
+	}
+	return &label, nil
+}
+
+// 分页查询地址标签，可按分类过滤
+func listAddressLabels(db *sql.DB, category string, limit, offset int) ([]AddressLabel, int, error) {
+	var conds []string
+	var args []interface{}
+	if category != "" {
+		conds = append(conds, "category = ?")
+		args = append(args, category)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM address_label"+where, args...).Scan(&total); err != nil {
+		return nil, 0, wrapError("查询地址标签总数", err)
+	}
+
+	query := "SELECT id, address, label, category, created_at, updated_at FROM address_label" + where +
+		fmt.Sprintf(" ORDER BY id LIMIT %d OFFSET %d", limit, offset)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, wrapError("查询地址标签", err)
+	}
+	defer rows.Close()
+
+	labels := []AddressLabel{}
+	for rows.Next() {
+		var l AddressLabel
+		if err := rows.Scan(&l.ID, &l.Address, &l.Label, &l.Category, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, 0, wrapError("扫描地址标签", err)
+		}
+		labels = append(labels, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, wrapError("遍历地址标签", err)
+	}
+	return labels, total, nil
+}
+
+// 创建地址标签
+func createAddressLabel(db *sql.DB, req AddressLabelRequest) (*AddressLabel, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM address_label WHERE address = ?)", req.Address).Scan(&exists)
+	if err != nil {
+		return nil, wrapError("检查地址标签是否存在", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("地址 %s 的标签已存在", req.Address)
+	}
+
+	_, err = db.Exec("INSERT INTO address_label (address, label, category) VALUES (?, ?, ?)", req.Address, req.Label, req.Category)
+	if err != nil {
+		return nil, wrapError("创建地址标签", err)
+	}
+	return getAddressLabel(db, req.Address)
+}
+
+// 更新地址标签
+func updateAddressLabel(db *sql.DB, address string, req AddressLabelRequest) (*AddressLabel, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM address_label WHERE address = ?)", address).Scan(&exists)
+	if err != nil {
+		return nil, wrapError("检查地址标签是否存在", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("地址 %s 的标签不存在", address)
+	}
+
+	_, err = db.Exec("UPDATE address_label SET label = ?, category = ?, updated_at = CURRENT_TIMESTAMP WHERE address = ?", req.Label, req.Category, address)
+	if err != nil {
+		return nil, wrapError("更新地址标签", err)
+	}
+	return getAddressLabel(db, address)
+}
+
+// 删除地址标签
+func deleteAddressLabel(db *sql.DB, address string) error {
+	result, err := db.Exec("DELETE FROM address_label WHERE address = ?", address)
+	if err != nil {
+		return wrapError("删除地址标签", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return wrapError("获取删除行数", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("地址 %s 的标签不存在", address)
+	}
+	return nil
+}
+
+// 处理 /labels 请求：GET 查询标签列表，POST 创建标签。
+// 标签变化影响全部mint在 exclude_known=true 时的总数，写入成功后清空缓存的总数
+func handleAddressLabels(store *Storage, maxOffset int, registry *CollectionRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			query := r.URL.Query()
+			page, limit, err := parsePagination(r)
+			if err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+			offset := (page - 1) * limit
+			if !checkMaxOffset(w, offset, maxOffset) {
+				return
+			}
+
+			var labels []AddressLabel
+			var total int
+			err = retryRead(r.Context(), func() (err error) {
+				labels, total, err = listAddressLabels(store.Reader(), query.Get("category"), limit, offset)
+				return err
+			})
+			if err != nil {
+				logError("查询地址标签列表", err)
+				sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+					Success: false,
+					Error:   "查询数据失败",
+				})
+				return
+			}
+			sendJSONResponse(w, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    labels,
+				Total:   total,
+				Page:    page,
+				Limit:   limit,
+			})
+
+		case http.MethodPost:
+			var req AddressLabelRequest
+			if !requireJSONContentType(w, r) {
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				logError("Failed to decode request body", err)
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "Invalid JSON format",
+				})
+				return
+			}
+			if err := req.Validate(true); err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+
+			label, err := createAddressLabel(store.Writer(), req)
+			if err != nil {
+				logError("Failed to create address label", err)
+				if strings.Contains(err.Error(), "已存在") {
+					sendJSONResponse(w, http.StatusConflict, APIResponse{
+						Success: false,
+						Error:   err.Error(),
+					})
+				} else {
+					sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+						Success: false,
+						Error:   "Failed to create address label",
+					})
+				}
+				return
+			}
+			registry.resetCounts("")
+			sendMutationResponse(w, r, http.StatusCreated, APIResponse{
+				Success: true,
+				Data:    label,
+			})
+
+		default:
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+		}
+	}
+}
+
+// 处理 /labels/{address} 请求：GET 查询、PUT 更新、DELETE 删除，写入成功后清空缓存的总数
+func handleAddressLabel(store *Storage, registry *CollectionRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := strings.Trim(strings.TrimPrefix(r.URL.Path, "/labels/"), "/")
+		if address == "" || strings.Contains(address, "/") {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "Invalid address",
+			})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			var label *AddressLabel
+			err := retryRead(r.Context(), func() (err error) {
+				label, err = getAddressLabel(store.Reader(), address)
+				return err
+			})
+			if err != nil {
+				if strings.Contains(err.Error(), "不存在") {
+					sendJSONResponse(w, http.StatusNotFound, APIResponse{
+						Success: false,
+						Error:   err.Error(),
+					})
+					return
+				}
+				logError("查询地址标签", err)
+				sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+					Success: false,
+					Error:   "查询数据失败",
+				})
+				return
+			}
+			sendJSONResponse(w, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    label,
+			})
+
+		case http.MethodPut:
+			var req AddressLabelRequest
+			if !requireJSONContentType(w, r) {
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				logError("Failed to decode request body", err)
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "Invalid JSON format",
+				})
+				return
+			}
+			if err := req.Validate(false); err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+
+			label, err := updateAddressLabel(store.Writer(), address, req)
+			if err != nil {
+				logError("Failed to update address label", err)
+				if strings.Contains(err.Error(), "不存在") {
+					sendJSONResponse(w, http.StatusNotFound, APIResponse{
+						Success: false,
+						Error:   err.Error(),
+					})
+				} else {
+					sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+						Success: false,
+						Error:   "Failed to update address label",
+					})
+				}
+				return
+			}
+			registry.resetCounts("")
+			sendMutationResponse(w, r, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    label,
+			})
+
+		case http.MethodDelete:
+			if err := deleteAddressLabel(store.Writer(), address); err != nil {
+				logError("Failed to delete address label", err)
+				if strings.Contains(err.Error(), "不存在") {
+					sendJSONResponse(w, http.StatusNotFound, APIResponse{
+						Success: false,
+						Error:   err.Error(),
+					})
+				} else {
+					sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+						Success: false,
+						Error:   "Failed to delete address label",
+					})
+				}
+				return
+			}
+			registry.resetCounts("")
+			sendJSONResponse(w, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    map[string]string{"address": address},
+			})
+
+		default:
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+		}
+	}
+}
+
+// holderField 持有者列表中可通过 fields 参数选择的字段
+type holderField struct {
+	name   string                      // JSON字段名
+	column string                      // SELECT 中的列表达式
+	target func(h *Holder) interface{} // 扫描目标
+}
+
+// 持有者列表的全部字段，未指定 fields 时按此顺序查询。delegate 为 NULL 时读取为空字符串
+var holderFields = []holderField{
+	{"id", "h.id", func(h *Holder) interface{} { return &h.ID }},
+	{"mint", "h.mint", func(h *Holder) interface{} { return &h.Mint }},
+	{"pubkey", "h.pubkey", func(h *Holder) interface{} { return &h.Pubkey }},
+	{"lamports", "h.lamports", func(h *Holder) interface{} { return &h.Lamports }},
+	{"isNative", "h.is_native", func(h *Holder) interface{} { return &h.IsNative }},
+	{"owner", "h.owner", func(h *Holder) interface{} { return &h.Owner }},
+	{"state", "h.state", func(h *Holder) interface{} { return &h.State }},
+	{"decimals", "h.decimals", func(h *Holder) interface{} { return &h.Decimals }},
+	{"amount", "h.amount", func(h *Holder) interface{} { return &h.Amount }},
+	{"uiAmount", "h.ui_amount", func(h *Holder) interface{} { return &h.UIAmount }},
+	{"uiAmountString", "h.ui_amount_string", func(h *Holder) interface{} { return &h.UIAmountString }},
+	{"delegate", "COALESCE(h.delegate, '')", func(h *Holder) interface{} { return &h.Delegate }},
+	{"delegatedAmount", "h.delegated_amount", func(h *Holder) interface{} { return &h.DelegatedAmount }},
+	{"createdAt", "h.created_at", func(h *Holder) interface{} { return &h.CreatedAt }},
+	{"updatedAt", "h.updated_at", func(h *Holder) interface{} { return &h.UpdatedAt }},
+}
+
+// 解析 fields 参数（逗号分隔的JSON字段名），返回需要输出的字段；未指定时返回 nil 表示全部字段
+func parseHolderFields(raw string) ([]holderField, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var fields []holderField
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		found := false
+		for _, f := range holderFields {
+			if f.name == name {
+				fields = append(fields, f)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, len(holderFields))
+			for i, f := range holderFields {
+				names[i] = f.name
+			}
+			return nil, fmt.Errorf("无效的字段: %s，可选值: %s", name, strings.Join(names, ", "))
+		}
+		seen[name] = true
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("fields不能为空")
+	}
+	return fields, nil
+}
+
+// sort 参数可用的holder表排序列
+var holderSortColumns = []string{
+	"id", "mint", "pubkey", "owner", "state", "lamports", "decimals", "amount", "ui_amount",
+	"delegated_amount", "created_at", "updated_at", "first_seen_at",
+}
+
+// sort 参数最多包含的排序字段数，避免客户端构造过长的 ORDER BY
+const maxHolderSortKeys = 3
+
+// 解析 sort 参数（逗号分隔的列名，前缀 - 表示降序），返回 ORDER BY 之后的排序表达式；未指定时返回空字符串
+func parseHolderSort(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+	keys := strings.Split(raw, ",")
+	if len(keys) > maxHolderSortKeys {
+		return "", fmt.Errorf("sort最多支持%d个排序字段", maxHolderSortKeys)
+	}
+	var terms []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		dir := "ASC"
+		col := key
+		if strings.HasPrefix(key, "-") {
+			dir = "DESC"
+			col = key[1:]
+		}
+		valid := false
+		for _, c := range holderSortColumns {
+			if c == col {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("无效的排序字段: %s，可选值: %s", key, strings.Join(holderSortColumns, ", "))
+		}
+		if seen[col] {
+			return "", fmt.Errorf("排序字段重复: %s", col)
+		}
+		seen[col] = true
+		terms = append(terms, "h."+col+" "+dir)
+	}
+	return strings.Join(terms, ", "), nil
+}
+
+// 按 fields 投影持有者记录，label、closeAuthority、value_usd 等附加字段在对应选项开启时保留
+func projectHolder(h *Holder, fields []holderField) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields)+2)
+	for _, f := range fields {
+		out[f.name] = reflect.ValueOf(f.target(h)).Elem().Interface()
+	}
+	if h.Label != "" {
+		out["label"] = h.Label
+		out["labelCategory"] = h.LabelCategory
+	}
+	if h.CloseAuthority != "" {
+		out["closeAuthority"] = h.CloseAuthority
+	}
+	if h.Extensions != nil {
+		out["extensions"] = h.Extensions
+	}
+	if h.ValueUSD != nil {
+		out["value_usd"] = *h.ValueUSD
+	}
+	return out
+}
+
+// 在字段列表中补充缺少的字段（只用于查询，不输出）
+func appendHolderFields(fields []holderField, names ...string) []holderField {
+	result := append([]holderField(nil), fields...)
+	for _, name := range names {
+		exists := false
+		for _, f := range result {
+			if f.name == name {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+		for _, f := range holderFields {
+			if f.name == name {
+				result = append(result, f)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// MariaDB API处理
+func apiHandlerMariaDB(db *sql.DB, maxOffset int, registry *CollectionRegistry, prices *PriceFeed, excludedCategories []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET和HEAD方法",
+			})
+			return
+		}
+		query := r.URL.Query()
+		page, limit, err := parsePagination(r)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		offset := (page - 1) * limit
+		if !checkMaxOffset(w, offset, maxOffset) {
+			return
+		}
+		includeLabels := query.Get("include_labels") == "true"
+		includeExtensions := query.Get("include_extensions") == "true"
+		includeValue := query.Get("include_value") == "true"
+		if includeValue && prices == nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "未配置价格源(price_feed_url)，不支持include_value",
+			})
+			return
+		}
+		// 指定 fields 时只查询需要输出的列；include_value 需要额外读取 mint 和 ui_amount 计算价值
+		fields, err := parseHolderFields(query.Get("fields"))
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		orderBy, err := parseHolderSort(query.Get("sort"))
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		selected := holderFields
+		if fields != nil {
+			selected = fields
+			if includeValue {
+				selected = appendHolderFields(selected, "mint", "uiAmount")
+			}
+		}
+		columns := make([]string, len(selected))
+		for i, f := range selected {
+			columns[i] = f.column
+		}
+		baseQuery := "SELECT " + strings.Join(columns, ", ")
+		if includeExtensions {
+			baseQuery += ", h.close_authority, h.extensions"
+		}
+		if includeLabels {
+			// 按持有者钱包地址(owner)关联已知地址标签
+			baseQuery += ", l.label, l.category FROM holder h LEFT JOIN address_label l ON l.address = h.owner"
+		} else {
+			baseQuery += " FROM holder h"
+		}
+		var args []interface{}
+		var conds []string
+		if mint := query.Get("mint"); mint != "" {
+			conds = append(conds, "h.mint = ?")
+			args = append(args, mint)
+		}
+		owners, err := parseOwnerFilter(query["owner"])
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if len(owners) > 0 {
+			conds = append(conds, "h.owner IN (?"+strings.Repeat(", ?", len(owners)-1)+")")
+			for _, owner := range owners {
+				args = append(args, owner)
+			}
+		}
+		if state := query.Get("state"); state != "" {
+			conds = append(conds, "h.state = ?")
+			args = append(args, state)
+		}
+		exclude, err := parseExcludeKnown(r, excludedCategories)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if len(exclude) > 0 {
+			cond, condArgs := knownAddressCondition("h", exclude)
+			conds = append(conds, cond)
+			args = append(args, condArgs...)
+		}
+		if len(conds) > 0 {
+			baseQuery += " WHERE " + strings.Join(conds, " AND ")
+		}
+		if orderBy != "" {
+			baseQuery += " ORDER BY " + orderBy
+		}
+		baseQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+		// 获取总数
+		countQuery := "SELECT COUNT(*) FROM holder h"
+		if len(conds) > 0 {
+			countQuery += " WHERE " + strings.Join(conds, " AND ")
+		}
+		// 客户端断开后请求上下文被取消，查询随之中止并释放数据库连接。
+		// 总数按过滤条件缓存，翻页时不重复执行 COUNT(*)，该mint采集结束时失效
+		ctx := r.Context()
+		countKey := countQuery + "\x00" + fmt.Sprintf("%q", args)
+		total, err := registry.CachedCount(countKey, query.Get("mint"), func() (total int, err error) {
+			err = retryRead(ctx, func() error {
+				return db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+			})
+			return total, err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				logInfo("客户端已断开连接，停止查询持有者数据: %v", ctx.Err())
+				return
+			}
+			logError("查询总数", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询总数失败",
+			})
+			return
+		}
+
+		// 只需要总数时不查询数据行：HEAD 请求只返回响应头，count_only=true 只返回总数
+		if r.Method == http.MethodHead || query.Get("count_only") == "true" {
+			w.Header().Set("X-Total-Count", strconv.Itoa(total))
+			if r.Method == http.MethodHead {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			sendJSONResponse(w, http.StatusOK, APIResponse{
+				Success:              true,
+				Data:                 map[string]int{"total": total},
+				CollectionInProgress: query.Get("mint") != "" && registry.InProgress(query.Get("mint")),
+			})
+			return
+		}
+
+		var rows *sql.Rows
+		err = retryRead(ctx, func() (err error) {
+			rows, err = db.QueryContext(ctx, baseQuery, args...)
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				logInfo("客户端已断开连接，停止查询持有者数据: %v", ctx.Err())
+				return
+			}
+			logError("查询持有者数据", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+		defer rows.Close()
+
+		var holders []Holder
+		for rows.Next() {
+			if ctx.Err() != nil {
+				logInfo("客户端已断开连接，停止扫描持有者数据: %v", ctx.Err())
+				return
+			}
+			var h Holder
+			dest := make([]interface{}, 0, len(selected)+4)
+			for _, f := range selected {
+				dest = append(dest, f.target(&h))
+			}
+			var closeAuthority, extensions sql.NullString
+			if includeExtensions {
+				dest = append(dest, &closeAuthority, &extensions)
+			}
+			var label, category sql.NullString
+			if includeLabels {
+				dest = append(dest, &label, &category)
+			}
+			err := rows.Scan(dest...)
+			if err != nil {
+				logError("扫描数据行", err)
+				sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+					Success: false,
+					Error:   "数据解析失败",
+				})
+				return
+			}
+			if fields == nil && h.Delegate == "" {
+				h.DelegatedAmount = "" // 未授权时不返回授权额度
+			}
+			h.CloseAuthority = closeAuthority.String
+			if extensions.Valid {
+				h.Extensions = json.RawMessage(extensions.String)
+			}
+			h.Label = label.String
+			h.LabelCategory = category.String
+			holders = append(holders, h)
+		}
+
+		if err := rows.Err(); err != nil {
+			if ctx.Err() != nil {
+				logInfo("客户端已断开连接，停止扫描持有者数据: %v", ctx.Err())
+				return
+			}
+			logError("遍历查询结果", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "数据遍历失败",
+			})
+			return
+		}
+
+		if holders == nil {
+			holders = []Holder{}
+		}
+
+		// 价格源不可用时只省略 value_usd，不影响持有者数据的返回
+		if includeValue {
+			priceMap, err := prices.Prices(ctx)
+			if err != nil {
+				logError("获取价格", err)
+			} else {
+				annotateHolderValues(holders, priceMap)
+			}
+		}
+
+		var data interface{} = holders
+		if fields != nil {
+			projected := make([]map[string]interface{}, len(holders))
+			for i := range holders {
+				projected[i] = projectHolder(&holders[i], fields)
+			}
+			data = projected
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success:              true,
+			Data:                 data,
+			Total:                total,
+			Page:                 page,
+			Limit:                limit,
+			CollectionInProgress: query.Get("mint") != "" && registry.InProgress(query.Get("mint")),
+		})
+	}
+}
+
+// 单次查询最多支持的owner数量
+const maxOwnerFilter = 100
+
+// 解析owner过滤参数，支持重复参数和逗号分隔，去重并校验每个地址
+func parseOwnerFilter(values []string) ([]string, error) {
+	var owners []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, owner := range strings.Split(value, ",") {
+			owner = strings.TrimSpace(owner)
+			if owner == "" || seen[owner] {
+				continue
+			}
+			if err := validateSolanaAddress(owner); err != nil {
+				return nil, fmt.Errorf("owner参数错误: %v", err)
+			}
+			seen[owner] = true
+			owners = append(owners, owner)
+		}
+	}
+	if len(owners) > maxOwnerFilter {
+		return nil, fmt.Errorf("owner最多支持%d个地址", maxOwnerFilter)
+	}
+	return owners, nil
+}
+
+// 检查mint是否在spl视图中
+func splExists(db *sql.DB, mintAddress string) (bool, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM spl WHERE mint = ?", mintAddress).Scan(&count); err != nil {
+		return false, wrapError("查询SPL Token", err)
+	}
+	return count > 0, nil
+}
+
+// SPLToken spl视图中的一个Token
+type SPLToken struct {
+	Symbol string                `json:"symbol"`
+	Mint   string                `json:"mint"`
+	Status *SPLCollectionSummary `json:"status,omitempty"` // 仅在 include_status=true 时返回
+}
+
+// SPLCollectionSummary Token的最近采集情况，从未采集过时各字段为空
+type SPLCollectionSummary struct {
+	LastCollectedAt *time.Time `json:"last_collected_at"` // 最近一次成功采集的完成时间
+	LastHolderCount *int64     `json:"last_holder_count"` // 最近一次成功采集的持有者数
+	LastStatus      string     `json:"last_status,omitempty"`
+	LastError       string     `json:"last_error,omitempty"` // 最近一次采集失败时的错误信息，最近一次成功时为空
+}
+
+// 查询spl视图中的Token，includeStatus 为true时关联 collection_status 表附带最近的采集情况
+func getSPLToken(db *sql.DB, mintAddress string, includeStatus bool) (*SPLToken, error) {
+	var token SPLToken
+	var err error
+	if !includeStatus {
+		err = db.QueryRow("SELECT symbol, mint FROM spl WHERE mint = ? LIMIT 1", mintAddress).Scan(&token.Symbol, &token.Mint)
+	} else {
+		var lastCollectedAt sql.NullTime
+		var lastHolderCount sql.NullInt64
+		var lastStatus, lastError sql.NullString
+		err = db.QueryRow(`SELECT s.symbol, s.mint, ok.finished_at, ok.holder_count, last.status, last.error_message
+			FROM spl s
+			LEFT JOIN collection_status ok ON ok.id = (
+				SELECT id FROM collection_status WHERE mint = s.mint AND status = ? ORDER BY started_at DESC, id DESC LIMIT 1)
+			LEFT JOIN collection_status last ON last.id = (
+				SELECT id FROM collection_status WHERE mint = s.mint ORDER BY started_at DESC, id DESC LIMIT 1)
+			WHERE s.mint = ? LIMIT 1`, collectionStatusSuccess, mintAddress).
+			Scan(&token.Symbol, &token.Mint, &lastCollectedAt, &lastHolderCount, &lastStatus, &lastError)
+		token.Status = &SPLCollectionSummary{LastStatus: lastStatus.String}
+		if lastCollectedAt.Valid {
+			token.Status.LastCollectedAt = &lastCollectedAt.Time
+		}
+		if lastHolderCount.Valid {
+			token.Status.LastHolderCount = &lastHolderCount.Int64
+		}
+		if lastStatus.String != collectionStatusSuccess {
+			token.Status.LastError = lastError.String
+		}
+	}
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("SPL Token %s 不存在", mintAddress)
+	}
+	if err != nil {
+		return nil, wrapError("查询SPL Token", err)
+	}
+	return &token, nil
+}
+
+// 处理 GET /spls/{mint_address} 请求，返回Token信息，include_status=true 时附带最近的采集情况
+func handleSPLToken(w http.ResponseWriter, r *http.Request, db *sql.DB, mintAddress string) {
+	if r.Method != http.MethodGet {
+		sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+			Success: false,
+			Error:   "只支持GET方法",
+		})
+		return
+	}
+
+	var token *SPLToken
+	err := retryRead(r.Context(), func() (err error) {
+		token, err = getSPLToken(db, mintAddress, r.URL.Query().Get("include_status") == "true")
+		return err
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "不存在") {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{
+				Success: false,
+				Error:   "SPL Token不存在",
+			})
+			return
+		}
+		logError("查询SPL Token", err)
+		sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "查询数据失败",
+		})
+		return
+	}
+	sendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    token,
+	})
+}
+
+// SPLSummary Token概览：spl视图中的信息、holder表中的持有汇总和最近一次成功采集的时间
+type SPLSummary struct {
+	Symbol          string     `json:"symbol"`
+	Mint            string     `json:"mint"`
+	Decimals        *int       `json:"decimals"`          // 取自已采集的Token账户，尚未采集到账户时为null
+	HolderCount     int64      `json:"holder_count"`      // 余额大于0的Token账户数
+	DistinctOwners  int64      `json:"distinct_owners"`   // 余额大于0的不同钱包地址数
+	TotalAmount     string     `json:"total_amount"`      // 全部账户余额之和（原始数量）
+	TotalUIAmount   string     `json:"total_ui_amount"`   // 按精度换算后的余额之和
+	LastCollectedAt *time.Time `json:"last_collected_at"` // 最近一次成功采集的完成时间
+}
+
+// 汇总mint的Token概览，mint不在spl视图中时返回"不存在"错误
+func getSPLSummary(db *sql.DB, mintAddress string) (*SPLSummary, error) {
+	token, err := getSPLToken(db, mintAddress, true)
+	if err != nil {
+		return nil, err
+	}
+	summary := &SPLSummary{Symbol: token.Symbol, Mint: token.Mint, LastCollectedAt: token.Status.LastCollectedAt}
+
+	var decimals sql.NullInt64
+	var total string
+	err = db.QueryRow(`SELECT COUNT(*), COUNT(DISTINCT owner), COALESCE(SUM(amount), 0),
+			(SELECT MAX(decimals) FROM holder WHERE mint = ?)
+		FROM holder WHERE mint = ? AND amount > 0`, mintAddress, mintAddress).
+		Scan(&summary.HolderCount, &summary.DistinctOwners, &total, &decimals)
+	if err != nil {
+		return nil, wrapError("汇总持有者数据", err)
+	}
+
+	amount, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的余额合计: %s", total)
+	}
+	summary.TotalAmount = amount.String()
+	summary.TotalUIAmount = summary.TotalAmount
+	if decimals.Valid {
+		d := int(decimals.Int64)
+		summary.Decimals = &d
+		summary.TotalUIAmount = formatUIAmount(amount, d)
+	}
+	return summary, nil
+}
+
+// 处理 GET /spls/{mint_address}/summary 请求，一次返回Token概览卡片需要的数据
+func handleSPLSummary(w http.ResponseWriter, r *http.Request, db *sql.DB, mintAddress string) {
+	if r.Method != http.MethodGet {
+		sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+			Success: false,
+			Error:   "只支持GET方法",
+		})
+		return
+	}
+
+	var summary *SPLSummary
+	err := retryRead(r.Context(), func() (err error) {
+		summary, err = getSPLSummary(db, mintAddress)
+		return err
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "不存在") {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{
+				Success: false,
+				Error:   "SPL Token不存在",
+			})
+			return
+		}
+		logError("查询Token概览", err)
+		sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "查询数据失败",
+		})
+		return
+	}
+	sendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    summary,
+	})
+}
+
+// SPLExportVersion /spls/export 文档的格式版本，格式发生不兼容的变化时递增
+const SPLExportVersion = 1
+
+// SPLExport /spls/export 返回的跟踪Token配置文档，用于备份和在环境之间迁移spl视图的数据
+type SPLExport struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	SPLs       []SPLConfigEntry `json:"spls"`
+}
+
+// SPLConfigEntry spl视图中一个mint的配置，视图中没有的可选列或值为NULL时为null
+type SPLConfigEntry struct {
+	Symbol             string          `json:"symbol"`
+	Mint               string          `json:"mint"`
+	ProgramID          *string         `json:"program_id"`
+	RPCFilters         json.RawMessage `json:"rpc_filters"`
+	KeepTopN           *int64          `json:"keep_top_n"`
+	HolderDropAlertPct *float64        `json:"holder_drop_alert_pct"`
+}
+
+// 读取spl视图中的全部mint及可选采集配置列，按mint排序
+func exportSPLConfig(db *sql.DB) (*SPLExport, error) {
+	cols := []string{"program_id", "rpc_filters", "keep_top_n", "holder_drop_alert_pct"}
+	for i, col := range cols {
+		exists, err := checkColumnExists(db, "spl", col)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			cols[i] = "NULL"
+		}
+	}
+
+	rows, err := db.Query("SELECT symbol, mint, " + strings.Join(cols, ", ") + " FROM spl ORDER BY mint")
+	if err != nil {
+		return nil, wrapError("查询SPL配置", err)
+	}
+	defer rows.Close()
+
+	export := &SPLExport{Version: SPLExportVersion, ExportedAt: time.Now(), SPLs: []SPLConfigEntry{}}
+	for rows.Next() {
+		var entry SPLConfigEntry
+		var programID, filters sql.NullString
+		var keepTopN sql.NullInt64
+		var dropAlertPct sql.NullFloat64
+		if err := rows.Scan(&entry.Symbol, &entry.Mint, &programID, &filters, &keepTopN, &dropAlertPct); err != nil {
+			return nil, wrapError("扫描SPL配置", err)
+		}
+		if programID.Valid {
+			entry.ProgramID = &programID.String
+		}
+		// rpc_filters 原样导出，不是有效JSON时作为字符串导出，避免整个文档无法序列化
+		if filters.Valid {
+			if json.Valid([]byte(filters.String)) {
+				entry.RPCFilters = json.RawMessage(filters.String)
+			} else {
+				entry.RPCFilters, _ = json.Marshal(filters.String)
+			}
+		}
+		if keepTopN.Valid {
+			entry.KeepTopN = &keepTopN.Int64
+		}
+		if dropAlertPct.Valid {
+			entry.HolderDropAlertPct = &dropAlertPct.Float64
+		}
+		export.SPLs = append(export.SPLs, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历查询结果", err)
+	}
+	return export, nil
+}
+
+// 处理 GET /spls/export 请求，导出spl视图中全部mint的配置
+func handleSPLExport(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		var export *SPLExport
+		err := retryRead(r.Context(), func() (err error) {
+			export, err = exportSPLConfig(db)
+			return err
+		})
+		if err != nil {
+			logError("导出SPL配置", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    export,
+			Total:   len(export.SPLs),
+		})
+	}
+}
+
+// 处理 /spls/{mint_address}/holders 路由，等价于 /holders?mint={mint_address}，
+// 支持与 /holders 相同的分页、排序和过滤参数；/spls/{mint_address} 返回Token信息，
+// /spls/{mint_address}/summary 返回Token概览，/spls/{mint_address}/snapshot-root 和
+// /spls/{mint_address}/snapshot-proof 返回Merkle快照根和证明
+func handleSPLHolders(db *sql.DB, maxOffset int, registry *CollectionRegistry, prices *PriceFeed, excludedCategories []string) http.HandlerFunc {
+	holdersHandler := apiHandlerMariaDB(db, maxOffset, registry, prices, excludedCategories)
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/spls/")
+		parts := strings.Split(path, "/")
+		if len(parts) == 1 && parts[0] != "" {
+			handleSPLToken(w, r, db, parts[0])
+			return
+		}
+		if len(parts) == 2 && parts[0] != "" && parts[1] == "summary" {
+			handleSPLSummary(w, r, db, parts[0])
+			return
+		}
+		if len(parts) == 2 && parts[0] != "" && parts[1] == "snapshot-root" {
+			handleSnapshotRoot(w, r, db, parts[0])
+			return
+		}
+		if len(parts) == 2 && parts[0] != "" && parts[1] == "snapshot-proof" {
+			handleSnapshotProof(w, r, db, parts[0])
+			return
+		}
+		if len(parts) != 2 || parts[0] == "" || parts[1] != "holders" {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{
+				Success: false,
+				Error:   "Invalid URL format. Expected: /spls/{mint_address}, /spls/{mint_address}/holders, /spls/{mint_address}/summary, /spls/{mint_address}/snapshot-root or /spls/{mint_address}/snapshot-proof",
+			})
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET和HEAD方法",
+			})
+			return
+		}
+		mintAddress := parts[0]
+
+		var exists bool
+		err := retryRead(r.Context(), func() (err error) {
+			exists, err = splExists(db, mintAddress)
+			return err
+		})
+		if err != nil {
+			logError("查询SPL Token", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+		if !exists {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{
+				Success: false,
+				Error:   "SPL Token不存在",
+			})
+			return
+		}
+
+		// 路径中的mint优先于查询参数中的mint
+		query := r.URL.Query()
+		query.Set("mint", mintAddress)
+		r2 := r.Clone(r.Context())
+		r2.URL.RawQuery = query.Encode()
+		holdersHandler(w, r2)
+	}
+}
+
+// HolderMint holder表中出现的mint及其记录数
+type HolderMint struct {
+	Mint    string `json:"mint"`
+	Holders int64  `json:"holders"`
+	InSPL   bool   `json:"in_spl"` // false表示mint已不在spl视图中（孤立数据）
+}
+
+// 统计holder表中的所有mint及记录数，并标记是否仍在spl视图中
+func listHolderMints(db *sql.DB) ([]HolderMint, error) {
+	rows, err := db.Query(`SELECT h.mint, h.holders, EXISTS(SELECT 1 FROM spl WHERE spl.mint = h.mint)
+		FROM (SELECT mint, COUNT(*) AS holders FROM holder GROUP BY mint) h
+		ORDER BY h.holders DESC, h.mint`)
+	if err != nil {
+		return nil, wrapError("查询holder表中的mint", err)
+	}
+	defer rows.Close()
+
+	mints := []HolderMint{}
+	for rows.Next() {
+		var m HolderMint
+		if err := rows.Scan(&m.Mint, &m.Holders, &m.InSPL); err != nil {
+			return nil, wrapError("扫描mint", err)
+		}
+		mints = append(mints, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历查询结果", err)
+	}
+	return mints, nil
+}
+
+// HolderStats 单个mint余额大于0的持有者数及余额合计
+type HolderStats struct {
+	Mint            string `json:"mint"`
+	Holders         int64  `json:"holders"`
+	TotalSupplyHeld string `json:"total_supply_held"` // 按精度换算后的余额之和，保留全部小数位
+}
+
+// 按mint统计余额大于0的持有者数和余额合计，mintAddress 不为空时只统计该mint，exclude 不为空时排除这些分类的已知地址。
+// 合计按原始数量求和后再按精度换算，避免 ui_amount 只保留6位小数带来的误差
+func listHolderStats(db *sql.DB, mintAddress string, exclude []string) ([]HolderStats, error) {
+	query := "SELECT mint, COUNT(*), SUM(amount), MAX(decimals) FROM holder WHERE amount > 0"
+	var args []interface{}
+	if mintAddress != "" {
+		query += " AND mint = ?"
+		args = append(args, mintAddress)
+	}
+	if len(exclude) > 0 {
+		cond, condArgs := knownAddressCondition("holder", exclude)
+		query += " AND " + cond
+		args = append(args, condArgs...)
+	}
+	query += " GROUP BY mint ORDER BY mint"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, wrapError("统计持有者", err)
+	}
+	defer rows.Close()
+
+	stats := []HolderStats{}
+	for rows.Next() {
+		var s HolderStats
+		var total string
+		var decimals int
+		if err := rows.Scan(&s.Mint, &s.Holders, &total, &decimals); err != nil {
+			return nil, wrapError("扫描数据行", err)
+		}
+		amount, ok := new(big.Int).SetString(total, 10)
+		if !ok {
+			return nil, fmt.Errorf("无效的余额合计(mint: %s): %s", s.Mint, total)
+		}
+		s.TotalSupplyHeld = formatUIAmount(amount, decimals)
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历查询结果", err)
+	}
+	return stats, nil
+}
+
+// 处理按mint统计持有者的HTTP请求，支持 mint 参数只统计一个Token，exclude_known=true 时排除已知地址
+func handleHolderStats(db *sql.DB, excludedCategories []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+		exclude, err := parseExcludeKnown(r, excludedCategories)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		var stats []HolderStats
+		err = retryRead(r.Context(), func() (err error) {
+			stats, err = listHolderStats(db, r.URL.Query().Get("mint"), exclude)
+			return err
+		})
+		if err != nil {
+			logError("统计持有者", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    stats,
+			Total:   len(stats),
+		})
+	}
+}
+
+// 查询指定时间之后首次出现的持有者，按首次出现时间倒序分页返回
+func listNewHolders(db *sql.DB, mintAddress string, since time.Time, limit, offset int) ([]Holder, int, error) {
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM holder WHERE mint = ? AND first_seen_at > ?", mintAddress, since).Scan(&total); err != nil {
+		return nil, 0, wrapError("查询新增持有者总数", err)
+	}
+
+	rows, err := db.Query(`SELECT id, mint, pubkey, lamports, is_native, owner, state, decimals, amount, ui_amount, ui_amount_string, delegate, delegated_amount, created_at, updated_at, first_seen_at
+		FROM holder WHERE mint = ? AND first_seen_at > ?
+		ORDER BY first_seen_at DESC, id DESC LIMIT ? OFFSET ?`, mintAddress, since, limit, offset)
+	if err != nil {
+		return nil, 0, wrapError("查询新增持有者", err)
+	}
+	defer rows.Close()
+
+	holders := []Holder{}
+	for rows.Next() {
+		var h Holder
+		var firstSeenAt time.Time
+		var delegate sql.NullString
+		var delegatedAmount string
+		if err := rows.Scan(&h.ID, &h.Mint, &h.Pubkey, &h.Lamports, &h.IsNative, &h.Owner, &h.State, &h.Decimals,
+			&h.Amount, &h.UIAmount, &h.UIAmountString, &delegate, &delegatedAmount, &h.CreatedAt, &h.UpdatedAt, &firstSeenAt); err != nil {
+			return nil, 0, wrapError("扫描数据行", err)
+		}
+		h.setDelegate(delegate, delegatedAmount)
+		h.FirstSeenAt = &firstSeenAt
+		holders = append(holders, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, wrapError("遍历查询结果", err)
+	}
+	return holders, total, nil
+}
+
+// MultiTokenOwner 持有多个被跟踪mint的钱包地址
+type MultiTokenOwner struct {
+	Owner     string   `json:"owner"`
+	MintCount int      `json:"mint_count"`
+	Mints     []string `json:"mints"`
+}
+
+// 只统计余额大于0且仍在spl视图中的持有记录
+const multiHolderWhere = "amount > 0 AND mint IN (SELECT mint FROM spl)"
+
+// 查询持有至少 minTokens 个不同mint的owner，按持有mint数量倒序分页返回，并附带各自持有的mint列表。
+// exclude 不为空时排除这些分类的已知地址
+func listMultiTokenOwners(db *sql.DB, minTokens, limit, offset int, exclude []string) ([]MultiTokenOwner, int, error) {
+	where := multiHolderWhere
+	var whereArgs []interface{}
+	if len(exclude) > 0 {
+		cond, condArgs := knownAddressCondition("holder", exclude)
+		where += " AND " + cond
+		whereArgs = condArgs
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM (
+		SELECT owner FROM holder WHERE `+where+`
+		GROUP BY owner HAVING COUNT(DISTINCT mint) >= ?) t`, append(whereArgs, minTokens)...).Scan(&total); err != nil {
+		return nil, 0, wrapError("查询多币种持有者总数", err)
+	}
+
+	rows, err := db.Query(`SELECT owner, COUNT(DISTINCT mint) AS mint_count FROM holder
+		WHERE `+where+`
+		GROUP BY owner HAVING COUNT(DISTINCT mint) >= ?
+		ORDER BY mint_count DESC, owner LIMIT ? OFFSET ?`, append(whereArgs, minTokens, limit, offset)...)
+	if err != nil {
+		return nil, 0, wrapError("查询多币种持有者", err)
+	}
+	defer rows.Close()
+
+	owners := []MultiTokenOwner{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var o MultiTokenOwner
+		if err := rows.Scan(&o.Owner, &o.MintCount); err != nil {
+			return nil, 0, wrapError("扫描数据行", err)
+		}
+		o.Mints = []string{}
+		index[o.Owner] = len(owners)
+		owners = append(owners, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, wrapError("遍历查询结果", err)
+	}
+	if len(owners) == 0 {
+		return owners, total, nil
+	}
+
+	// 第二次查询本页owner持有的mint，避免 GROUP_CONCAT 受 group_concat_max_len 截断
+	args := append([]interface{}{}, whereArgs...)
+	for _, o := range owners {
+		args = append(args, o.Owner)
+	}
+	mintRows, err := db.Query(`SELECT DISTINCT owner, mint FROM holder
+		WHERE `+where+` AND owner IN (?`+strings.Repeat(", ?", len(owners)-1)+`)
+		ORDER BY owner, mint`, args...)
+	if err != nil {
+		return nil, 0, wrapError("查询持有的mint", err)
+	}
+	defer mintRows.Close()
+	for mintRows.Next() {
+		var owner, mint string
+		if err := mintRows.Scan(&owner, &mint); err != nil {
+			return nil, 0, wrapError("扫描数据行", err)
+		}
+		if i, ok := index[owner]; ok {
+			owners[i].Mints = append(owners[i].Mints, mint)
+		}
+	}
+	if err := mintRows.Err(); err != nil {
+		return nil, 0, wrapError("遍历查询结果", err)
+	}
+	return owners, total, nil
+}
+
+// 处理多币种持有者查询的HTTP请求，min_tokens 默认为2，exclude_known=true 时排除已知地址
+func handleMultiTokenOwners(db *sql.DB, maxOffset int, excludedCategories []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		query := r.URL.Query()
+		minTokens := 2
+		if raw := query.Get("min_tokens"); raw != "" {
+			var err error
+			minTokens, err = strconv.Atoi(raw)
+			if err != nil || minTokens < 1 {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "min_tokens必须是正整数",
+				})
+				return
+			}
+		}
+
+		page, limit, err := parsePagination(r)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		offset := (page - 1) * limit
+		if !checkMaxOffset(w, offset, maxOffset) {
+			return
+		}
+		exclude, err := parseExcludeKnown(r, excludedCategories)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		var owners []MultiTokenOwner
+		var total int
+		err = retryRead(r.Context(), func() (err error) {
+			owners, total, err = listMultiTokenOwners(db, minTokens, limit, offset, exclude)
+			return err
+		})
+		if err != nil {
+			logError("查询多币种持有者", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    owners,
+			Total:   total,
+			Page:    page,
+			Limit:   limit,
+		})
+	}
+}
+
+// HolderOverlap 两个mint持有者(owner)的重叠情况，只统计余额大于0的记录
+type HolderOverlap struct {
+	MintA  string   `json:"mint_a"`
+	MintB  string   `json:"mint_b"`
+	Both   int      `json:"both"`             // 同时持有两个mint的owner数
+	OnlyA  int      `json:"only_a"`           // 只持有mint_a的owner数
+	OnlyB  int      `json:"only_b"`           // 只持有mint_b的owner数
+	Owners []string `json:"owners,omitempty"` // include_owners=true 时返回同时持有两个mint的owner，按地址排序分页
+}
+
+// 统计两个mint持有者的交集和各自独有的owner数。按owner分组，一次扫描得到三个数量
+func countHolderOverlap(db *sql.DB, mintA, mintB string) (*HolderOverlap, error) {
+	overlap := &HolderOverlap{MintA: mintA, MintB: mintB}
+	err := db.QueryRow(`SELECT
+			COUNT(CASE WHEN has_a = 1 AND has_b = 1 THEN 1 END),
+			COUNT(CASE WHEN has_a = 1 AND has_b = 0 THEN 1 END),
+			COUNT(CASE WHEN has_a = 0 AND has_b = 1 THEN 1 END)
+		FROM (
+			SELECT owner, MAX(mint = ?) AS has_a, MAX(mint = ?) AS has_b
+			FROM holder WHERE mint IN (?, ?) AND amount > 0
+			GROUP BY owner
+		) t`, mintA, mintB, mintA, mintB).Scan(&overlap.Both, &overlap.OnlyA, &overlap.OnlyB)
+	if err != nil {
+		return nil, wrapError("统计持有者重叠", err)
+	}
+	return overlap, nil
+}
+
+// 分页查询同时持有两个mint的owner，按地址排序
+func listOverlapOwners(db *sql.DB, mintA, mintB string, limit, offset int) ([]string, error) {
+	rows, err := db.Query(`SELECT owner FROM holder
+		WHERE mint IN (?, ?) AND amount > 0
+		GROUP BY owner HAVING COUNT(DISTINCT mint) = 2
+		ORDER BY owner LIMIT ? OFFSET ?`, mintA, mintB, limit, offset)
+	if err != nil {
+		return nil, wrapError("查询同时持有两个mint的owner", err)
+	}
+	defer rows.Close()
+
+	owners := []string{}
+	for rows.Next() {
+		var owner string
+		if err := rows.Scan(&owner); err != nil {
+			return nil, wrapError("扫描数据行", err)
+		}
+		owners = append(owners, owner)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历查询结果", err)
+	}
+	return owners, nil
+}
+
+// 处理两个mint持有者重叠查询的HTTP请求，include_owners=true 时按 page、limit 分页返回重叠的owner
+func handleHolderOverlap(db *sql.DB, maxOffset int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		query := r.URL.Query()
+		mintA, mintB := query.Get("mint_a"), query.Get("mint_b")
+		if mintA == "" || mintB == "" {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "mint_a和mint_b不能为空",
+			})
+			return
+		}
+		if mintA == mintB {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "mint_a和mint_b不能相同",
+			})
+			return
+		}
+		includeOwners := query.Get("include_owners") == "true"
+
+		var page, limit, offset int
+		if includeOwners {
+			var err error
+			page, limit, err = parsePagination(r)
+			if err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+			offset = (page - 1) * limit
+			if !checkMaxOffset(w, offset, maxOffset) {
+				return
+			}
+		}
+
+		var overlap *HolderOverlap
+		err := retryRead(r.Context(), func() (err error) {
+			overlap, err = countHolderOverlap(db, mintA, mintB)
+			if err != nil || !includeOwners {
+				return err
+			}
+			overlap.Owners, err = listOverlapOwners(db, mintA, mintB, limit, offset)
+			return err
+		})
+		if err != nil {
+			logError("查询持有者重叠", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		resp := APIResponse{Success: true, Data: overlap}
+		if includeOwners {
+			resp.Total, resp.Page, resp.Limit = overlap.Both, page, limit
+		}
+		sendJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
+// 查询mint最近一次成功采集的开始时间
+func lastSuccessfulCollectionStart(db *sql.DB, mintAddress string) (time.Time, error) {
+	var startedAt time.Time
+	err := db.QueryRow(`SELECT started_at FROM collection_status
+		WHERE mint = ? AND status = ? ORDER BY started_at DESC LIMIT 1`, mintAddress, collectionStatusSuccess).Scan(&startedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, fmt.Errorf("mint %s 的成功采集记录不存在", mintAddress)
+	}
+	if err != nil {
+		return time.Time{}, wrapError("查询最近一次采集记录", err)
+	}
+	return startedAt, nil
+}
+
+// 持有者流式导出每批从数据库读取的行数，单次导出的内存占用与该值成正比
+const holderStreamBatchSize = 1000
+
+// 流式导出每写一批前延长的写超时。服务器的 WriteTimeout 针对普通请求，大型mint导出耗时远超过它
+const holderStreamWriteTimeout = 30 * time.Second
+
+// holderStreamEnd NDJSON流的最后一行。NextCursor 为空表示已导出全部记录
+type holderStreamEnd struct {
+	NextCursor string `json:"next_cursor"`
+	Error      string `json:"error,omitempty"`
+}
+
+// 处理持有者流式导出的HTTP请求（GET /holders/stream），以NDJSON格式按id升序逐行输出持有者，
+// 最后一行为 {"next_cursor": "..."}。游标即最后一条记录的id（keyset分页），导出中断时
+// 客户端也可以用已收到的最后一行的id继续导出，不会重复或遗漏。
+// 响应按请求时的数据实时生成，两次请求的字节内容不同，因此不支持 Range 请求，断点续传使用 cursor
+func handleHolderStream(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		query := r.URL.Query()
+		mintAddress := query.Get("mint")
+		if mintAddress == "" {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "mint不能为空",
+			})
+			return
+		}
+		var cursor int64
+		if raw := query.Get("cursor"); raw != "" {
+			v, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || v < 0 {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "cursor必须是非负整数",
+				})
+				return
+			}
+			cursor = v
+		}
+		// limit 限制本次响应的最大行数，0表示导出到末尾
+		var limit int
+		if raw := query.Get("limit"); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil || v < 0 {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "limit必须是非负整数",
+				})
+				return
+			}
+			limit = v
+		}
+
+		columns := make([]string, len(holderFields))
+		for i, f := range holderFields {
+			columns[i] = f.column
+		}
+		selectQuery := "SELECT " + strings.Join(columns, ", ") + " FROM holder h WHERE h.mint = ? AND h.id > ? ORDER BY h.id LIMIT ?"
+
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+		// 忽略请求中的 Range 头并返回完整响应，告知下载工具不要按字节偏移续传
+		w.Header().Set("Accept-Ranges", "none")
+		w.WriteHeader(http.StatusOK)
+		rc := http.NewResponseController(w)
+		enc := json.NewEncoder(w)
+
+		// 响应头已发送，之后的错误只能写在最后一行
+		ctx := r.Context()
+		written := 0
+		for {
+			batch := holderStreamBatchSize
+			if limit > 0 && limit-written < batch {
+				batch = limit - written
+			}
+			if batch == 0 {
+				enc.Encode(holderStreamEnd{NextCursor: strconv.FormatInt(cursor, 10)})
+				return
+			}
+
+			// 不支持设置写超时（如测试中的ResponseRecorder）时忽略
+			rc.SetWriteDeadline(time.Now().Add(holderStreamWriteTimeout))
+			n, last, err := streamHolderBatch(ctx, db, enc, selectQuery, mintAddress, cursor, batch)
+			written += n
+			if n > 0 {
+				cursor = last
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					logInfo("客户端已断开连接，停止导出持有者数据: %v", ctx.Err())
+					return
+				}
+				logError("导出持有者数据", err)
+				enc.Encode(holderStreamEnd{NextCursor: strconv.FormatInt(cursor, 10), Error: "查询数据失败"})
+				return
+			}
+			rc.Flush()
+			if n < batch {
+				enc.Encode(holderStreamEnd{})
+				return
+			}
+		}
+	}
+}
+
+// 读取并输出一批持有者，返回输出的行数和最后一条记录的id
+func streamHolderBatch(ctx context.Context, db *sql.DB, enc *json.Encoder, selectQuery, mintAddress string, cursor int64, batch int) (int, int64, error) {
+	rows, err := db.QueryContext(ctx, selectQuery, mintAddress, cursor, batch)
+	if err != nil {
+		return 0, cursor, wrapError("查询持有者数据", err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var h Holder
+		dest := make([]interface{}, len(holderFields))
+		for i, f := range holderFields {
+			dest[i] = f.target(&h)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return n, cursor, wrapError("扫描数据行", err)
+		}
+		if h.Delegate == "" {
+			h.DelegatedAmount = ""
+		}
+		if err := enc.Encode(h); err != nil {
+			return n, cursor, wrapError("写入响应", err)
+		}
+		n++
+		cursor = h.ID
+	}
+	if err := rows.Err(); err != nil {
+		return n, cursor, wrapError("遍历查询结果", err)
+	}
+	return n, cursor, nil
+}
+
+// 处理新增持有者查询的HTTP请求。未指定since时返回最近一次成功采集中首次出现的持有者
+func handleNewHolders(db *sql.DB, maxOffset int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		query := r.URL.Query()
+		mintAddress := query.Get("mint")
+		if mintAddress == "" {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "mint不能为空",
+			})
+			return
+		}
+
+		var since time.Time
+		if raw := query.Get("since"); raw != "" {
+			var err error
+			since, err = time.Parse(time.RFC3339, raw)
+			if err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "since格式错误，应为RFC3339格式，例如 2025-09-09T12:00:00+08:00",
+				})
+				return
+			}
+		} else {
+			// 最近一次成功采集开始之后首次出现的记录即为该周期新增的持有者
+			var err error
+			since, err = lastSuccessfulCollectionStart(db, mintAddress)
+			if err != nil {
+				if strings.Contains(err.Error(), "不存在") {
+					sendJSONResponse(w, http.StatusNotFound, APIResponse{
+						Success: false,
+						Error:   err.Error(),
+					})
+					return
+				}
+				logError("查询最近一次采集记录", err)
+				sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+					Success: false,
+					Error:   "查询数据失败",
+				})
+				return
+			}
+		}
+
+		page, limit, err := parsePagination(r)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		offset := (page - 1) * limit
+		if !checkMaxOffset(w, offset, maxOffset) {
+			return
+		}
+
+		var holders []Holder
+		var total int
+		err = retryRead(r.Context(), func() (err error) {
+			holders, total, err = listNewHolders(db, mintAddress, since, limit, offset)
+			return err
+		})
+		if err != nil {
+			logError("查询新增持有者", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    holders,
+			Total:   total,
+			Page:    page,
+			Limit:   limit,
+		})
+	}
+}
+
+// 处理holder表mint列表的HTTP请求
+func handleHolderMints(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		var mints []HolderMint
+		err := retryRead(r.Context(), func() (err error) {
+			mints, err = listHolderMints(db)
+			return err
+		})
+		if err != nil {
+			logError("查询holder表中的mint", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		if r.URL.Query().Get("orphaned") == "true" {
+			orphaned := []HolderMint{}
+			for _, m := range mints {
+				if !m.InSPL {
+					orphaned = append(orphaned, m)
+				}
+			}
+			mints = orphaned
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    mints,
+			Total:   len(mints),
+		})
+	}
+}
+
+// HolderTier 持有量阈值及达到该阈值的账户数
+type HolderTier struct {
+	MinAmount float64 `json:"min_amount"`
+	Holders   int64   `json:"holders"`
+}
+
+// 解析逗号分隔的阈值列表，要求为严格递增的正数
+func parseTiers(raw string) ([]float64, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("tiers不能为空")
+	}
+	parts := strings.Split(raw, ",")
+	if len(parts) > 20 {
+		return nil, fmt.Errorf("tiers最多支持20个阈值")
+	}
+	tiers := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("无效的阈值: %s，必须为正数", part)
+		}
+		if len(tiers) > 0 && v <= tiers[len(tiers)-1] {
+			return nil, fmt.Errorf("tiers必须按升序排列且不能重复")
+		}
+		tiers = append(tiers, v)
+	}
+	return tiers, nil
+}
+
+// 统计指定mint下持有量达到各阈值的账户数（单条条件计数SQL），exclude 不为空时排除这些分类的已知地址
+func countHolderTiers(db *sql.DB, mintAddress string, tiers []float64, exclude []string) ([]HolderTier, error) {
+	cols := make([]string, len(tiers))
+	args := make([]interface{}, 0, len(tiers)+1)
+	for i, tier := range tiers {
+		cols[i] = "COALESCE(SUM(CASE WHEN ui_amount >= ? THEN 1 ELSE 0 END), 0)"
+		args = append(args, tier)
+	}
+	args = append(args, mintAddress)
+
+	counts := make([]int64, len(tiers))
+	dest := make([]interface{}, len(tiers))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	query := "SELECT " + strings.Join(cols, ", ") + " FROM holder WHERE mint = ?"
+	if len(exclude) > 0 {
+		cond, condArgs := knownAddressCondition("holder", exclude)
+		query += " AND " + cond
+		args = append(args, condArgs...)
+	}
+	if err := db.QueryRow(query, args...).Scan(dest...); err != nil {
+		return nil, wrapError("统计持有量分布", err)
+	}
+
+	result := make([]HolderTier, len(tiers))
+	for i, tier := range tiers {
+		result[i] = HolderTier{MinAmount: tier, Holders: counts[i]}
+	}
+	return result, nil
+}
+
+// 处理持有量阈值统计的HTTP请求，exclude_known=true 时排除已知地址
+func handleHolderTiers(db *sql.DB, excludedCategories []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		query := r.URL.Query()
+		mintAddress := query.Get("mint")
+		if mintAddress == "" {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "mint不能为空",
+			})
+			return
+		}
+		tiers, err := parseTiers(query.Get("tiers"))
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		exclude, err := parseExcludeKnown(r, excludedCategories)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		var result []HolderTier
+		err = retryRead(r.Context(), func() (err error) {
+			result, err = countHolderTiers(db, mintAddress, tiers, exclude)
+			return err
+		})
+		if err != nil {
+			logError("统计持有量分布", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"mint":  mintAddress,
+				"tiers": result,
+			},
+		})
+	}
+}
+
+// HistogramBucket 持有量分布直方图中的一个区间 [Lower, Upper)，最后一个区间包含上界
+type HistogramBucket struct {
+	Lower   float64 `json:"lower"`
+	Upper   float64 `json:"upper"`
+	Holders int64   `json:"holders"`
+}
+
+// HolderHistogram 持有量分布直方图
+type HolderHistogram struct {
+	Mint     string            `json:"mint"`
+	Scale    string            `json:"scale"`
+	Min      float64           `json:"min"`
+	Max      float64           `json:"max"`
+	Excluded int64             `json:"excluded"` // 对数刻度下被排除的零余额账户数
+	Buckets  []HistogramBucket `json:"buckets"`
+}
+
+const (
+	histogramScaleLinear = "linear"
+	histogramScaleLog    = "log"
+	maxHistogramBuckets  = 100
+)
+
+// 计算指定mint的持有量分布直方图，分桶在SQL中完成，只返回各桶计数。exclude 不为空时排除这些分类的已知地址
+func buildHolderHistogram(db *sql.DB, mintAddress string, bucketCount int, scale string, exclude []string) (*HolderHistogram, error) {
+	mintWhere := "mint = ?"
+	whereArgs := []interface{}{mintAddress}
+	if len(exclude) > 0 {
+		cond, condArgs := knownAddressCondition("holder", exclude)
+		mintWhere += " AND " + cond
+		whereArgs = append(whereArgs, condArgs...)
+	}
+	// 对数刻度下只统计正余额，并对 LOG10(ui_amount) 分桶
+	valueExpr := "ui_amount"
+	where := mintWhere
+	if scale == histogramScaleLog {
+		valueExpr = "LOG10(ui_amount)"
+		where = mintWhere + " AND ui_amount > 0"
+	}
+
+	histogram := &HolderHistogram{Mint: mintAddress, Scale: scale, Buckets: []HistogramBucket{}}
+
+	var total int64
+	var minValue, maxValue sql.NullFloat64
+	err := db.QueryRow(
+		"SELECT COUNT(*), MIN("+valueExpr+"), MAX("+valueExpr+") FROM holder WHERE "+where,
+		whereArgs...,
+	).Scan(&total, &minValue, &maxValue)
+	if err != nil {
+		return nil, wrapError("统计持有量范围", err)
+	}
+	if scale == histogramScaleLog {
+		var all int64
+		if err := db.QueryRow("SELECT COUNT(*) FROM holder WHERE "+mintWhere, whereArgs...).Scan(&all); err != nil {
+			return nil, wrapError("统计持有者数量", err)
+		}
+		histogram.Excluded = all - total
+	}
+	if total == 0 {
+		return histogram, nil
+	}
+
+	lo, hi := minValue.Float64, maxValue.Float64
+	width := (hi - lo) / float64(bucketCount)
+	counts := make([]int64, bucketCount)
+	if width == 0 {
+		// 所有余额相同，全部落入第一个桶
+		bucketCount = 1
+		counts = []int64{total}
+	} else {
+		rows, err := db.Query(
+			"SELECT LEAST(FLOOR(("+valueExpr+" - ?) / ?), ?) AS bucket, COUNT(*) FROM holder WHERE "+where+" GROUP BY bucket",
+			append([]interface{}{lo, width, bucketCount - 1}, whereArgs...)...,
+		)
+		if err != nil {
+			return nil, wrapError("统计持有量分布", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var bucket, count int64
+			if err := rows.Scan(&bucket, &count); err != nil {
+				return nil, wrapError("扫描分布数据", err)
+			}
+			if bucket < 0 {
+				bucket = 0
+			}
+			counts[bucket] += count
+		}
+		if err := rows.Err(); err != nil {
+			return nil, wrapError("遍历分布数据", err)
+		}
+	}
+
+	bound := func(v float64) float64 {
+		if scale == histogramScaleLog {
+			return math.Pow(10, v)
+		}
+		return v
+	}
+	histogram.Min, histogram.Max = bound(lo), bound(hi)
+	for i := 0; i < bucketCount; i++ {
+		upper := lo + width*float64(i+1)
+		if i == bucketCount-1 {
+			upper = hi
+		}
+		histogram.Buckets = append(histogram.Buckets, HistogramBucket{
+			Lower:   bound(lo + width*float64(i)),
+			Upper:   bound(upper),
+			Holders: counts[i],
+		})
+	}
+	return histogram, nil
+}
+
+// 处理持有量分布直方图的HTTP请求，exclude_known=true 时排除已知地址
+func handleHolderHistogram(db *sql.DB, excludedCategories []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		query := r.URL.Query()
+		mintAddress := query.Get("mint")
+		if mintAddress == "" {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "mint不能为空",
+			})
+			return
+		}
+
+		buckets := 20
+		if bucketsStr := query.Get("buckets"); bucketsStr != "" {
+			n, err := strconv.Atoi(bucketsStr)
+			if err != nil || n < 1 || n > maxHistogramBuckets {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   fmt.Sprintf("buckets必须在1-%d之间", maxHistogramBuckets),
+				})
+				return
+			}
+			buckets = n
+		}
+
+		scale := query.Get("scale")
+		if scale == "" {
+			scale = histogramScaleLinear
+		}
+		if scale != histogramScaleLinear && scale != histogramScaleLog {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "scale必须是linear或log",
+			})
+			return
+		}
+		exclude, err := parseExcludeKnown(r, excludedCategories)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		var histogram *HolderHistogram
+		err = retryRead(r.Context(), func() (err error) {
+			histogram, err = buildHolderHistogram(db, mintAddress, buckets, scale, exclude)
+			return err
+		})
+		if err != nil {
+			logError("统计持有量分布直方图", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    histogram,
+		})
+	}
+}
+
+// 采集事务的原子性：best-effort 跳过写入失败的账户并提交其余记录，
+// strict 任意账户写入失败时回滚该mint的整个事务，保留上一次采集的数据
+const (
+	CollectionAtomicityBestEffort = "best-effort"
+	CollectionAtomicityStrict     = "strict"
+)
+
+// getProgramAccounts 支持的账户数据编码
+const (
+	RPCEncodingJSONParsed = "jsonParsed"
+	RPCEncodingBase64     = "base64"
+)
+
+// SPL Token 账户二进制布局（共165字节，Token-2022 账户在其后追加扩展数据）
+const (
+	tokenAccountSize            = 165
+	tokenAccountDelegateTag     = 72  // delegate: COption<Pubkey> 的标记位(u32)，公钥紧随其后
+	tokenAccountStateOffset     = 108 // state: u8
+	tokenAccountNativeTag       = 109 // is_native: COption<u64> 的标记位(u32)
+	tokenAccountDelegatedAmount = 121 // delegated_amount: u64
+	tokenAccountCloseAuthority  = 129 // close_authority: COption<Pubkey> 的标记位(u32)，公钥紧随其后
+	mintDecimalsOffset          = 44  // Mint 账户中 decimals 的偏移量
+)
+
+// 账户状态枚举值，与 jsonParsed 返回的字符串保持一致
+var tokenAccountStates = []string{"uninitialized", "initialized", "frozen"}
+
+// ParseCollectStates 解析逗号分隔的账户状态列表（不区分大小写），空字符串表示采集全部状态
+func ParseCollectStates(raw string) ([]string, error) {
+	var states []string
+	for _, part := range strings.Split(raw, ",") {
+		state := strings.ToLower(strings.TrimSpace(part))
+		if state == "" {
+			continue
+		}
+		valid := false
+		for _, s := range tokenAccountStates {
+			if s == state {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("无效的账户状态: %s，可选值: %s", part, strings.Join(tokenAccountStates, ", "))
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// parseMinUIAmount 解析 min_ui_amount（十进制数，如 0.01），空字符串表示不过滤
+func parseMinUIAmount(raw string) (*big.Rat, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	v, ok := new(big.Rat).SetString(raw)
+	if !ok || strings.Contains(raw, "/") {
+		return nil, fmt.Errorf("无效的最小余额: %s", raw)
+	}
+	if v.Sign() < 0 {
+		return nil, fmt.Errorf("最小余额不能为负数: %s", raw)
+	}
+	if v.Sign() == 0 {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// minRawAmount 把最小余额按decimals换算为原始数量，向上取整，原始数量小于该值的账户视为零头
+func minRawAmount(minUIAmount *big.Rat, decimals int) *big.Int {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	scaled := new(big.Rat).Mul(minUIAmount, new(big.Rat).SetInt(scale))
+	q, r := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if r.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58编码（Solana地址格式）
+func base58Encode(b []byte) string {
+	x := new(big.Int).SetBytes(b)
+	base := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, base, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	// 前导零字节编码为 '1'
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58解码
+func base58Decode(str string) ([]byte, error) {
+	x := new(big.Int)
+	base := big.NewInt(58)
+	for _, c := range str {
+		idx := strings.IndexRune(base58Alphabet, c)
+		if idx < 0 {
+			return nil, fmt.Errorf("无效的base58字符: %q", c)
+		}
+		x.Mul(x, base)
+		x.Add(x, big.NewInt(int64(idx)))
+	}
+	// 前导 '1' 解码为零字节
+	leading := 0
+	for leading < len(str) && str[leading] == base58Alphabet[0] {
+		leading++
+	}
+	return append(make([]byte, leading), x.Bytes()...), nil
+}
+
+// 校验Solana地址：base58编码的32字节公钥
+func validateSolanaAddress(address string) error {
+	if address == "" {
+		return fmt.Errorf("地址不能为空")
+	}
+	decoded, err := base58Decode(address)
+	if err != nil {
+		return fmt.Errorf("无效的地址 %s: %v", address, err)
+	}
+	if len(decoded) != 32 {
+		return fmt.Errorf("无效的地址 %s: 解码后长度为%d字节，应为32字节", address, len(decoded))
+	}
+	return nil
+}
+
+// 按精度将原始数量格式化为 UI 字符串（去除末尾多余的0），与 RPC 的 uiAmountString 格式一致
+func formatUIAmount(amount *big.Int, decimals int) string {
+	digits := new(big.Int).Abs(amount).String()
+	if decimals <= 0 {
+		return digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	intPart := digits[:len(digits)-decimals]
+	fracPart := strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fracPart == "" {
+		return intPart
+	}
+	return intPart + "." + fracPart
+}
+
+// 解析 SPL Token 账户的原始数据。账户数据中不包含精度，需调用 applyDecimals 补全
+func parseTokenAccount(data []byte) (Info, error) {
+	if len(data) < tokenAccountSize {
+		return Info{}, fmt.Errorf("账户数据长度%d不足%d字节", len(data), tokenAccountSize)
+	}
+	state := int(data[tokenAccountStateOffset])
+	if state >= len(tokenAccountStates) {
+		return Info{}, fmt.Errorf("无效的账户状态: %d", state)
+	}
+	amount := binary.LittleEndian.Uint64(data[64:72])
+	info := Info{
+		Mint:     base58Encode(data[0:32]),
+		Owner:    base58Encode(data[32:64]),
+		State:    tokenAccountStates[state],
+		IsNative: binary.LittleEndian.Uint32(data[tokenAccountNativeTag:tokenAccountNativeTag+4]) == 1,
+		TokenAmount: TokenAmount{
+			Amount: BigAmount{i: new(big.Int).SetUint64(amount)},
+		},
+	}
+	if binary.LittleEndian.Uint32(data[tokenAccountDelegateTag:tokenAccountDelegateTag+4]) == 1 {
+		delegated := binary.LittleEndian.Uint64(data[tokenAccountDelegatedAmount : tokenAccountDelegatedAmount+8])
+		info.Delegate = base58Encode(data[tokenAccountDelegateTag+4 : tokenAccountDelegateTag+36])
+		info.DelegatedAmount = &TokenAmount{Amount: BigAmount{i: new(big.Int).SetUint64(delegated)}}
+	}
+	if binary.LittleEndian.Uint32(data[tokenAccountCloseAuthority:tokenAccountCloseAuthority+4]) == 1 {
+		info.CloseAuthority = base58Encode(data[tokenAccountCloseAuthority+4 : tokenAccountCloseAuthority+36])
+	}
+	return info, nil
+}
+
+// 根据精度补全 decimals、uiAmount 和 uiAmountString（包括授权额度）
+func applyDecimals(info *Info, decimals int) {
+	info.TokenAmount.Decimals = decimals
+	info.TokenAmount.UIAmountString = formatUIAmount(info.TokenAmount.Amount.Int(), decimals)
+	info.TokenAmount.UIAmount, _ = strconv.ParseFloat(info.TokenAmount.UIAmountString, 64)
+	if info.DelegatedAmount != nil {
+		info.DelegatedAmount.Decimals = decimals
+		info.DelegatedAmount.UIAmountString = formatUIAmount(info.DelegatedAmount.Amount.Int(), decimals)
+		info.DelegatedAmount.UIAmount, _ = strconv.ParseFloat(info.DelegatedAmount.UIAmountString, 64)
+	}
+}
+
+// decodeRawAccounts 将以原始字节返回的账户解析为 Parsed 结构。
+// 精度优先取同一响应中已由 RPC 解析的账户，没有时再查询 mint 账户
+func decodeRawAccounts(ctx context.Context, client *SolanaRPCClient, mintAddress string, items []ResultItem) error {
+	decimals := -1
+	rawCount := 0
+	for _, item := range items {
+		if item.Account.Data.Raw != nil {
+			rawCount++
+		} else if item.Account.Data.Parsed.Type == "account" && decimals < 0 {
+			decimals = item.Account.Data.Parsed.Info.TokenAmount.Decimals
+		}
+	}
+	if rawCount == 0 {
+		return nil
+	}
+	if decimals < 0 {
+		var err error
+		decimals, err = fetchMintDecimals(ctx, client, mintAddress)
+		if err != nil {
+			return wrapError("获取mint精度", err)
+		}
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.Account.Data.Raw == nil {
+			continue
+		}
+		info, err := parseTokenAccount(item.Account.Data.Raw)
+		if err == nil && info.Mint != mintAddress {
+			err = fmt.Errorf("账户所属mint %s 与请求的mint不一致", info.Mint)
+		}
+		if err != nil {
+			logError(fmt.Sprintf("解析账户数据(pubkey: %s)", item.Pubkey), err)
+			continue // 解析失败的记录没有 Parsed.Type，后续会被跳过
+		}
+		applyDecimals(&info, decimals)
+		item.Account.Data.Parsed = Parsed{Type: "account", Info: info}
+	}
+	logDebug("mint地址 %s: 本地解析了 %d 个原始账户数据", mintAddress, rawCount)
+	return nil
+}
+
+// fetchMintDecimals 通过 getAccountInfo 读取 mint 账户的精度
+func fetchMintDecimals(ctx context.Context, client *SolanaRPCClient, mintAddress string) (int, error) {
+	data, err := client.GetAccountData(ctx, mintAddress)
+	if err != nil {
+		return 0, err
+	}
+	if data == nil {
+		return 0, fmt.Errorf("mint账户 %s 不存在", mintAddress)
+	}
+	if len(data) <= mintDecimalsOffset {
+		return 0, fmt.Errorf("mint账户数据长度%d无效", len(data))
+	}
+	decimals := int(data[mintDecimalsOffset])
+	if err := validateDecimals(decimals); err != nil {
+		return 0, err
+	}
+	return decimals, nil
+}
+
+// fetchProgramAccounts 调用 getProgramAccounts 获取并解析mint的全部Token账户
+// minSlot 不为 nil 时请求附带 withContext，并以已见过的最高slot作为 minContextSlot，
+// 拒绝落后节点返回的旧快照；成功后记录并返回本次响应的slot（未附带 withContext 时为0）
+func fetchProgramAccounts(ctx context.Context, config *Config, client *SolanaRPCClient, mintAddress string, opts MintOptions, minSlot *slotTracker) ([]ResultItem, uint64, error) {
+	if mintAddress == "" {
+		return nil, 0, fmt.Errorf("mint地址不能为空")
+	}
+
+	programID, err := opts.tokenProgram()
+	if err != nil {
+		return nil, 0, err
+	}
+	filters, err := buildProgramAccountsFilters(mintAddress, opts.RPCFilters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rpcOpts := ProgramAccountsOptions{Encoding: config.RPCEncoding, MaxResponseBytes: config.RPCMaxResponseBytes}
+	var requiredSlot uint64
+	if minSlot != nil {
+		requiredSlot = minSlot.Load()
+		rpcOpts.WithContext = true
+		rpcOpts.MinContextSlot = requiredSlot
+	}
+
+	// 可选：解析前把原始响应归档到磁盘，归档失败不影响采集
+	var archive *rawArchive
+	if config.ArchiveDir != "" {
+		rpcOpts.WrapBody = func(body io.Reader) io.Reader {
+			var archiveErr error
+			if archive, archiveErr = newRawArchive(config.ArchiveDir, mintAddress); archiveErr != nil {
+				logError("创建原始响应归档", archiveErr)
+				return body
+			}
+			return io.TeeReader(body, archive)
+		}
+		defer func() {
+			if archive != nil {
+				archive.Close(config.ArchiveRetentionDays, config.ArchiveMaxFiles)
+			}
+		}()
+	}
+
+	if profile := collectionProfileFromContext(ctx); profile != nil {
+		rpcOpts.Timing = &profile.RPC
+	}
+
+	logInfo("开始获取 SPL token 账户信息: %s (程序: %s)", mintAddress, programID)
+
+	result, err := client.GetProgramAccounts(ctx, programID, filters, rpcOpts)
+	if err != nil {
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == rpcErrMinContextSlotNotReached {
+			return nil, 0, fmt.Errorf("RPC节点落后于已见过的最高slot %d, 拒绝使用旧快照: %s", requiredSlot, rpcErr.Message)
+		}
+		// result 为 null 或缺失通常意味着节点出错或不支持该方法，不能当作没有持有者，
+		// 否则会被记录为一次成功的空采集
+		if errors.Is(err, errNullRPCResult) {
+			logInfo("警告: mint地址 %s 的 getProgramAccounts 响应中 result 为 null", mintAddress)
+		}
+		return nil, 0, err
+	}
+	if minSlot != nil {
+		minSlot.Observe(result.ContextSlot)
+	}
+	if len(result.Accounts) == 0 {
+		return nil, result.ContextSlot, nil
+	}
+
+	// 解析以原始字节返回的账户（base64 编码，或 jsonParsed 下 RPC 无法解析的账户）
+	if err := decodeRawAccounts(ctx, client, mintAddress, result.Accounts); err != nil {
+		return nil, 0, wrapError("解析原始账户数据", err)
+	}
+	return result.Accounts, result.ContextSlot, nil
+}
+
+// errNullRPCResult getProgramAccounts 响应中没有错误信息，但 result 为 null 或缺失
+var errNullRPCResult = errors.New("RPC响应的result为null，节点可能出错或不支持该方法")
+
+// programAccountsResponse 解析后的 getProgramAccounts 响应
+type programAccountsResponse struct {
+	RPCResponse
+	ContextSlot uint64 // withContext 时节点返回数据对应的slot
+	HasResult   bool   // 账户数组存在且不为null，用于区分 null 和空数组 []
+}
+
+// decodeProgramAccountsResponse 流式解析 getProgramAccounts 响应，逐个解码 result 中的账户，
+// 不需要把整个响应体缓存在解码器中。withContext 为 true 时 result 为 {"context":{"slot":N},"value":[...]}。
+// 响应在账户数组结束前中断（如连接中途断开）时返回错误，调用方据此放弃整次采集，
+// 不会只提交已解析的部分账户
+func decodeProgramAccountsResponse(r io.Reader, withContext bool) (programAccountsResponse, error) {
+	var resp programAccountsResponse
+	dec := json.NewDecoder(r)
+
+	err := decodeJSONObject(dec, func(key string) error {
+		switch key {
+		case "jsonrpc":
+			return dec.Decode(&resp.Jsonrpc)
+		case "id":
+			return dec.Decode(&resp.ID)
+		case "error":
+			return dec.Decode(&resp.Error)
+		case "result":
+			if !withContext {
+				items, ok, err := decodeAccountArray(dec)
+				resp.Result, resp.HasResult = items, ok
+				return err
+			}
+			return decodeJSONObject(dec, func(key string) error {
+				switch key {
+				case "context":
+					var rpcContext struct {
+						Slot uint64 `json:"slot"`
+					}
+					err := dec.Decode(&rpcContext)
+					resp.ContextSlot = rpcContext.Slot
+					return err
+				case "value":
+					items, ok, err := decodeAccountArray(dec)
+					resp.Result, resp.HasResult = items, ok
+					return err
+				default:
+					var skip json.RawMessage
+					return dec.Decode(&skip)
+				}
+			})
+		default:
+			var skip json.RawMessage
+			return dec.Decode(&skip)
+		}
+	})
+	if err != nil {
+		return programAccountsResponse{}, err
+	}
+	return resp, nil
+}
+
+// decodeJSONObject 逐个读取JSON对象的键，由 decodeValue 解码对应的值。值为 null 时直接返回
+func decodeJSONObject(dec *json.Decoder, decodeValue func(key string) error) error {
+	tok, err := readJSONToken(dec)
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("期望JSON对象, 实际为: %v", tok)
+	}
+	for dec.More() {
+		tok, err := readJSONToken(dec)
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("无效的JSON对象键: %v", tok)
+		}
+		if err := decodeValue(key); err != nil {
+			return fmt.Errorf("解析字段 %s: %w", key, err)
+		}
+	}
+	return expectJSONDelim(dec, '}')
+}
+
+// decodeAccountArray 逐个解码账户数组，必须读到结束的 ']' 才算成功。值为 null 时 ok 为 false
+func decodeAccountArray(dec *json.Decoder) (items []ResultItem, ok bool, err error) {
+	tok, err := readJSONToken(dec)
+	if err != nil {
+		return nil, false, err
+	}
+	if tok == nil {
+		return nil, false, nil
+	}
+	if delim, isDelim := tok.(json.Delim); !isDelim || delim != '[' {
+		return nil, false, fmt.Errorf("期望账户数组, 实际为: %v", tok)
+	}
+	for dec.More() {
+		var item ResultItem
+		if err := dec.Decode(&item); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, false, fmt.Errorf("解析第 %d 个账户: %w", len(items)+1, err)
+		}
+		items = append(items, item)
+	}
+	if err := expectJSONDelim(dec, ']'); err != nil {
+		return nil, false, fmt.Errorf("账户数组不完整(已解析 %d 个账户): %w", len(items), err)
+	}
+	return items, true, nil
+}
+
+// readJSONToken 读取下一个JSON token，响应提前结束时返回 io.ErrUnexpectedEOF
+func readJSONToken(dec *json.Decoder) (json.Token, error) {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return tok, err
+}
+
+func expectJSONDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := readJSONToken(dec)
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("期望 '%v', 实际为: %v", want, tok)
+	}
+	return nil
+}
+
+// fetchAndStoreData 从 RPC 获取数据并存入数据库
+func fetchAndStoreData(ctx context.Context, config *Config, db *sql.DB, client *SolanaRPCClient, mintAddress string, opts MintOptions, minSlot *slotTracker) (CollectionResult, error) {
+	var result CollectionResult
+
+	// 按阶段记录耗时，用于判断该mint的瓶颈在RPC节点还是数据库；未启用时不做任何计时
+	var profile *collectionProfile
+	if config.ProfileCollection {
+		profile = &collectionProfile{}
+		ctx = withCollectionProfile(ctx, profile)
+		start := time.Now()
+		defer func() {
+			logInfo("[run:%s] mint地址 %s 分阶段耗时: RPC请求 %v，JSON解析 %v，数据库写入 %v，合计 %v",
+				runIDFromContext(ctx), mintAddress, profile.RPC.Request, profile.RPC.Decode, profile.DBUpsert, time.Since(start))
+		}()
+	}
+
+	// 保存Merkle快照根和发布采集事件时需要记录响应的slot，未启用 enforce_min_slot 时只跟踪本次请求
+	if (config.SnapshotRoots || config.EventBrokerURL != "") && minSlot == nil {
+		minSlot = &slotTracker{}
+	}
+
+	items, contextSlot, err := fetchProgramAccounts(ctx, config, client, mintAddress, opts, minSlot)
+	if err != nil {
+		return result, err
+	}
+	if len(items) == 0 {
+		logInfo("[run:%s] mint地址 %s 未发现持有者记录", runIDFromContext(ctx), mintAddress)
+		return result, nil
+	}
+
+	// 使用事务批量更新
+	var dbStart time.Time
+	if profile != nil {
+		dbStart = time.Now()
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, wrapError("开始数据库事务", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logError("回滚事务", err)
+		}
+	}()
+
+	// 只保留余额最大的前N个持有者：按余额降序排列，超出部分不入库，
+	// 提交前再删除数据库中排在N名之后的旧记录
+	keepTopN := config.KeepTopN
+	if opts.KeepTopN.Valid {
+		keepTopN = int(opts.KeepTopN.Int64)
+	}
+	if keepTopN > 0 {
+		sortByAmountDesc(items)
+	}
+
+	result, minRaw, err := upsertHolders(tx, config, mintAddress, items, keepTopN)
+	if err != nil {
+		return result, err
+	}
+
+	if keepTopN > 0 {
+		deleted, err := evictHoldersBeyondTopN(tx, mintAddress, keepTopN)
+		if err != nil {
+			return result, err
+		}
+		result.Evicted += int(deleted)
+	}
+
+	// 之前入库、余额已降到阈值以下的账户不会再被更新，一并删除以免保留旧余额
+	if minRaw != nil {
+		res, err := tx.Exec("DELETE FROM holder WHERE mint = ? AND amount < ?", mintAddress, minRaw.String())
+		if err != nil {
+			return result, wrapError("删除低于最小余额的持有者记录", err)
+		}
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return result, wrapError("获取删除行数", err)
+		}
+		if deleted > 0 {
+			logInfo("mint地址 %s: 删除 %d 条余额低于 %s 的旧记录", mintAddress, deleted, config.MinUIAmount)
+		}
+	}
+
+	// 本次未重写、仍带着其他精度的记录按旧精度计算的ui_amount已失效，提交前按本次采集的精度重新计算
+	recomputed, err := recomputeStaleDecimals(ctx, tx, mintAddress, collectedDecimals(items))
+	if err != nil {
+		return result, err
+	}
+	result.DecimalsChanged = recomputed > 0
+
+	if err := tx.Commit(); err != nil {
+		return result, wrapError("提交数据库事务", err)
+	}
+	// 并发采集时共享的最高slot可能来自其他mint，只使用本mint响应的slot
+	result.Slot = contextSlot
+	if profile != nil {
+		profile.DBUpsert = time.Since(dbStart)
+	}
+	atomicity := config.CollectionAtomicity
+	if atomicity == "" {
+		atomicity = CollectionAtomicityBestEffort
+	}
+	logInfo("[run:%s] mint地址 %s (%s): 成功处理 %d 条记录，跳过 %d 条记录，按状态过滤 %d 条记录，低于最小余额过滤 %d 条记录，超出前%d名丢弃 %d 条记录",
+		runIDFromContext(ctx), mintAddress, atomicity, result.Upserted, result.Skipped, result.Filtered, result.BelowMin, keepTopN, result.Evicted)
+
+	// Merkle快照根在提交后按入库余额计算，保存失败不影响本次采集结果
+	if config.SnapshotRoots {
+		if snapshot, err := storeSnapshotRoot(ctx, db, mintAddress, contextSlot); err != nil {
+			logError(fmt.Sprintf("[run:%s] 保存mint地址 %s 的Merkle快照根", runIDFromContext(ctx), mintAddress), err)
+		} else if snapshot != nil {
+			logInfo("[run:%s] mint地址 %s Merkle快照根: %s (%d 个账户)", runIDFromContext(ctx), mintAddress, snapshot.Root, snapshot.LeafCount)
+		}
+	}
+	// SOL余额同样在提交后按入库的owner查询，失败不影响本次采集结果
+	if config.CollectNativeBalance {
+		if updated, err := storeNativeBalances(ctx, db, client, mintAddress); err != nil {
+			logError(fmt.Sprintf("[run:%s] 保存mint地址 %s 持有者的SOL余额(已更新 %d 个)", runIDFromContext(ctx), mintAddress, updated), err)
+		} else {
+			logInfo("[run:%s] mint地址 %s: 更新 %d 个持有者的SOL余额", runIDFromContext(ctx), mintAddress, updated)
+		}
+	}
+	return result, nil
+}
+
+// collectedDecimals 返回本次采集的账户精度，没有Token账户时返回-1
+func collectedDecimals(items []ResultItem) int {
+	for _, item := range items {
+		if item.Account.Data.Parsed.Type == "account" {
+			return item.Account.Data.Parsed.Info.TokenAmount.Decimals
+		}
+	}
+	return -1
+}
+
+// recomputeStaleDecimals mint精度变化通常说明之前入库的数据有误。本次采集写入后，该mint仍带着
+// 其他精度的记录就是未被重写的旧记录，输出告警日志，并按本次采集的精度重新计算它们的
+// decimals、ui_amount 和 ui_amount_string。重新计算后不再有精度不一致的记录，告警不会在后续采集中重复。
+// 返回重新计算的记录数，没有Token账户时不检查
+func recomputeStaleDecimals(ctx context.Context, tx *sql.Tx, mintAddress string, collected int) (int, error) {
+	if collected < 0 {
+		return 0, nil
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT id, amount, decimals FROM holder WHERE mint = ? AND decimals <> ?", mintAddress, collected)
+	if err != nil {
+		return 0, wrapError("查询精度不一致的持有者记录", err)
+	}
+	type staleRow struct {
+		id     int64
+		amount string
+	}
+	var stale []staleRow
+	seen := map[int]bool{}
+	var oldDecimals []int
+	for rows.Next() {
+		var row staleRow
+		var decimals int
+		if err := rows.Scan(&row.id, &row.amount, &decimals); err != nil {
+			rows.Close()
+			return 0, wrapError("扫描数据行", err)
+		}
+		stale = append(stale, row)
+		if !seen[decimals] {
+			seen[decimals] = true
+			oldDecimals = append(oldDecimals, decimals)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, wrapError("遍历查询结果", err)
+	}
+	rows.Close()
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	logError("数据质量告警", fmt.Errorf("[run:%s] mint地址 %s 的精度由 %v 变为 %d，%d 条未重写的记录按新精度重新计算ui_amount",
+		runIDFromContext(ctx), mintAddress, oldDecimals, collected, len(stale)))
+	for _, row := range stale {
+		amount, ok := new(big.Int).SetString(row.amount, 10)
+		if !ok {
+			return 0, fmt.Errorf("无效的amount(id: %d): %s", row.id, row.amount)
+		}
+		uiAmount := formatUIAmount(amount, collected)
+		if _, err := tx.Exec("UPDATE holder SET decimals = ?, ui_amount = ?, ui_amount_string = ? WHERE id = ?",
+			collected, uiAmount, uiAmount, row.id); err != nil {
+			return 0, wrapError(fmt.Sprintf("重新计算持有者记录(id: %d)的ui_amount", row.id), err)
+		}
+	}
+	return len(stale), nil
+}
+
+// upsertHolders 在事务中逐条写入一次采集得到的账户，按账户类型、collect_states、min_ui_amount 和
+// keep_top_n 筛选。返回写入统计和按该mint精度换算的最小原始数量（未设置 min_ui_amount 时为nil）
+func upsertHolders(tx *sql.Tx, config *Config, mintAddress string, items []ResultItem, keepTopN int) (CollectionResult, *big.Int, error) {
+	var result CollectionResult
+
+	// 余额低于 min_ui_amount 的零头账户不入库，阈值按该mint的decimals换算为原始数量比较
+	minUIAmount, err := parseMinUIAmount(config.MinUIAmount)
+	if err != nil {
+		return result, nil, err
+	}
+	var minRaw *big.Int
+
+	for _, item := range items {
+		if item.Account.Data.Parsed.Type != "account" {
+			result.Skipped++
+			continue
+		}
+		if !config.shouldCollectState(item.Account.Data.Parsed.Info.State) {
+			result.Filtered++
+			continue
+		}
+		if minUIAmount != nil {
+			amount := item.Account.Data.Parsed.Info.TokenAmount
+			if minRaw == nil {
+				minRaw = minRawAmount(minUIAmount, amount.Decimals)
+			}
+			if amount.Amount.Int().Cmp(minRaw) < 0 {
+				result.BelowMin++
+				continue
+			}
+		}
+		if keepTopN > 0 && result.Upserted >= keepTopN {
+			result.Evicted++
+			continue
+		}
+		if err := upsertHolderMariaDB(tx, mintAddress, item); err != nil {
+			if config.CollectionAtomicity == CollectionAtomicityStrict {
+				// 返回后由defer回滚，本次已写入的记录全部撤销
+				return CollectionResult{}, nil, wrapError(fmt.Sprintf("更新记录(pubkey: %s)，strict模式回滚整个mint", item.Pubkey), err)
+			}
+			logError(fmt.Sprintf("更新记录(pubkey: %s)", item.Pubkey), err)
+			result.Skipped++
+			continue // 采集失败时跳过该条
+		}
+		result.Upserted++
+	}
+	return result, minRaw, nil
+}
+
+// 按余额降序排列账户，余额相同时保持原有顺序
+func sortByAmountDesc(items []ResultItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Account.Data.Parsed.Info.TokenAmount.Amount.Int().Cmp(
+			items[j].Account.Data.Parsed.Info.TokenAmount.Amount.Int()) > 0
+	})
+}
+
+// VerifyReport 数据库与链上实时数据的一致性比对结果
+type VerifyReport struct {
+	Mint        string     `json:"mint"`
+	OnChain     int        `json:"on_chain"`      // 按采集规则应入库的链上账户数
+	Stored      int        `json:"stored"`        // 数据库中的记录数
+	Matching    int        `json:"matching"`      // 余额一致
+	Divergent   int        `json:"divergent"`     // 两边都存在但余额不一致
+	MissingInDB int        `json:"missing_in_db"` // 链上存在但数据库中没有
+	StaleInDB   int        `json:"stale_in_db"`   // 数据库中存在但链上已不存在
+	KeepTopN    int        `json:"keep_top_n,omitempty"`
+	Samples     VerifyDiff `json:"samples"` // 每类最多返回前20个pubkey
+	CheckedAt   time.Time  `json:"checked_at"`
+}
+
+// VerifyDiff 各类差异的示例pubkey
+type VerifyDiff struct {
+	Divergent   []string `json:"divergent"`
+	MissingInDB []string `json:"missing_in_db"`
+	StaleInDB   []string `json:"stale_in_db"`
+}
+
+const verifyMaxSamples = 20
+
+// verifyHolders 获取mint的链上持有者并与数据库中的记录比对，不写入任何数据。
+// 链上账户按与采集相同的规则筛选（账户类型、collect_states、min_ui_amount、keep_top_n）
+func verifyHolders(ctx context.Context, config *Config, db *sql.DB, client *SolanaRPCClient, mintAddress string, opts MintOptions) (*VerifyReport, error) {
+	minUIAmount, err := parseMinUIAmount(config.MinUIAmount)
+	if err != nil {
+		return nil, err
+	}
+	items, _, err := fetchProgramAccounts(ctx, config, client, mintAddress, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{
+		Mint:      mintAddress,
+		Samples:   VerifyDiff{Divergent: []string{}, MissingInDB: []string{}, StaleInDB: []string{}},
+		CheckedAt: time.Now(),
+	}
+	report.KeepTopN = config.KeepTopN
+	if opts.KeepTopN.Valid {
+		report.KeepTopN = int(opts.KeepTopN.Int64)
+	}
+	if report.KeepTopN > 0 {
+		sortByAmountDesc(items)
+	}
+
+	onChain := make(map[string]string, len(items))
+	var minRaw *big.Int
+	for _, item := range items {
+		if item.Account.Data.Parsed.Type != "account" || !config.shouldCollectState(item.Account.Data.Parsed.Info.State) {
+			continue
+		}
+		// 低于 min_ui_amount 的零头账户不会入库，也不计入链上账户
+		if minUIAmount != nil {
+			amount := item.Account.Data.Parsed.Info.TokenAmount
+			if minRaw == nil {
+				minRaw = minRawAmount(minUIAmount, amount.Decimals)
+			}
+			if amount.Amount.Int().Cmp(minRaw) < 0 {
+				continue
+			}
+		}
+		if report.KeepTopN > 0 && len(onChain) >= report.KeepTopN {
+			break
+		}
+		onChain[item.Pubkey] = item.Account.Data.Parsed.Info.TokenAmount.Amount.String()
+	}
+	report.OnChain = len(onChain)
+
+	rows, err := db.QueryContext(ctx, "SELECT pubkey, amount FROM holder WHERE mint = ?", mintAddress)
+	if err != nil {
+		return nil, wrapError("查询持有者数据", err)
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool, len(onChain))
+	for rows.Next() {
+		var pubkey, amount string
+		if err := rows.Scan(&pubkey, &amount); err != nil {
+			return nil, wrapError("扫描数据行", err)
+		}
+		report.Stored++
+		chainAmount, ok := onChain[pubkey]
+		switch {
+		case !ok:
+			report.StaleInDB++
+			if len(report.Samples.StaleInDB) < verifyMaxSamples {
+				report.Samples.StaleInDB = append(report.Samples.StaleInDB, pubkey)
+			}
+		case chainAmount != amount:
+			seen[pubkey] = true
+			report.Divergent++
+			if len(report.Samples.Divergent) < verifyMaxSamples {
+				report.Samples.Divergent = append(report.Samples.Divergent, pubkey)
+			}
+		default:
+			seen[pubkey] = true
+			report.Matching++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历查询结果", err)
+	}
+
+	for pubkey := range onChain {
+		if seen[pubkey] {
+			continue
+		}
+		report.MissingInDB++
+		if len(report.Samples.MissingInDB) < verifyMaxSamples {
+			report.Samples.MissingInDB = append(report.Samples.MissingInDB, pubkey)
+		}
+	}
+	sort.Strings(report.Samples.MissingInDB)
+	return report, nil
+}
+
+// 处理数据一致性核对的HTTP请求
+func handleVerifyHolders(config *Config, db *sql.DB) http.HandlerFunc {
+	client := NewSolanaRPCClient(config.RPCURL, &http.Client{Timeout: 60 * time.Second})
+	client.SetMaxRetries(config.RPCMaxRetries)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+			return
+		}
+
+		mintAddress := r.URL.Query().Get("mint")
+		if mintAddress == "" {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "mint不能为空",
+			})
+			return
+		}
+
+		var exists bool
+		err := retryRead(r.Context(), func() (err error) {
+			exists, err = splExists(db, mintAddress)
+			return err
+		})
+		if err != nil {
+			logError("查询spl记录", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "Database query failed",
+			})
+			return
+		}
+		if !exists {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{
+				Success: false,
+				Error:   "mint不存在",
+			})
+			return
+		}
+
+		mintOptions, err := getMintOptions(db)
+		if err != nil {
+			logError("获取mint采集配置", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "Database query failed",
+			})
+			return
+		}
+
+		report, err := verifyHolders(r.Context(), config, db, client, mintAddress, mintOptions[mintAddress])
+		if err != nil {
+			logError(fmt.Sprintf("核对mint地址 %s", mintAddress), err)
+			sendJSONResponse(w, http.StatusBadGateway, APIResponse{
+				Success: false,
+				Error:   "Failed to verify holders",
+			})
+			return
+		}
+
+		logInfo("mint地址 %s 核对完成: 链上 %d，数据库 %d，一致 %d，余额不一致 %d，数据库缺失 %d，数据库过期 %d",
+			mintAddress, report.OnChain, report.Stored, report.Matching, report.Divergent, report.MissingInDB, report.StaleInDB)
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    report,
+		})
+	}
+}
+
+// rawArchive 把一次 getProgramAccounts 的原始响应以gzip格式写入
+// <dir>/<mint>/<UTC时间戳>.json.gz，写入完成前使用临时文件名
+type rawArchive struct {
+	file    *os.File
+	gz      *gzip.Writer
+	dir     string
+	tmpPath string
+	path    string
+}
+
+func newRawArchive(baseDir, mintAddress string) (*rawArchive, error) {
+	dir := filepath.Join(baseDir, mintAddress)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, wrapError("创建归档目录", err)
+	}
+	name := time.Now().UTC().Format("20060102T150405.000Z") + ".json.gz"
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, wrapError("创建归档文件", err)
+	}
+	return &rawArchive{file: file, gz: gzip.NewWriter(file), dir: dir, tmpPath: path + ".tmp", path: path}, nil
+}
+
+func (a *rawArchive) Write(p []byte) (int, error) {
+	return a.gz.Write(p)
+}
+
+// Close 完成归档文件，并按保留天数和最大文件数清理该mint的旧归档
+func (a *rawArchive) Close(retentionDays, maxFiles int) {
+	if err := a.gz.Close(); err != nil {
+		logError("写入归档文件", err)
+	}
+	if err := a.file.Close(); err != nil {
+		logError("关闭归档文件", err)
+	}
+	if err := os.Rename(a.tmpPath, a.path); err != nil {
+		logError("保存归档文件", err)
+		return
+	}
+	if removed, err := pruneRawArchives(a.dir, retentionDays, maxFiles); err != nil {
+		logError("清理旧归档文件", err)
+	} else if removed > 0 {
+		logDebug("删除 %d 个旧归档文件: %s", removed, a.dir)
+	}
+}
+
+// 删除目录中超过保留天数的归档，并只保留最新的maxFiles个；参数为0表示不限制
+func pruneRawArchives(dir string, retentionDays, maxFiles int) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	// 文件名为UTC时间戳，按名称排序即按时间排序
+	var archives []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json.gz") {
+			archives = append(archives, entry.Name())
+		}
+	}
+	sort.Strings(archives)
+
+	removed := 0
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	for i, name := range archives {
+		expired := maxFiles > 0 && len(archives)-i > maxFiles
+		if !expired && retentionDays > 0 {
+			info, err := os.Stat(filepath.Join(dir, name))
+			if err != nil {
+				return removed, err
+			}
+			expired = info.ModTime().Before(cutoff)
+		}
+		if !expired {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// CollectionResult 单个mint一次采集的处理结果
+type CollectionResult struct {
+	Upserted int
+	Skipped  int
+	Filtered int
+	BelowMin int // 余额低于 min_ui_amount 未入库的账户数
+	Evicted  int // 因 keep_top_n 未入库或被删除的记录数
+	// 存在与本次采集精度不一致的旧记录，已在提交前按新精度重新计算
+	DecimalsChanged bool
+	Slot            uint64 // 本mint的getProgramAccounts响应对应的slot，未跟踪slot时为0
+}
+
+// 删除指定mint中余额排在前N名之后的持有者记录
+func evictHoldersBeyondTopN(tx *sql.Tx, mintAddress string, keepTopN int) (int64, error) {
+	res, err := tx.Exec(`DELETE FROM holder WHERE mint = ? AND id NOT IN (
+		SELECT id FROM (
+			SELECT id FROM holder WHERE mint = ? ORDER BY amount DESC, id LIMIT ?
+		) AS top_holders
+	)`, mintAddress, mintAddress, keepTopN)
+	if err != nil {
+		return 0, wrapError("删除前N名之外的持有者", err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, wrapError("获取删除行数", err)
+	}
+	return deleted, nil
+}
+
+// 采集状态
+const (
+	collectionStatusSuccess = "success"
+	collectionStatusFailed  = "failed"
+)
+
+// CollectionStatus collection_status 表中的一条采集记录
+type CollectionStatus struct {
+	ID          int64  `json:"id"`
+	Mint        string `json:"mint"`
+	Status      string `json:"status"`
+	HolderCount int64  `json:"holder_count"`
+	// 截至本次的最近5个采集周期中成功采集的持有者数平均值，用于平滑余额归零等造成的抖动；
+	// 这些周期全部失败时为null
+	HolderCountAvg *float64  `json:"holder_count_avg"`
+	Upserted       int       `json:"upserted"`
+	Skipped        int       `json:"skipped"`
+	ErrorMessage   string    `json:"error_message,omitempty"`
+	RPCLagging     bool      `json:"rpc_lagging"` // 采集期间检测到RPC节点落后
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+}
+
+// HolderCountTrendPoint 按天汇总的持有者数量
+type HolderCountTrendPoint struct {
+	Day         string `json:"day"`
+	HolderCount int64  `json:"holder_count"` // 当天最后一次成功采集的持有者数
+	MinCount    int64  `json:"min_count"`
+	MaxCount    int64  `json:"max_count"`
+	Cycles      int    `json:"cycles"`
+}
+
+// 记录一次采集的结果，成功时统计该mint当前余额大于0的持有者数并返回
+func recordCollectionStatus(db *sql.DB, mintAddress string, startedAt time.Time, result CollectionResult, collectErr error, rpcLagging bool) (int64, error) {
+	status := collectionStatusSuccess
+	var holderCount int64
+	var errMsg sql.NullString
+	if collectErr != nil {
+		status = collectionStatusFailed
+		errMsg = sql.NullString{String: collectErr.Error(), Valid: true}
+	} else if err := db.QueryRow("SELECT COUNT(*) FROM holder WHERE mint = ? AND amount > 0", mintAddress).Scan(&holderCount); err != nil {
+		return 0, wrapError("统计持有者数量", err)
+	}
+
+	_, err := db.Exec(`INSERT INTO collection_status (
+		mint, status, holder_count, upserted_count, skipped_count, error_message, rpc_lagging, started_at, finished_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		mintAddress, status, holderCount, result.Upserted, result.Skipped, errMsg, rpcLagging, startedAt, time.Now())
+	if err != nil {
+		return 0, wrapError("写入采集状态", err)
+	}
+	return holderCount, nil
+}
+
+// 持有者数滑动平均的周期数
+const holderCountAvgWindow = 5
+
+// 查询最近的采集记录，mintAddress为空时返回全部mint
+func listCollectionStatus(db *sql.DB, mintAddress string, limit int) ([]CollectionStatus, error) {
+	// 滑动平均需要基于分页范围之外的历史记录计算，先在子查询中按mint开窗，再排序分页
+	query := `SELECT id, mint, status, holder_count, holder_count_avg, upserted_count, skipped_count, error_message, rpc_lagging, started_at, finished_at
+		FROM (
+			SELECT cs.*, AVG(CASE WHEN status = ? THEN holder_count END) OVER (
+				PARTITION BY mint ORDER BY started_at, id
+				ROWS BETWEEN ? PRECEDING AND CURRENT ROW
+			) AS holder_count_avg
+			FROM collection_status cs`
+	args := []interface{}{collectionStatusSuccess, holderCountAvgWindow - 1}
+	if mintAddress != "" {
+		query += " WHERE mint = ?"
+		args = append(args, mintAddress)
+	}
+	query += ") t ORDER BY started_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, wrapError("查询采集状态", err)
+	}
+	defer rows.Close()
+
+	statuses := []CollectionStatus{}
+	for rows.Next() {
+		var st CollectionStatus
+		var errMsg sql.NullString
+		var avg sql.NullFloat64
+		if err := rows.Scan(&st.ID, &st.Mint, &st.Status, &st.HolderCount, &avg, &st.Upserted, &st.Skipped, &errMsg, &st.RPCLagging, &st.StartedAt, &st.FinishedAt); err != nil {
+			return nil, wrapError("扫描采集状态", err)
+		}
+		st.ErrorMessage = errMsg.String
+		if avg.Valid {
+			rounded := math.Round(avg.Float64*100) / 100
+			st.HolderCountAvg = &rounded
+		}
+		statuses = append(statuses, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历采集状态", err)
+	}
+	return statuses, nil
+}
+
+// 按天汇总最近days天内成功采集的持有者数量
+func getHolderCountTrend(db *sql.DB, mintAddress string, days int) ([]HolderCountTrendPoint, error) {
+	rows, err := db.Query(`SELECT DATE_FORMAT(started_at, '%Y-%m-%d') AS day,
+			SUBSTRING_INDEX(GROUP_CONCAT(holder_count ORDER BY started_at DESC), ',', 1),
+			MIN(holder_count), MAX(holder_count), COUNT(*)
+		FROM collection_status
+		WHERE mint = ? AND status = ? AND started_at >= CURRENT_DATE - INTERVAL ? DAY
+		GROUP BY day
+		ORDER BY day`,
+		mintAddress, collectionStatusSuccess, days-1)
+	if err != nil {
+		return nil, wrapError("查询持有者数量趋势", err)
+	}
+	defer rows.Close()
+
+	points := []HolderCountTrendPoint{}
+	for rows.Next() {
+		var p HolderCountTrendPoint
+		if err := rows.Scan(&p.Day, &p.HolderCount, &p.MinCount, &p.MaxCount, &p.Cycles); err != nil {
+			return nil, wrapError("扫描持有者数量趋势", err)
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历持有者数量趋势", err)
+	}
+	return points, nil
+}
+
+// 处理采集状态列表的HTTP请求
+func handleCollectionStatus(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		query := r.URL.Query()
+		limit := 50
+		if limitStr := query.Get("limit"); limitStr != "" {
+			n, err := strconv.Atoi(limitStr)
+			if err != nil || n < 1 || n > 500 {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "limit必须在1-500之间",
+				})
+				return
+			}
+			limit = n
+		}
+
+		var statuses []CollectionStatus
+		err := retryRead(r.Context(), func() (err error) {
+			statuses, err = listCollectionStatus(db, query.Get("mint"), limit)
+			return err
+		})
+		if err != nil {
+			logError("查询采集状态", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    statuses,
+			Limit:   limit,
+		})
+	}
+}
+
+// 处理持有者数量趋势的HTTP请求 (GET /status/collections/{mint_address}/trend)
+func handleHolderCountTrend(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/status/collections/")
+		parts := strings.Split(path, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] != "trend" {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{
+				Success: false,
+				Error:   "Invalid URL format. Expected: /status/collections/{mint_address}/trend",
+			})
+			return
+		}
+		mintAddress := parts[0]
+
+		days := 7
+		if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+			n, err := strconv.Atoi(daysStr)
+			if err != nil || n < 1 || n > 90 {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "days必须在1-90之间",
+				})
+				return
+			}
+			days = n
+		}
+
+		var points []HolderCountTrendPoint
+		err := retryRead(r.Context(), func() (err error) {
+			points, err = getHolderCountTrend(db, mintAddress, days)
+			return err
+		})
+		if err != nil {
+			logError("查询持有者数量趋势", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+
+		var change int64
+		if len(points) > 0 {
+			change = points[len(points)-1].HolderCount - points[0].HolderCount
+		}
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"mint":   mintAddress,
+				"days":   days,
+				"change": change,
+				"points": points,
+			},
+		})
+	}
+}
+
+// activeCollection 一次正在进行的单个mint采集
+type activeCollection struct {
+	cancel    context.CancelFunc
+	startedAt time.Time
+}
+
+// CollectionRegistry 记录正在进行的采集及其取消函数，按mint索引，
+// 用于在不重启服务的情况下中止卡住的单个mint采集；同时缓存列表接口的总数，采集结束时按mint失效
+type CollectionRegistry struct {
+	mu     sync.Mutex
+	active map[string][]*activeCollection
+	counts map[string]cachedCount
+}
+
+func newCollectionRegistry() *CollectionRegistry {
+	return &CollectionRegistry{active: make(map[string][]*activeCollection), counts: make(map[string]cachedCount)}
+}
+
+// 列表接口总数的缓存时间和最大条目数。翻页时过滤条件不变，在缓存时间内复用总数，避免每页都执行 COUNT(*)；
+// 采集、状态更新、地址标签修改和清理孤立记录会立即使相关的总数失效，其他途径的数据变化（如直接修改数据库）
+// 最多延迟该时间反映到总数中
+const (
+	holderCountCacheTTL        = 30 * time.Second
+	holderCountCacheMaxEntries = 1024
+)
+
+// cachedCount 一条缓存的总数，mint 为过滤条件中的mint，为空表示不限mint
+type cachedCount struct {
+	total   int
+	mint    string
+	expires time.Time
+}
+
+// CachedCount 按 key（过滤条件，不含分页参数）返回缓存的总数，未命中或已过期时调用 count 计算并缓存。
+// registry 为nil时不缓存
+func (r *CollectionRegistry) CachedCount(key, mintAddress string, count func() (int, error)) (int, error) {
+	if r == nil {
+		return count()
+	}
+	r.mu.Lock()
+	entry, ok := r.counts[key]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.total, nil
+	}
+
+	total, err := count()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.counts) >= holderCountCacheMaxEntries {
+		for k, e := range r.counts {
+			if !now.Before(e.expires) {
+				delete(r.counts, k)
+			}
+		}
+		if len(r.counts) >= holderCountCacheMaxEntries {
+			clear(r.counts)
+		}
+	}
+	r.counts[key] = cachedCount{total: total, mint: mintAddress, expires: now.Add(holderCountCacheTTL)}
+	return total, nil
+}
+
+// invalidateCounts 删除指定mint以及不限mint的缓存总数，调用方需持有 r.mu
+func (r *CollectionRegistry) invalidateCounts(mintAddress string) {
+	for k, e := range r.counts {
+		if e.mint == mintAddress || e.mint == "" {
+			delete(r.counts, k)
+		}
+	}
+}
+
+// resetCounts 采集以外的写操作修改数据后调用，删除指定mint以及不限mint的缓存总数，
+// mintAddress 为空时删除全部缓存总数。registry 为nil时不做任何事
+func (r *CollectionRegistry) resetCounts(mintAddress string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if mintAddress == "" {
+		clear(r.counts)
+		return
+	}
+	r.invalidateCounts(mintAddress)
+}
+
+// Start 为指定mint创建可单独取消的子上下文，采集结束后必须调用返回的 done 函数
+func (r *CollectionRegistry) Start(ctx context.Context, mintAddress string) (context.Context, func()) {
+	collectCtx, cancel := context.WithCancel(ctx)
+	entry := &activeCollection{cancel: cancel, startedAt: time.Now()}
+
+	r.mu.Lock()
+	r.active[mintAddress] = append(r.active[mintAddress], entry)
+	r.mu.Unlock()
+
+	return collectCtx, func() {
+		cancel()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// 采集可能已经提交了新的持有者数据，该mint的总数需要重新计算
+		r.invalidateCounts(mintAddress)
+		entries := r.active[mintAddress]
+		for i, e := range entries {
+			if e == entry {
+				entries = append(entries[:i], entries[i+1:]...)
+				break
+			}
+		}
+		if len(entries) == 0 {
+			delete(r.active, mintAddress)
+		} else {
+			r.active[mintAddress] = entries
+		}
+	}
+}
+
+// InProgress 判断指定mint是否有正在进行的采集
+func (r *CollectionRegistry) InProgress(mintAddress string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.active[mintAddress]) > 0
+}
+
+// Abort 取消指定mint正在进行的所有采集，返回被取消的采集数
+func (r *CollectionRegistry) Abort(mintAddress string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := r.active[mintAddress]
+	for _, e := range entries {
+		logInfo("中止mint地址 %s 的采集，已运行 %v", mintAddress, time.Since(e.startedAt))
+		e.cancel()
+	}
+	return len(entries)
+}
+
+// 处理中止采集的HTTP请求
+func handleAbortCollection(registry *CollectionRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+			return
+		}
+
+		mintAddress := r.URL.Query().Get("mint")
+		if mintAddress == "" {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "mint不能为空",
+			})
+			return
+		}
+
+		aborted := registry.Abort(mintAddress)
+		if aborted == 0 {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{
+				Success: false,
+				Error:   "该mint没有正在进行的采集",
+			})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"mint":    mintAddress,
+				"aborted": aborted,
+			},
+		})
+	}
+}
+
+// ReadOnlyMode 只读维护模式：启用时拒绝写操作并暂停数据采集，查询接口照常提供服务
+type ReadOnlyMode struct {
+	enabled atomic.Bool
+}
+
+// NewReadOnlyMode 创建只读模式开关，enabled 为初始状态
+func NewReadOnlyMode(enabled bool) *ReadOnlyMode {
+	m := &ReadOnlyMode{}
+	m.enabled.Store(enabled)
+	return m
+}
+
+// Enabled 判断是否处于只读维护模式，nil 表示未启用
+func (m *ReadOnlyMode) Enabled() bool {
+	return m != nil && m.enabled.Load()
+}
+
+// Set 切换只读维护模式，返回切换前的状态
+func (m *ReadOnlyMode) Set(enabled bool) bool {
+	return m.enabled.Swap(enabled)
+}
+
+// 只读模式下仍然允许的非GET请求：切换只读模式本身、数据一致性核对(只读)、中止正在进行的采集
+var readOnlyExemptPaths = map[string]bool{
+	"/admin/read-only":        true,
+	"/admin/verify":           true,
+	"/admin/abort-collection": true,
+}
+
+// withReadOnly 只读维护模式下，对写操作直接返回503
+func withReadOnly(mode *ReadOnlyMode, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if mode.Enabled() && !readOnlyExemptPaths[r.URL.Path] {
+				sendJSONResponse(w, http.StatusServiceUnavailable, APIResponse{
+					Success: false,
+					Error:   "服务处于只读维护模式，暂不接受写操作",
+				})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 处理 /admin/read-only 请求：GET 查询当前状态，POST ?enabled=true|false 切换只读维护模式
+func handleReadOnlyMode(mode *ReadOnlyMode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+			if err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "enabled必须是true或false",
+				})
+				return
+			}
+			if previous := mode.Set(enabled); previous != enabled {
+				if enabled {
+					logInfo("已进入只读维护模式：拒绝写操作并暂停数据采集")
+				} else {
+					logInfo("已退出只读维护模式：恢复写操作和数据采集")
+				}
+			}
+		default:
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+			return
+		}
+
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    map[string]bool{"read_only": mode.Enabled()},
+		})
+	}
+}
+
+// worker 执行一个采集周期。无法获取mint列表，或失败的mint比例超过 max_failure_ratio 时返回错误。
+// 处于只读维护模式时跳过采集，周期进行中进入只读模式时在处理下一个mint前停止
+func worker(ctx context.Context, config *Config, db *sql.DB, registry *CollectionRegistry, monitor *RPCHealthMonitor, readOnly *ReadOnlyMode, alerts *HolderDropAlerter, publisher CollectionPublisher) error {
+	if readOnly.Enabled() {
+		logInfo("服务处于只读维护模式，跳过本次采集")
+		return nil
+	}
+	startTime := time.Now()
+	runID := newRunID()
+	ctx = withRunID(ctx, runID)
+	logInfo("[run:%s] 数据采集任务开始", runID)
+
+	client := NewSolanaRPCClient(config.RPCURL, &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     30 * time.Second,
+		},
+	})
+	client.SetMaxRetries(config.RPCMaxRetries)
+
+	mintAddresses, err := getAllMintAddresses(db)
+	if err != nil {
+		logError("获取mint地址列表", err)
+		return wrapError("获取mint地址列表", err)
+	}
+
+	if len(mintAddresses) == 0 {
+		// spl是由集成方定义的视图，服务不会自行写入默认Token
+		logInfo("[run:%s] spl表中没有mint地址，跳过本次采集；默认Token列表见setup/init_database.sql，或检查spl视图的数据来源", runID)
+		return nil
+	}
+
+	mintOptions, err := getMintOptions(db)
+	if err != nil {
+		logError("获取mint采集配置", err)
+		return wrapError("获取mint采集配置", err)
+	}
+
+	// 可选：要求RPC节点返回不早于已见最高slot的快照
+	var minSlot *slotTracker
+	if config.EnforceMinSlot {
+		minSlot = &monitor.contextSlot
+	}
+
+	concurrency := max(config.FetchConcurrency, 1)
+	logInfo("开始处理 %d 个mint地址，并发数: %d", len(mintAddresses), concurrency)
+	var successCount, failedCount atomic.Int64
+	var decodeFailedCount atomic.Int64 // 失败的mint中RPC响应无法解析的数量，通常说明节点返回了截断或异常的响应
+
+	// processMint 采集单个mint并记录采集状态，每个mint使用独立的事务。
+	// panic 只使该mint计为失败，不影响同时运行的其他mint
+	processMint := func(i int, mintAddress string) {
+		counted := false
+		defer func() {
+			if r := recover(); r != nil {
+				logError(fmt.Sprintf("[run:%s] 采集mint地址 %s", runID, mintAddress), fmt.Errorf("panic: %v\n%s", r, debug.Stack()))
+				if !counted {
+					failedCount.Add(1)
+				}
+			}
+		}()
+		logDebug("处理第 %d/%d 个mint地址: %s", i+1, len(mintAddresses), mintAddress)
+		collectStart := time.Now()
+		rpcLagging := monitor.IsLagging()
+		// 采集结束（包括panic）时都要从registry中移除
+		result, err := func() (CollectionResult, error) {
+			collectCtx, done := registry.Start(ctx, mintAddress)
+			defer done()
+			return fetchAndStoreData(collectCtx, config, db, client, mintAddress, mintOptions[mintAddress], minSlot)
+		}()
+		if err != nil {
+			logError(fmt.Sprintf("[run:%s] 采集mint地址 %s", runID, mintAddress), err)
+			failedCount.Add(1)
+			if errors.Is(err, errRPCResponseDecode) {
+				decodeFailedCount.Add(1)
+			}
+		} else {
+			successCount.Add(1)
+		}
+		counted = true
+		rpcLagging = rpcLagging || monitor.IsLagging()
+		if rpcLagging {
+			logInfo("警告: mint地址 %s 采集期间RPC节点可能落后，本次结果已标记", mintAddress)
+		}
+		// 需要持有者数量告警时，在写入本次状态前读取上一次成功采集的数量
+		opts := mintOptions[mintAddress]
+		var previousCount int64
+		checkDrop := err == nil && alerts.enabled(opts.HolderDropAlertPct)
+		if checkDrop {
+			var countErr error
+			if previousCount, countErr = lastSuccessfulHolderCount(db, mintAddress); countErr != nil {
+				logError("持有者数量告警", countErr)
+				checkDrop = false
+			}
+		}
+		collected := err == nil
+		holderCount, err := recordCollectionStatus(db, mintAddress, collectStart, result, err, rpcLagging)
+		if err != nil {
+			logError("记录采集状态", err)
+		} else if checkDrop {
+			alerts.Check(ctx, mintAddress, previousCount, holderCount, opts.HolderDropAlertPct)
+		}
+		if collected && publisher != nil {
+			event := CollectionEvent{
+				Type:        CollectionEventMintUpdated,
+				RunID:       runID,
+				Mint:        mintAddress,
+				Upserted:    result.Upserted,
+				Skipped:     result.Skipped,
+				Filtered:    result.Filtered,
+				DurationMS:  time.Since(collectStart).Milliseconds(),
+				CollectedAt: collectStart,
+			}
+			if err == nil {
+				event.HolderCount = &holderCount
+			}
+			if result.Slot > 0 {
+				event.Slot = &result.Slot
+			}
+			publishCollectionEvent(ctx, publisher, event)
+		}
+	}
+
+	// 信号量限制同时采集的mint数；取消或进入只读模式后不再启动新的mint，等待已启动的mint结束
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var stopErr error
+dispatch:
+	for i, mintAddress := range mintAddresses {
+		if ctx.Err() != nil {
+			logInfo("收到取消信号，停止数据采集")
+			stopErr = ctx.Err()
+			break
+		}
+		if readOnly.Enabled() {
+			logInfo("[run:%s] 已进入只读维护模式，停止本次采集，%d 个mint未处理", runID, len(mintAddresses)-i)
+			wg.Wait()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			logInfo("收到取消信号，停止数据采集")
+			stopErr = ctx.Err()
+			break dispatch
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			processMint(i, mintAddress)
+		}()
+	}
+	wg.Wait()
+	if stopErr != nil {
+		return stopErr
+	}
+
+	duration := time.Since(startTime)
+	failureRatio := float64(failedCount.Load()) / float64(len(mintAddresses))
+	if failureRatio > config.MaxFailureRatio {
+		// 大面积失败通常意味着RPC节点或数据库故障，不能当作正常完成
+		err := fmt.Errorf("%d/%d 个mint采集失败(其中 %d 个RPC响应无法解析)，失败比例 %.1f%% 超过阈值 %.1f%%",
+			failedCount.Load(), len(mintAddresses), decodeFailedCount.Load(), failureRatio*100, config.MaxFailureRatio*100)
+		logError(fmt.Sprintf("[run:%s] 数据采集周期失败(耗时: %v)", runID, duration), err)
+		return err
+	}
+	if decodeFailedCount.Load() > 0 {
+		logInfo("[run:%s] 警告: %d 个mint的RPC响应无法解析", runID, decodeFailedCount.Load())
+	}
+	logInfo("[run:%s] 数据采集任务完成，处理了 %d/%d 个地址，耗时: %v", runID, successCount.Load(), len(mintAddresses), duration)
+	return nil
+}
+
+// startWorker 启动一个定时任务，周期性地获取数据
+func startWorker(ctx context.Context, config *Config, db *sql.DB, registry *CollectionRegistry, monitor *RPCHealthMonitor, workers *WorkerLimiter, readOnly *ReadOnlyMode, alerts *HolderDropAlerter, publisher CollectionPublisher) {
+	interval := config.CollectionInterval()
+	logInfo("启动定时数据采集任务，间隔: %v", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// 在新的goroutine中执行worker，避免阻塞定时器；RPC或数据库变慢导致上一个周期
+	// 尚未结束时，活跃worker数量达到上限后拒绝再启动，防止goroutine堆积
+	spawn := func() {
+		if !workers.TryAcquire() {
+			logError("启动数据采集任务", fmt.Errorf("活跃的采集goroutine已达上限 %d，跳过本次采集", workers.Limit()))
+			return
+		}
+		go func() {
+			defer workers.Release()
+			worker(ctx, config, db, registry, monitor, readOnly, alerts, publisher)
+		}()
+	}
+
+	// 启动时执行一次，可跳过或延迟，给RPC节点和数据库预热时间
+	if config.SkipInitialCollection {
+		logInfo("跳过启动时的首次采集，将在 %v 后开始采集", interval)
+	} else if config.InitialCollectionDelay > 0 {
+		delay := time.Duration(config.InitialCollectionDelay) * time.Second
+		logInfo("首次采集将延迟 %v 执行", delay)
+		go func() {
+			select {
+			case <-time.After(delay):
+				spawn()
+			case <-ctx.Done():
+			}
+		}()
+	} else {
+		spawn()
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			spawn()
+		case <-ctx.Done():
+			logInfo("数据采集定时任务正在关闭")
+			return
+		}
+	}
+}
+
+// WorkerLimiter 统计正在运行的采集goroutine数量，并限制同时运行的上限
+type WorkerLimiter struct {
+	limit    int
+	active   atomic.Int64
+	rejected atomic.Uint64
+}
+
+// NewWorkerLimiter 创建限制器，limit 小于等于0表示不限制
+func NewWorkerLimiter(limit int) *WorkerLimiter {
+	return &WorkerLimiter{limit: limit}
+}
+
+// TryAcquire 活跃数量未达上限时占用一个名额并返回true，否则记录一次拒绝并返回false
+func (l *WorkerLimiter) TryAcquire() bool {
+	for {
+		current := l.active.Load()
+		if l.limit > 0 && current >= int64(l.limit) {
+			l.rejected.Add(1)
+			return false
+		}
+		if l.active.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+// Release 释放 TryAcquire 占用的名额
+func (l *WorkerLimiter) Release() {
+	l.active.Add(-1)
+}
+
+// Active 返回正在运行的采集goroutine数量
+func (l *WorkerLimiter) Active() int64 {
+	return l.active.Load()
+}
+
+// Rejected 返回因达到上限而被拒绝启动的次数
+func (l *WorkerLimiter) Rejected() uint64 {
+	return l.rejected.Load()
+}
+
+// Limit 返回同时运行的上限，0表示不限制
+func (l *WorkerLimiter) Limit() int {
+	return l.limit
+}
+
+// RequestTracker 统计正在处理的HTTP请求数，并记录耗时超过阈值的慢请求
+type RequestTracker struct {
+	slowThreshold time.Duration
+	inFlight      atomic.Int64
+	slow          atomic.Uint64
+}
+
+// NewRequestTracker 创建请求统计，slowThreshold 小于等于0表示不记录慢请求
+func NewRequestTracker(slowThreshold time.Duration) *RequestTracker {
+	return &RequestTracker{slowThreshold: slowThreshold}
+}
+
+// InFlight 返回正在处理的HTTP请求数
+func (t *RequestTracker) InFlight() int64 {
+	return t.inFlight.Load()
+}
+
+// SlowRequests 返回耗时超过阈值的请求总数
+func (t *RequestTracker) SlowRequests() uint64 {
+	return t.slow.Load()
+}
+
+// withRequestTracking 统计正在处理的请求数，请求耗时超过阈值时输出一条慢请求日志
+func withRequestTracking(tracker *RequestTracker, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracker.inFlight.Add(1)
+		start := time.Now()
+		defer func() {
+			tracker.inFlight.Add(-1)
+			if elapsed := time.Since(start); tracker.slowThreshold > 0 && elapsed >= tracker.slowThreshold {
+				tracker.slow.Add(1)
+				logInfo("慢请求: %s %s 耗时 %v，超过阈值 %v", r.Method, r.URL.Path, elapsed, tracker.slowThreshold)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 处理 /metrics 请求，以Prometheus文本格式输出采集goroutine和HTTP请求指标
+func handleMetrics(workers *WorkerLimiter, requests *RequestTracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprintf(w, "# HELP solana_spl_holder_active_workers 正在运行的数据采集goroutine数量\n")
+		fmt.Fprintf(w, "# TYPE solana_spl_holder_active_workers gauge\n")
+		fmt.Fprintf(w, "solana_spl_holder_active_workers %d\n", workers.Active())
+		fmt.Fprintf(w, "# HELP solana_spl_holder_max_workers 同时运行的数据采集goroutine上限，0表示不限制\n")
+		fmt.Fprintf(w, "# TYPE solana_spl_holder_max_workers gauge\n")
+		fmt.Fprintf(w, "solana_spl_holder_max_workers %d\n", workers.Limit())
+		fmt.Fprintf(w, "# HELP solana_spl_holder_worker_rejections_total 因达到上限而被拒绝启动的数据采集次数\n")
+		fmt.Fprintf(w, "# TYPE solana_spl_holder_worker_rejections_total counter\n")
+		fmt.Fprintf(w, "solana_spl_holder_worker_rejections_total %d\n", workers.Rejected())
+		fmt.Fprintf(w, "# HELP solana_spl_holder_http_requests_in_flight 正在处理的HTTP请求数量\n")
+		fmt.Fprintf(w, "# TYPE solana_spl_holder_http_requests_in_flight gauge\n")
+		fmt.Fprintf(w, "solana_spl_holder_http_requests_in_flight %d\n", requests.InFlight())
+		fmt.Fprintf(w, "# HELP solana_spl_holder_http_slow_requests_total 耗时超过slow_request_threshold的HTTP请求次数\n")
+		fmt.Fprintf(w, "# TYPE solana_spl_holder_http_slow_requests_total counter\n")
+		fmt.Fprintf(w, "solana_spl_holder_http_slow_requests_total %d\n", requests.SlowRequests())
+	}
+}
+
+// RPCHealthStatus RPC节点最近一次健康探测的结果
+type RPCHealthStatus struct {
+	Slot              uint64    `json:"slot"`
+	Healthy           bool      `json:"healthy"` // getHealth 返回 ok
+	HealthMessage     string    `json:"health_message,omitempty"`
+	Lagging           bool      `json:"lagging"` // slot未推进或getHealth报告节点落后
+	ProbeError        string    `json:"probe_error,omitempty"`
+	LastProbeAt       time.Time `json:"last_probe_at"`
+	LastSlotAdvanceAt time.Time `json:"last_slot_advance_at"`
+}
+
+// RPCHealthMonitor 保存周期性探测得到的RPC节点状态，供采集任务和 /status 读取
+type RPCHealthMonitor struct {
+	mu     sync.RWMutex
+	status RPCHealthStatus
+
+	// 启用 enforce_min_slot 时，采集过程中 getProgramAccounts 返回过的最高slot
+	contextSlot slotTracker
+}
+
+// slotTracker 记录已见过的最高slot，只增不减
+type slotTracker struct {
+	highest atomic.Uint64
+}
+
+// Observe 记录一个slot，比当前最高slot小时忽略
+func (t *slotTracker) Observe(slot uint64) {
+	for {
+		current := t.highest.Load()
+		if slot <= current || t.highest.CompareAndSwap(current, slot) {
+			return
+		}
+	}
+}
+
+// Load 返回已见过的最高slot，尚未记录时为0
+func (t *slotTracker) Load() uint64 {
+	return t.highest.Load()
+}
+
+// Snapshot 返回当前状态的副本
+func (m *RPCHealthMonitor) Snapshot() RPCHealthStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// IsLagging 判断RPC节点当前是否被认为落后
+func (m *RPCHealthMonitor) IsLagging() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status.Lagging
+}
+
+// probe 执行一次 getSlot/getHealth 探测并更新状态。slot与上次探测相比没有推进，
+// 或 getHealth 返回错误时，认为节点落后
+func (m *RPCHealthMonitor) probe(ctx context.Context, client *SolanaRPCClient) {
+	now := time.Now()
+	var slot uint64
+	slotErr := client.Call(ctx, "getSlot", nil, &slot)
+
+	var health string
+	healthErr := client.Call(ctx, "getHealth", nil, &health)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev := m.status
+	next := prev
+	next.LastProbeAt = now
+	next.ProbeError = ""
+
+	if slotErr != nil {
+		next.ProbeError = slotErr.Error()
+	} else {
+		if slot > prev.Slot {
+			next.LastSlotAdvanceAt = now
+		}
+		next.Slot = slot
+	}
+	next.Healthy = healthErr == nil && health == "ok"
+	next.HealthMessage = ""
+	if healthErr != nil {
+		next.HealthMessage = healthErr.Error()
+	}
+
+	// 首次探测没有可比较的slot，只依据getHealth判断
+	slotStalled := slotErr != nil || (!prev.LastProbeAt.IsZero() && slot <= prev.Slot)
+	next.Lagging = slotStalled || !next.Healthy
+	m.status = next
+
+	if next.Lagging && !prev.Lagging {
+		logError("RPC节点健康探测", fmt.Errorf("RPC节点可能落后或停滞: slot=%d, 上次推进时间=%v, health=%s, 探测错误=%s",
+			next.Slot, next.LastSlotAdvanceAt.Format(time.RFC3339), next.HealthMessage, next.ProbeError))
+	} else if !next.Lagging && prev.Lagging {
+		logInfo("RPC节点已恢复正常，当前slot: %d", next.Slot)
+	}
+}
+
+// startRPCProbe 启动定时任务，周期性地探测RPC节点的slot和健康状态
+func startRPCProbe(ctx context.Context, config *Config, monitor *RPCHealthMonitor) {
+	interval := time.Duration(config.RPCProbeInterval) * time.Second
+	logInfo("启动RPC节点健康探测任务，间隔: %v", interval)
+	client := NewSolanaRPCClient(config.RPCURL, &http.Client{Timeout: 10 * time.Second})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	monitor.probe(ctx, client)
+	for {
+		select {
+		case <-ticker.C:
+			monitor.probe(ctx, client)
+		case <-ctx.Done():
+			logInfo("RPC节点健康探测任务正在关闭")
+			return
+		}
+	}
+}
+
+// 处理服务运行状态的HTTP请求
+func handleStatus(config *Config, monitor *RPCHealthMonitor, pruner *HistoryPruner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		status := map[string]interface{}{
+			"rpc_probe_enabled": config.RPCProbeInterval > 0,
+		}
+		if config.RPCProbeInterval > 0 {
+			status["rpc"] = monitor.Snapshot()
+		}
+		if config.EnforceMinSlot {
+			status["min_context_slot"] = monitor.contextSlot.Load()
+		}
+		if config.HistoryRetentionDays > 0 {
+			status["history_prune"] = pruner.Snapshot()
+		}
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    status,
+		})
+	}
+}
+
+// startOrphanCleanup 启动定时任务，周期性地清理孤立的Holder记录
+func startOrphanCleanup(ctx context.Context, interval time.Duration, db *sql.DB) {
+	logInfo("启动孤立Holder清理任务，间隔: %v", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := deleteOrphanHolders(db)
+			if err != nil {
+				logError("定时清理孤立Holder记录", err)
+				continue
+			}
+			logInfo("定时清理孤立Holder记录完成，删除 %d 条记录", deleted)
+		case <-ctx.Done():
+			logInfo("孤立Holder清理任务正在关闭")
+			return
+		}
+	}
+}
+
+// collection_status 清理任务的执行间隔和每批删除的行数。分批删除避免长时间锁表
+const (
+	historyPruneInterval  = time.Hour
+	historyPruneBatchSize = 5000
+)
+
+// HistoryPruner 记录 collection_status 清理任务最近一次的执行结果
+type HistoryPruner struct {
+	retentionDays int
+
+	mu          sync.Mutex
+	lastRunAt   time.Time
+	lastDeleted int64
+	lastErr     string
+}
+
+// HistoryPruneStatus /status 中返回的清理任务状态
+type HistoryPruneStatus struct {
+	RetentionDays int        `json:"retention_days"`
+	LastRunAt     *time.Time `json:"last_run_at"` // 尚未执行时为null
+	LastDeleted   int64      `json:"last_deleted"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// NewHistoryPruner 创建清理任务状态，retentionDays 为0表示不启用
+func NewHistoryPruner(retentionDays int) *HistoryPruner {
+	return &HistoryPruner{retentionDays: retentionDays}
+}
+
+// Snapshot 返回最近一次清理的结果
+func (p *HistoryPruner) Snapshot() HistoryPruneStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := HistoryPruneStatus{RetentionDays: p.retentionDays, LastDeleted: p.lastDeleted, LastError: p.lastErr}
+	if !p.lastRunAt.IsZero() {
+		runAt := p.lastRunAt
+		status.LastRunAt = &runAt
+	}
+	return status
+}
+
+func (p *HistoryPruner) record(deleted int64, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastRunAt = time.Now()
+	p.lastDeleted = deleted
+	p.lastErr = ""
+	if err != nil {
+		p.lastErr = err.Error()
+	}
+}
+
+// 分批删除早于 cutoff 的采集记录，每批之间检查ctx，返回删除的总行数
+func pruneCollectionStatus(ctx context.Context, db *sql.DB, cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		res, err := db.ExecContext(ctx, "DELETE FROM collection_status WHERE started_at < ? LIMIT ?", cutoff, batchSize)
+		if err != nil {
+			return total, wrapError("删除过期采集记录", err)
+		}
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return total, wrapError("获取删除行数", err)
+		}
+		total += deleted
+		if deleted < int64(batchSize) {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// startHistoryPrune 启动定时任务，删除超过保留天数的 collection_status 记录。
+// 启动时立即执行一次，只读维护模式下跳过
+func startHistoryPrune(ctx context.Context, db *sql.DB, pruner *HistoryPruner, readOnly *ReadOnlyMode) {
+	retention := time.Duration(pruner.retentionDays) * 24 * time.Hour
+	logInfo("启动采集记录清理任务，保留 %d 天，间隔: %v", pruner.retentionDays, historyPruneInterval)
+	ticker := time.NewTicker(historyPruneInterval)
+	defer ticker.Stop()
+
+	prune := func() {
+		if readOnly.Enabled() {
+			logInfo("服务处于只读维护模式，跳过本次采集记录清理")
+			return
+		}
+		deleted, err := pruneCollectionStatus(ctx, db, time.Now().Add(-retention), historyPruneBatchSize)
+		pruner.record(deleted, err)
+		if err != nil {
+			logError("清理过期采集记录", err)
+			return
+		}
+		logInfo("清理过期采集记录完成，删除 %d 条记录", deleted)
+	}
+
+	prune()
+	for {
+		select {
+		case <-ticker.C:
+			prune()
+		case <-ctx.Done():
+			logInfo("采集记录清理任务正在关闭")
+			return
+		}
+	}
+}
+
+// 配置结构
+type Config struct {
+	RPCURL                 string
+	DBConnStr              string
+	DBReadConnStr          string // 只读副本连接字符串，为空时查询也使用主库
+	IntervalTime           int
+	Interval               time.Duration // 以时长表示的采集间隔，非零时覆盖 IntervalTime
+	ListenPort             int
+	TLSCertFile            string        // TLS证书文件，与 TLSKeyFile 同时设置时以HTTPS(支持HTTP/2)提供服务
+	TLSKeyFile             string        // TLS私钥文件
+	OrphanCleanupInterval  int           // 孤立Holder清理间隔(秒)，0表示不启用
+	HistoryRetentionDays   int           // collection_status 记录保留天数，0表示不清理
+	DBStatementTimeout     int           // 数据库语句执行超时(秒)，0表示不限制
+	RPCEncoding            string        // getProgramAccounts 的账户数据编码: jsonParsed 或 base64
+	CollectStates          []string      // 需要入库的账户状态(小写)，为空表示全部状态
+	ArchiveDir             string        // 原始RPC响应归档目录，为空表示不归档
+	ArchiveRetentionDays   int           // 归档保留天数，0表示不按时间清理
+	ArchiveMaxFiles        int           // 每个mint最多保留的归档文件数，0表示不限制
+	MaxFailureRatio        float64       // 单个采集周期允许的mint失败比例(0-1)，超过时该周期视为失败
+	KeepTopN               int           // 每个mint只保留余额最大的前N个持有者，0表示不限制
+	MinUIAmount            string        // 最小余额(十进制，按decimals换算)，低于该值的账户不入库，为空表示不过滤
+	CollectionAtomicity    string        // 账户写入失败时的处理方式: best-effort(默认) 或 strict
+	RPCProbeInterval       int           // RPC节点健康探测间隔(秒)，0表示不启用
+	RPCMaxResponseBytes    int64         // getProgramAccounts 响应体的最大字节数，超过时本次采集失败，0表示不限制
+	RPCMaxRetries          int           // RPC请求遇到网络错误或429/5xx时的最大重试次数，0表示不重试
+	EnforceMinSlot         bool          // getProgramAccounts 以已见最高slot作为 minContextSlot，拒绝落后节点的旧快照
+	ProfileCollection      bool          // 按mint输出RPC请求、JSON解析、数据库写入各阶段的耗时
+	SnapshotRoots          bool          // 采集后计算并保存持有者余额的Merkle根，需要 snapshot_root 表
+	CollectNativeBalance   bool          // 采集后查询持有者owner的SOL余额，需要 native_balance 表
+	MaxConcurrentWorkers   int           // 同时运行的采集goroutine上限，0表示不限制
+	FetchConcurrency       int           // 一个采集周期内同时采集的mint数，0或1表示逐个采集
+	ReadOnly               bool          // 以只读维护模式启动：拒绝写操作并暂停数据采集
+	PriceFeedURL           string        // 返回 mint→美元价格 的价格源URL，为空表示不支持 include_value
+	PriceCacheTTL          int           // 价格缓存时间(秒)
+	EventBrokerURL         string        // 采集事件发布的消息队列地址(nats://host:port)，为空表示不发布
+	EventTopic             string        // 采集事件发布的主题
+	MaxOffset              int           // 列表接口允许的最大分页偏移量，0表示不限制
+	ExcludedCategories     []string      // exclude_known=true 时排除的地址标签分类，为空表示不支持 exclude_known
+	CacheControlMaxAge     int           // /holders 和 /spls 成功响应的 Cache-Control max-age(秒)，0表示不设置
+	SlowRequestThreshold   time.Duration // HTTP请求耗时达到该值时输出慢请求日志，0表示不记录
+	SkipInitialCollection  bool          // 跳过启动时的首次采集，等待第一个采集周期
+	InitialCollectionDelay int           // 首次采集延迟(秒)
+	HolderDropAlertPct     float64       // 持有者数量较上次成功采集下降超过该百分比时告警，0表示不启用
+	HolderDropAlertWebhook string        // 持有者数量告警的webhook URL，为空时只输出日志
+}
+
+// shouldCollectState 判断该账户状态是否需要入库
+func (c *Config) shouldCollectState(state string) bool {
+	if len(c.CollectStates) == 0 {
+		return true
+	}
+	state = strings.ToLower(state)
+	for _, s := range c.CollectStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectionInterval 返回采集间隔：设置了 Interval 时使用 Interval，否则使用 IntervalTime 秒
+func (c *Config) CollectionInterval() time.Duration {
+	if c.Interval != 0 {
+		return c.Interval
+	}
+	return time.Duration(c.IntervalTime) * time.Second
+}
+
+// 验证配置
+func (c *Config) Validate() error {
+	if c.RPCURL == "" {
+		return fmt.Errorf("RPC URL不能为空")
+	}
+	if c.DBConnStr == "" {
+		return fmt.Errorf("数据库连接字符串不能为空")
+	}
+	if c.CollectionInterval() < 10*time.Second {
+		return fmt.Errorf("采集间隔不能小于10秒")
+	}
+	if c.ListenPort < 1 || c.ListenPort > 65535 {
+		return fmt.Errorf("监听端口必须在1-65535范围内")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("tls_cert和tls_key必须同时设置")
+	}
+	if c.TLSCertFile != "" {
+		if _, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile); err != nil {
+			return wrapError("加载TLS证书", err)
+		}
+	}
+	if c.OrphanCleanupInterval != 0 && c.OrphanCleanupInterval < 60 {
+		return fmt.Errorf("孤立Holder清理间隔不能小于60秒")
+	}
+	if c.HistoryRetentionDays < 0 {
+		return fmt.Errorf("history_retention_days不能为负数")
+	}
+	if c.RPCProbeInterval != 0 && c.RPCProbeInterval < 5 {
+		return fmt.Errorf("RPC节点探测间隔不能小于5秒")
+	}
+	if c.RPCMaxResponseBytes < 0 {
+		return fmt.Errorf("rpc_max_response_bytes不能为负数")
+	}
+	if c.RPCMaxRetries < 0 {
+		return fmt.Errorf("rpc_max_retries不能为负数")
+	}
+	if c.ArchiveRetentionDays < 0 || c.ArchiveMaxFiles < 0 {
+		return fmt.Errorf("归档保留天数和最大文件数不能为负数")
+	}
+	if c.MaxFailureRatio < 0 || c.MaxFailureRatio > 1 {
+		return fmt.Errorf("max_failure_ratio必须在0到1之间")
+	}
+	if c.KeepTopN < 0 {
+		return fmt.Errorf("keep_top_n不能为负数")
+	}
+	if _, err := parseMinUIAmount(c.MinUIAmount); err != nil {
+		return err
+	}
+	if c.MaxConcurrentWorkers < 0 {
+		return fmt.Errorf("max_concurrent_workers不能为负数")
+	}
+	if c.FetchConcurrency < 0 {
+		return fmt.Errorf("fetch_concurrency不能为负数")
+	}
+	if c.PriceCacheTTL < 0 {
+		return fmt.Errorf("price_cache_ttl不能为负数")
+	}
+	if c.EventBrokerURL != "" {
+		if _, err := NewCollectionPublisher(c.EventBrokerURL, c.EventTopic); err != nil {
+			return err
+		}
+	}
+	if c.MaxOffset < 0 {
+		return fmt.Errorf("最大分页偏移量不能为负数")
+	}
+	if c.CacheControlMaxAge < 0 {
+		return fmt.Errorf("Cache-Control max-age不能为负数")
+	}
+	if c.SlowRequestThreshold < 0 {
+		return fmt.Errorf("slow_request_threshold不能为负数")
+	}
+	if c.InitialCollectionDelay < 0 {
+		return fmt.Errorf("首次采集延迟不能为负数")
+	}
+	if c.HolderDropAlertPct < 0 || c.HolderDropAlertPct > 100 {
+		return fmt.Errorf("holder_drop_alert_pct必须在0到100之间")
+	}
+	if c.DBStatementTimeout < 0 {
+		return fmt.Errorf("数据库语句执行超时不能为负数")
+	}
+	switch c.CollectionAtomicity {
+	case "", CollectionAtomicityBestEffort, CollectionAtomicityStrict:
+	default:
+		return fmt.Errorf("collection_atomicity必须是 %s 或 %s", CollectionAtomicityBestEffort, CollectionAtomicityStrict)
+	}
+	switch c.RPCEncoding {
+	case RPCEncodingJSONParsed, RPCEncodingBase64:
+	default:
+		return fmt.Errorf("RPC编码必须是 %s 或 %s", RPCEncodingJSONParsed, RPCEncodingBase64)
+	}
+	return nil
+}
+
+// 生成API文档HTML
+func getAPIDocumentation() string {
+	return `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Solana SPL Holder API 文档</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 40px; line-height: 1.6; }
+        h1 { color: #333; border-bottom: 2px solid #007cba; padding-bottom: 10px; }
+        h2 { color: #007cba; margin-top: 30px; }
+        h3 { color: #555; }
+        .endpoint { background: #f4f4f4; padding: 15px; margin: 10px 0; border-radius: 5px; }
+        .method { font-weight: bold; color: white; padding: 3px 8px; border-radius: 3px; }
+        .get { background: #28a745; }
+        .put { background: #ffc107; color: black; }
+        .post { background: #007cba; }
+        .delete { background: #dc3545; }
+        .code { background: #f8f9fa; padding: 10px; border-radius: 3px; font-family: monospace; white-space: pre-wrap; }
+        .response { background: #e9ecef; padding: 10px; border-radius: 3px; margin-top: 10px; white-space: pre-wrap; font-family: monospace; }
+        table { border-collapse: collapse; width: 100%; margin: 10px 0; }
+        th, td { border: 1px solid #ddd; padding: 8px; text-align: left; }
+        th { background-color: #f2f2f2; }
+    </style>
+</head>
+<body>
+    <h1>🚀 Solana SPL Holder API 文档</h1>
+    
+    <h2>📋 概述</h2>
+    <p>这是一个用于管理 Solana SPL Token 持有者信息的 RESTful API 服务。</p>
+    <p><strong>基础URL:</strong> <code id="base-url"></code></p>
+    <script>
+        // 动态获取当前页面的基础URL
+        document.getElementById('base-url').textContent = window.location.origin;
+    </script>
+    
+    <h2>🔗 API 端点</h2>
+    
+    <h3>1. Holder 数据查询</h3>
+    
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /holders</h4>
+        <p><strong>描述:</strong> 查询 Token 持有者信息（支持分页、排序和多维度筛选）</p>
+        <p>已授权的账户额外返回 delegate（被授权地址）和 delegatedAmount（授权额度），未授权时省略。</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>page</td><td>int</td><td>页码（默认1）</td><td>page=2</td></tr>
+            <tr><td>limit</td><td>int</td><td>每页数量（默认10，最大1000）</td><td>limit=50</td></tr>
+            <tr><td>owner</td><td>string</td><td>按持有者地址筛选，支持逗号分隔或重复参数指定多个地址（最多100个）</td><td>owner=13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf,6Vmny6y3mLA4kaDTjnZJabvZ8jLKQBg4aqbaERHmEeLZ</td></tr>
+            <tr><td>mint_address</td><td>string</td><td>按 mint 地址筛选</td><td>mint_address=EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v</td></tr>
+            <tr><td>state</td><td>string</td><td>按状态筛选（uninitialized/initialized/frozen）</td><td>state=frozen</td></tr>
+            <tr><td>sort</td><td>string</td><td>排序字段，多个字段用逗号分隔（最多3个），加 - 前缀为降序；可用字段: id、mint、pubkey、owner、state、lamports、decimals、amount、ui_amount、delegated_amount、created_at、updated_at、first_seen_at</td><td>sort=owner,-ui_amount</td></tr>
+            <tr><td>include_labels</td><td>bool</td><td>附带持有者(owner)的已知地址标签（label、labelCategory）</td><td>include_labels=true</td></tr>
+            <tr><td>exclude_known</td><td>bool</td><td>排除 owner 或 pubkey 被标注为 excluded_label_categories 分类（默认 program、burn、pool）的已知地址</td><td>exclude_known=true</td></tr>
+            <tr><td>count_only</td><td>bool</td><td>只返回符合条件的总数 {"total": N}，不查询数据行；HEAD 请求同样只执行计数，通过 X-Total-Count 响应头返回</td><td>count_only=true</td></tr>
+            <tr><td>fields</td><td>string</td><td>逗号分隔的返回字段，只查询和返回这些字段（如 pubkey,owner,uiAmount），默认返回全部字段</td><td>fields=pubkey,owner,uiAmount</td></tr>
+            <tr><td>include_extensions</td><td>bool</td><td>附带 Token-2022 账户的 closeAuthority 和 extensions（扩展状态JSON）</td><td>include_extensions=true</td></tr>
+            <tr><td>include_value</td><td>bool</td><td>按价格源计算持有价值 value_usd = uiAmount * price，需要启动时配置 price_feed_url，价格源中没有该mint时省略</td><td>include_value=true</td></tr>
+        </table>
+        
+        <p><strong>排序说明:</strong></p>
+        <ul>
+            <li><code>sort=ui_amount</code> - 按金额升序排列</li>
+            <li><code>sort=-ui_amount</code> - 按金额降序排列</li>
+            <li><code>sort=pubkey</code> - 按公钥升序排列</li>
+            <li><code>sort=-pubkey</code> - 按公钥降序排列</li>
+            <li><code>sort=created_at</code> - 按创建时间升序排列</li>
+            <li><code>sort=-created_at</code> - 按创建时间降序排列</li>
+            <li><code>sort=owner,-ui_amount</code> - 先按所有者升序，同一所有者内按金额降序排列</li>
+        </ul>
+        
+        <p><strong>查询示例:</strong></p>
+        <ul>
+            <li>基本查询: <code>/holders?page=1&limit=10</code></li>
+            <li>按状态过滤: <code>/holders?state=frozen</code></li>
+            <li>按Token过滤: <code>/holders?mint_address=EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v</code></li>
+            <li>金额排序: <code>/holders?sort=-ui_amount&limit=20</code></li>
+            <li>组合查询: <code>/holders?mint_address=EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v&state=frozen&sort=-ui_amount</code></li>
+            <li>多条件过滤: <code>/holders?state=initialized&sort=pubkey&page=2&limit=25</code></li>
+        </ul>
+        
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": [
+        {
+            "id": 1,
+            "mint_address": "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
+            "pubkey": "13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf",
+            "state": "initialized",
+            "owner": "13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf",
+            "amount": "1000000",
+            "uiAmount": 1.0,
+            "decimals": 6,
+            "createdAt": "2024-01-01T12:00:00Z",
+            "updatedAt": "2024-01-01T12:00:00Z"
+        }
+    ],
+    "total": 100,
+    "page": 1,
+    "limit": 10
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /spls/{mint_address}/holders</h4>
+        <p><strong>描述:</strong> 获取指定 Token 的持有者列表，等价于 <code>/holders?mint={mint_address}</code>，支持相同的分页、排序和过滤参数。mint 不在 spl 视图中时返回 404。</p>
+        <p><strong>示例:</strong> <code>/spls/Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg/holders?sort=-ui_amount&amp;limit=20</code></p>
+        <h4><span class="method get">GET</span> /spls/{mint_address}</h4>
+        <p><strong>描述:</strong> 获取 Token 信息（symbol、mint）。指定 <code>include_status=true</code> 时附带最近的采集情况：<code>last_collected_at</code>、<code>last_holder_count</code>（最近一次成功采集）、<code>last_status</code> 和 <code>last_error</code>（最近一次采集失败时的错误）。</p>
+        <h4><span class="method get">GET</span> /spls/export</h4>
+        <p><strong>描述:</strong> 导出 spl 视图中全部 Token 的配置（symbol、mint 和可选列 program_id、rpc_filters、keep_top_n、holder_drop_alert_pct），带格式版本 <code>version</code>，用于备份和在环境之间迁移。</p>
+        <h4><span class="method get">GET</span> /spls/{mint_address}/summary</h4>
+        <p><strong>描述:</strong> Token 概览：symbol、decimals、余额大于0的持有者数（<code>holder_count</code>）、不同钱包地址数（<code>distinct_owners</code>）、余额合计（<code>total_amount</code>、<code>total_ui_amount</code>）和最近一次成功采集时间（<code>last_collected_at</code>）。</p>
+        <h4><span class="method get">GET</span> /spls/{mint_address}/snapshot-root</h4>
+        <p><strong>描述:</strong> 最近一次保存的持有者余额 Merkle 根（<code>root</code>）、账户数（<code>leaf_count</code>）和采集时的 <code>slot</code>，需要启用 <code>--snapshot_roots</code>，没有记录时返回 404。</p>
+        <h4><span class="method get">GET</span> /spls/{mint_address}/snapshot-proof?pubkey={pubkey}</h4>
+        <p><strong>描述:</strong> 按当前入库余额生成 Token 账户的 Merkle 证明（<code>leaf</code>、<code>proof</code>、<code>root</code>），并返回最近一次保存的快照根（<code>snapshot</code>）及是否一致（<code>matches_snapshot</code>）。账户不存在或余额为0时返回 404。</p>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /holders/stats</h4>
+        <p><strong>描述:</strong> 按 mint 统计余额大于 0 的持有者数（holders）和余额合计（total_supply_held，按精度换算，保留全部小数位），按 mint 排序</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint</td><td>string</td><td>只统计该 Token，不指定时统计全部</td><td>mint=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+            <tr><td>exclude_known</td><td>bool</td><td>排除 owner 或 pubkey 被标注为 excluded_label_categories 分类（默认 program、burn、pool）的已知地址</td><td>exclude_known=true</td></tr>
+        </table>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": [
+        {"mint": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg", "holders": 1290, "total_supply_held": "98123456.789012"}
+    ],
+    "total": 1
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /holders/mints</h4>
+        <p><strong>描述:</strong> 列出 holder 表中出现的所有 mint 及其记录数，in_spl 为 false 表示该 mint 已不在 spl 视图中（可通过 /admin/cleanup-orphans 清理）</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>orphaned</td><td>bool</td><td>为 true 时只返回不在 spl 视图中的 mint</td><td>orphaned=true</td></tr>
+        </table>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": [
+        {"mint": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg", "holders": 1290, "in_spl": true},
+        {"mint": "So11111111111111111111111111111111111111112", "holders": 37, "in_spl": false}
+    ],
+    "total": 2
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /holders/new</h4>
+        <p><strong>描述:</strong> 返回指定 Token 在 since 之后首次出现的持有者，按首次出现时间倒序排列，每条记录额外返回 firstSeenAt</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint</td><td>string</td><td>Token 的 mint 地址（必填）</td><td>mint=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+            <tr><td>since</td><td>string</td><td>RFC3339 格式的时间，默认为该 mint 最近一次成功采集的开始时间</td><td>since=2025-09-09T00:00:00%2B08:00</td></tr>
+            <tr><td>page</td><td>int</td><td>页码，默认 1</td><td>page=1</td></tr>
+            <tr><td>limit</td><td>int</td><td>每页数量，默认 10，最大 1000</td><td>limit=100</td></tr>
+        </table>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /owners/{owner}/native-balance</h4>
+        <p><strong>描述:</strong> 返回采集时保存的钱包地址 SOL 余额（<code>lamports</code>、按9位精度换算的 <code>sol</code>、查询时的 <code>slot</code>），需要启用 <code>--collect_native_balance</code>，没有记录时返回 404</p>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /owners/multi-holders</h4>
+        <p><strong>描述:</strong> 返回持有至少 min_tokens 个不同被跟踪 Token 的 owner 及其持有的 mint 列表，按持有数量倒序排列（只统计余额大于 0 的记录）</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>min_tokens</td><td>int</td><td>最少持有的不同 Token 数量，默认 2</td><td>min_tokens=3</td></tr>
+            <tr><td>exclude_known</td><td>bool</td><td>排除 owner 或 pubkey 被标注为 excluded_label_categories 分类（默认 program、burn、pool）的已知地址</td><td>exclude_known=true</td></tr>
+            <tr><td>page</td><td>int</td><td>页码，默认 1</td><td>page=1</td></tr>
+            <tr><td>limit</td><td>int</td><td>每页数量，默认 10，最大 1000</td><td>limit=100</td></tr>
+        </table>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /holders/overlap</h4>
+        <p><strong>描述:</strong> 比较两个 Token 的持有者（owner）：返回同时持有两者的 owner 数（both）和各自独有的 owner 数（only_a、only_b），只统计余额大于 0 的记录</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint_a</td><td>string</td><td>第一个 Token 的 mint 地址（必填）</td><td>mint_a=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+            <tr><td>mint_b</td><td>string</td><td>第二个 Token 的 mint 地址（必填，不能与 mint_a 相同）</td><td>mint_b=EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v</td></tr>
+            <tr><td>include_owners</td><td>bool</td><td>同时返回同时持有两者的 owner 列表，按地址排序，支持 page、limit 分页</td><td>include_owners=true</td></tr>
+        </table>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /holders/stream</h4>
+        <p><strong>描述:</strong> 以 NDJSON（application/x-ndjson）格式按 id 升序流式导出指定 Token 的全部持有者，每行一条记录，最后一行为 {"next_cursor": "..."}，next_cursor 为空表示已导出全部记录。游标即最后一条记录的 id，导出中断时可以用已收到的最后一行的 id 继续</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint</td><td>string</td><td>Token 的 mint 地址（必填）</td><td>mint=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+            <tr><td>cursor</td><td>int</td><td>从 id 大于该值的记录开始导出，默认 0。不支持 Range 请求（响应头 Accept-Ranges: none），断点续传使用 cursor</td><td>cursor=10240</td></tr>
+            <tr><td>limit</td><td>int</td><td>本次最多导出的行数，默认 0 表示导出到末尾</td><td>limit=100000</td></tr>
+        </table>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /holders/tiers</h4>
+        <p><strong>描述:</strong> 统计指定 Token 持有量达到各阈值（ui_amount &gt;= 阈值）的账户数</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint</td><td>string</td><td>Token 的 mint 地址（必填）</td><td>mint=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+            <tr><td>tiers</td><td>string</td><td>逗号分隔的阈值列表，必须为升序正数，最多20个（必填）</td><td>tiers=1,100,10000</td></tr>
+            <tr><td>exclude_known</td><td>bool</td><td>排除 owner 或 pubkey 被标注为 excluded_label_categories 分类（默认 program、burn、pool）的已知地址</td><td>exclude_known=true</td></tr>
+        </table>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "mint": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg",
+        "tiers": [
+            {"min_amount": 1, "holders": 1200},
+            {"min_amount": 100, "holders": 85},
+            {"min_amount": 10000, "holders": 3}
+        ]
+    }
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /holders/histogram</h4>
+        <p><strong>描述:</strong> 返回指定 Token 持有量（ui_amount）的分布直方图，分桶在数据库中完成，客户端无需下载全部余额</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint</td><td>string</td><td>Token 的 mint 地址（必填）</td><td>mint=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+            <tr><td>buckets</td><td>int</td><td>分桶数量，1-100，默认20</td><td>buckets=20</td></tr>
+            <tr><td>scale</td><td>string</td><td>刻度：linear（默认）或 log；log 刻度会排除零余额账户</td><td>scale=log</td></tr>
+            <tr><td>exclude_known</td><td>bool</td><td>排除 owner 或 pubkey 被标注为 excluded_label_categories 分类（默认 program、burn、pool）的已知地址</td><td>exclude_known=true</td></tr>
+        </table>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "mint": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg",
+        "scale": "log",
+        "min": 0.01,
+        "max": 100000,
+        "excluded": 12,
+        "buckets": [
+            {"lower": 0.01, "upper": 1, "holders": 830},
+            {"lower": 1, "upper": 100, "holders": 370},
+            ...
+        ]
+    }
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method put">PUT</span> /holders/{mint_address}/{pubkey}</h4>
+        <p><strong>描述:</strong> 更新指定 Holder 的状态</p>
+        <p><strong>路径参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th></tr>
+            <tr><td>mint_address</td><td>string</td><td>Token 的 mint 地址</td></tr>
+            <tr><td>pubkey</td><td>string</td><td>Holder 的公钥地址</td></tr>
+        </table>
+        <p><strong>请求体:</strong></p>
+        <div class="code">{
+    "state": "initialized"
+}</div>
+        <p><strong>支持的状态值:</strong></p>
+        <ul>
+            <li><code>uninitialized</code> - 未初始化</li>
+<li><code>initialized</code> - 已初始化</li>
+<li><code>frozen</code> - 已冻结</li>
+        </ul>
+        <p><strong>示例:</strong> <code>/holders/Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg/13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf</code></p>
+        <p><strong>成功响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "id": 1,
+        "mint_address": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg",
+        "pubkey": "13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf",
+        "state": "initialized",
+        "owner": "13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf",
+        "amount": "1000000",
+        "uiAmount": 1.0,
+        "decimals": 6,
+        "updatedAt": "2024-01-01T12:05:00Z"
+    }
+}</div>
+        <p><strong>错误响应示例:</strong></p>
+        <div class="response">{
+    "success": false,
+    "error": "state 必须是 uninitialized、initialized、frozen 之一"
+}</div>
+    </div>
+
+    <h3>2. 地址标签</h3>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /labels</h4>
+        <p><strong>描述:</strong> 查询已知地址标签列表（支持分页，可按 <code>category</code> 过滤）</p>
+        <h4><span class="method post">POST</span> /labels</h4>
+        <p><strong>描述:</strong> 创建地址标签，地址已存在时返回 409</p>
+        <h4><span class="method get">GET</span> <span class="method put">PUT</span> <span class="method delete">DELETE</span> /labels/{address}</h4>
+        <p><strong>描述:</strong> 查询、更新或删除指定地址的标签（PUT 请求体无需 address 字段）</p>
+        <p><strong>请求体:</strong></p>
+        <div class="code">{
+    "address": "6Vmny6y3mLA4kaDTjnZJabvZ8jLKQBg4aqbaERHmEeLZ",
+    "label": "Example Exchange",
+    "category": "exchange"
+}</div>
+        <p><strong>支持的分类:</strong> <code>exchange</code>、<code>program</code>、<code>burn</code>、<code>pool</code>、<code>other</code></p>
+        <p>查询 <code>/holders?include_labels=true</code> 时，按持有者的 owner 地址关联标签。</p>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /watchlist</h4>
+        <p><strong>描述:</strong> 查询关注地址列表（支持分页）</p>
+        <h4><span class="method post">POST</span> /watchlist</h4>
+        <p><strong>描述:</strong> 添加关注地址（钱包地址或Token账户地址），地址已存在时返回 409</p>
+        <h4><span class="method get">GET</span> <span class="method put">PUT</span> <span class="method delete">DELETE</span> /watchlist/{address}</h4>
+        <p><strong>描述:</strong> 查询、更新备注或删除指定的关注地址</p>
+        <p><strong>请求体:</strong></p>
+        <div class="code">{
+    "address": "6Vmny6y3mLA4kaDTjnZJabvZ8jLKQBg4aqbaERHmEeLZ",
+    "note": "团队钱包"
+}</div>
+        <h4><span class="method get">GET</span> /watchlist/balances</h4>
+        <p><strong>描述:</strong> 返回全部关注地址在所有被跟踪 mint 中的当前余额，按 owner 或 pubkey 匹配 holder 表，可用 <code>mint</code> 参数过滤</p>
+    </div>
+
+    <h3>3. 管理接口</h3>
+
+    <div class="endpoint">
+        <h4><span class="method post">POST</span> /admin/cleanup-orphans</h4>
+        <p><strong>描述:</strong> 删除 mint 已不在 spl 视图中的孤立 Holder 记录，返回删除的记录数。spl 视图为空时不执行清理。</p>
+        <p><strong>定时清理:</strong> 启动时指定 <code>--orphan_cleanup_interval</code>（秒）可开启周期性清理，默认不启用。</p>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "deleted": 42
+    }
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method post">POST</span> /admin/recompute-ui-amount</h4>
+        <p><strong>描述:</strong> 按 amount 与 decimals 重新计算 ui_amount_string，检查与存储值不一致的记录，可选回填正确值</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint</td><td>string</td><td>只检查指定 mint（可选，默认检查全部）</td><td>mint=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+            <tr><td>fix</td><td>bool</td><td>为 true 时回填正确值，默认只报告</td><td>fix=true</td></tr>
+        </table>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "scanned": 5210,
+        "inconsistent": 2,
+        "fixed": 2,
+        "samples": [
+            {"mint": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg", "pubkey": "13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf", "stored": "2.0012179", "expected": "2.00121791"}
+        ]
+    }
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method post">POST</span> /admin/abort-collection</h4>
+        <p><strong>描述:</strong> 中止指定 mint 正在进行的采集（例如 RPC 或数据库响应缓慢导致卡住），未提交的事务会被回滚，其他 mint 的采集不受影响。没有正在进行的采集时返回 404。</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint</td><td>string</td><td>Token 的 mint 地址（必填）</td><td>mint=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+        </table>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "mint": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg",
+        "aborted": 1
+    }
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method post">POST</span> /admin/verify</h4>
+        <p><strong>描述:</strong> 实时获取指定 mint 的链上持有者，按与采集相同的规则筛选后与数据库中的记录比对，只读不写，用于排查采集偏差。mint 不在 spl 表中时返回 404，RPC 调用失败时返回 502。</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint</td><td>string</td><td>Token 的 mint 地址（必填）</td><td>mint=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+        </table>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "mint": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg",
+        "on_chain": 1520,
+        "stored": 1518,
+        "matching": 1510,
+        "divergent": 6,
+        "missing_in_db": 4,
+        "stale_in_db": 2,
+        "samples": {
+            "divergent": ["13nkreFLoEtJ5rRpknHtAUgKH1yo2CychKrtVuBLmwdf"],
+            "missing_in_db": [],
+            "stale_in_db": []
+        },
+        "checked_at": "2025-09-09T12:00:00+08:00"
+    }
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> <span class="method post">POST</span> /admin/read-only</h4>
+        <p><strong>描述:</strong> 查询或切换只读维护模式（POST ?enabled=true|false）。只读模式下写操作返回 503，定时采集暂停，查询接口照常可用</p>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "read_only": true
+    }
+}</div>
+    </div>
+
+    <h3>4. 系统状态</h3>
+    
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /health</h4>
+        <p><strong>描述:</strong> 健康检查端点，<code>active_workers</code> 为正在运行的数据采集goroutine数量</p>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "status": "healthy",
+        "version": "1.0.0",
+        "build_time": "2024-01-01 12:00:00 UTC",
+        "git_commit": "abc123",
+        "bin_name": "solana-spl-holder",
+        "active_workers": 1,
+        "read_only": false
+    }
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /metrics</h4>
+        <p><strong>描述:</strong> 以 Prometheus 文本格式输出采集goroutine指标：<code>solana_spl_holder_active_workers</code>、<code>solana_spl_holder_max_workers</code>、<code>solana_spl_holder_worker_rejections_total</code>，以及HTTP请求指标：<code>solana_spl_holder_http_requests_in_flight</code>、<code>solana_spl_holder_http_slow_requests_total</code></p>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /status</h4>
+        <p><strong>描述:</strong> 服务运行状态，包括最近一次 RPC 节点探测（getSlot/getHealth）的结果。slot 未推进或 getHealth 报错时 lagging 为 true。</p>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "rpc_probe_enabled": true,
+        "rpc": {
+            "slot": 312345678,
+            "healthy": true,
+            "lagging": false,
+            "last_probe_at": "2025-09-09T12:00:30+08:00",
+            "last_slot_advance_at": "2025-09-09T12:00:30+08:00"
+        }
+    }
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /status/collections</h4>
+        <p><strong>描述:</strong> 按时间倒序返回最近的采集记录（每个 mint 每次采集一条），holder_count_avg 为截至该次的最近5个采集周期中成功采集的持有者数平均值</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>mint</td><td>string</td><td>Token 的 mint 地址（可选）</td><td>mint=Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg</td></tr>
+            <tr><td>limit</td><td>int</td><td>返回条数，1-500，默认50</td><td>limit=10</td></tr>
+        </table>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": [
+        {
+            "id": 1024,
+            "mint": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg",
+            "status": "success",
+            "holder_count": 1235,
+            "holder_count_avg": 1231.4,
+            "upserted": 1290,
+            "skipped": 0,
+            "rpc_lagging": false,
+            "started_at": "2025-09-09T12:00:00+08:00",
+            "finished_at": "2025-09-09T12:00:03+08:00"
+        }
+    ],
+    "limit": 10
+}</div>
+    </div>
+
+    <div class="endpoint">
+        <h4><span class="method get">GET</span> /status/collections/{mint_address}/trend</h4>
+        <p><strong>描述:</strong> 按天汇总最近若干天成功采集的持有者数量（余额大于0），holder_count 为当天最后一次采集的数量</p>
+        <p><strong>查询参数:</strong></p>
+        <table>
+            <tr><th>参数</th><th>类型</th><th>描述</th><th>示例</th></tr>
+            <tr><td>days</td><td>int</td><td>统计天数，1-90，默认7</td><td>days=7</td></tr>
+        </table>
+        <p><strong>响应示例:</strong></p>
+        <div class="response">{
+    "success": true,
+    "data": {
+        "mint": "Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg",
+        "days": 7,
+        "change": 35,
+        "points": [
+            {"day": "2025-09-08", "holder_count": 1200, "min_count": 1195, "max_count": 1200, "cycles": 288},
+            {"day": "2025-09-09", "holder_count": 1235, "min_count": 1200, "max_count": 1236, "cycles": 288}
+        ]
+    }
+}</div>
+    </div>
+    
+    <h2>📝 响应格式</h2>
+    <p>所有 API 响应都遵循统一的 JSON 格式：</p>
+    <div class="code">{
+    "success": boolean,     // 请求是否成功
+    "data": object,        // 响应数据（成功时）
+    "error": string,       // 错误信息（失败时）
+    "total": int,          // 总记录数（分页时）
+    "page": int,           // 当前页码（分页时）
+    "limit": int,          // 每页数量（分页时）
+    "collection_in_progress": boolean  // 按 mint 查询持有者时，该 mint 正在采集中（数据可能即将变化）
+}</div>
+    <p>所有 JSON 接口都支持 <code>?pretty=true</code> 参数，以缩进格式输出响应，便于调试。</p>
+    <p>请求带有 <code>Accept: application/msgpack</code> 时，以 MessagePack 编码返回相同结构的响应，默认返回 JSON。</p>
+    
+    <h2>🔧 数据验证</h2>
+    <ul>
+        <li><strong>state:</strong> 必填，必须是 uninitialized、initialized、frozen 之一</li>
+    </ul>
+    
+    <h2>⚡ 特性</h2>
+    <ul>
+
+        <li>✅ 分页支持</li>
+        <li>✅ 多字段排序（金额、公钥、时间）</li>
+        <li>✅ 状态过滤（uninitialized/initialized/frozen）</li>
+        <li>✅ 地址过滤（mint_address、owner）</li>
+        <li>✅ 组合查询（多条件同时使用）</li>
+        <li>✅ 数据验证</li>
+        <li>✅ 错误处理</li>
+        <li>✅ 自动数据采集</li>
+        <li>✅ 健康检查</li>
+    </ul>
+    
+    <p style="margin-top: 40px; text-align: center; color: #666;">
+        <em>Solana SPL Holder API v1.0.0 - 构建于 Go</em>
+    </p>
+</body>
+</html>`
+}
@@ -0,0 +1,2903 @@
+package splholder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// =============================================================================
+// 测试用数据库驱动
+// =============================================================================
+
+// fakeQueryFunc 根据SQL和参数返回结果集
+type fakeQueryFunc func(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error)
+
+// fakeExecFunc 处理写操作，返回 driver.ErrSkip 时按未注册处理（回退到Prepare而失败）
+type fakeExecFunc func(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error)
+
+type fakeBackend struct {
+	query fakeQueryFunc
+	exec  fakeExecFunc
+}
+
+var (
+	fakeBackendsMu sync.Mutex
+	fakeBackends   = map[string]fakeBackend{}
+	registerOnce   sync.Once
+)
+
+// fakeDriver 以DSN为键查找测试注册的查询函数，不需要真实数据库
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	fakeBackendsMu.Lock()
+	defer fakeBackendsMu.Unlock()
+	backend, ok := fakeBackends[dsn]
+	if !ok {
+		return nil, fmt.Errorf("未注册的测试数据源: %s", dsn)
+	}
+	return &fakeConn{query: backend.query, exec: backend.exec}, nil
+}
+
+type fakeConn struct {
+	query fakeQueryFunc
+	exec  fakeExecFunc
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.exec == nil {
+		return nil, driver.ErrSkip
+	}
+	return c.exec(ctx, query, args)
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("测试驱动不支持Prepare")
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return &fakeTx{conn: c}, nil }
+
+// fakeTx 把提交和回滚作为 "COMMIT"/"ROLLBACK" 查询交给测试的查询函数记录；
+// 未注册写操作函数时，事务中的写操作仍因不支持Prepare而失败
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	_, err := tx.conn.query(context.Background(), "COMMIT", nil)
+	return err
+}
+
+func (tx *fakeTx) Rollback() error {
+	_, err := tx.conn.query(context.Background(), "ROLLBACK", nil)
+	return err
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.query(ctx, query, args)
+}
+
+// fakeRows 逐行返回预置数据，可在返回某一行时执行回调
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+	onRow   func(i int)
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *fakeRows) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	if r.onRow != nil {
+		r.onRow(r.next)
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
+
+// openFakeDB 注册查询函数并打开对应的 *sql.DB
+func openFakeDB(t *testing.T, query fakeQueryFunc) *sql.DB {
+	t.Helper()
+	return openFakeDBWithExec(t, query, nil)
+}
+
+// openFakeDBWithExec 同 openFakeDB，写操作交给 exec 处理
+func openFakeDBWithExec(t *testing.T, query fakeQueryFunc, exec fakeExecFunc) *sql.DB {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register("fakedb", fakeDriver{}) })
+
+	dsn := t.Name()
+	fakeBackendsMu.Lock()
+	fakeBackends[dsn] = fakeBackend{query: query, exec: exec}
+	fakeBackendsMu.Unlock()
+
+	db, err := sql.Open("fakedb", dsn)
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakeBackendsMu.Lock()
+		delete(fakeBackends, dsn)
+		fakeBackendsMu.Unlock()
+	})
+	return db
+}
+
+// holderRow 生成一行与 apiHandlerMariaDB 查询列一致的持有者数据
+func holderRow(i int) []driver.Value {
+	now := time.Now()
+	return []driver.Value{
+		int64(i + 1), "mint1", fmt.Sprintf("pubkey%d", i), int64(2039280), false, "owner1",
+		"initialized", int64(6), "1000000", float64(1), "1", "", "0", now, now,
+	}
+}
+
+var holderColumns = []string{
+	"id", "mint", "pubkey", "lamports", "is_native", "owner", "state",
+	"decimals", "amount", "ui_amount", "ui_amount_string", "delegate", "delegated_amount", "created_at", "updated_at",
+}
+
+// =============================================================================
+// 持有者查询测试
+// =============================================================================
+
+// TestHoldersHandlerStopsOnClientDisconnect 客户端断开后停止扫描结果并释放连接
+func TestHoldersHandlerStopsOnClientDisconnect(t *testing.T) {
+	const totalRows = 100
+	const cancelAt = 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var rows *fakeRows
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(totalRows)}}}, nil
+		}
+		values := make([][]driver.Value, totalRows)
+		for i := range values {
+			values[i] = holderRow(i)
+		}
+		// 模拟客户端在读取到第 cancelAt 行时断开连接
+		rows = &fakeRows{columns: holderColumns, values: values, onRow: func(i int) {
+			if i == cancelAt {
+				cancel()
+			}
+		}}
+		return rows, nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/holders?mint=mint1&limit=1000", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	apiHandlerMariaDB(db, 0, newCollectionRegistry(), nil, nil)(rec, req)
+
+	if rows == nil {
+		t.Fatal("未执行持有者查询")
+	}
+	if rows.next >= totalRows {
+		t.Errorf("客户端断开后应停止扫描，实际读取了全部 %d 行", rows.next)
+	}
+	if !rows.isClosed() {
+		t.Error("客户端断开后结果集应被关闭")
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("客户端断开后不应写入响应，实际写入: %s", rec.Body.String())
+	}
+}
+
+// TestHoldersHandlerCompletesWithoutCancel 未取消时正常返回全部数据
+func TestHoldersHandlerCompletesWithoutCancel(t *testing.T) {
+	const totalRows = 5
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(totalRows)}}}, nil
+		}
+		values := make([][]driver.Value, totalRows)
+		for i := range values {
+			values[i] = holderRow(i)
+		}
+		return &fakeRows{columns: holderColumns, values: values}, nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/holders?mint=mint1", nil)
+	rec := httptest.NewRecorder()
+	apiHandlerMariaDB(db, 0, newCollectionRegistry(), nil, nil)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 实际 %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"total":5`) {
+		t.Errorf("期望返回总数5，实际响应: %s", rec.Body.String())
+	}
+}
+
+// TestHoldersHandlerFields fields 参数只查询并返回指定的列
+func TestHoldersHandlerFields(t *testing.T) {
+	var selectQuery string
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(1)}}}, nil
+		}
+		selectQuery = query
+		return &fakeRows{columns: []string{"pubkey", "owner"}, values: [][]driver.Value{{"pubkey0", "owner1"}}}, nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/holders?mint=mint1&fields=pubkey,owner", nil)
+	rec := httptest.NewRecorder()
+	apiHandlerMariaDB(db, 0, newCollectionRegistry(), nil, nil)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 实际 %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if !strings.HasPrefix(selectQuery, "SELECT h.pubkey, h.owner FROM") {
+		t.Errorf("应只查询指定的列，实际SQL: %s", selectQuery)
+	}
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if len(resp.Data) != 1 || len(resp.Data[0]) != 2 || resp.Data[0]["pubkey"] != "pubkey0" || resp.Data[0]["owner"] != "owner1" {
+		t.Errorf("期望只返回 pubkey 和 owner，实际: %v", resp.Data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/holders?mint=mint1&fields=pubkey,password", nil)
+	rec = httptest.NewRecorder()
+	apiHandlerMariaDB(db, 0, newCollectionRegistry(), nil, nil)(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("未知字段期望状态码 %d, 实际 %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+// TestHolderStream NDJSON导出按id游标分批读取，最后一行返回下一次导出的游标
+func TestHolderStream(t *testing.T) {
+	const totalRows = 5
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		cursor, batch := args[1].Value.(int64), args[2].Value.(int64)
+		var values [][]driver.Value
+		for i := int(cursor); i < totalRows && int64(len(values)) < batch; i++ {
+			values = append(values, holderRow(i)) // holderRow(i) 的id为 i+1
+		}
+		return &fakeRows{columns: holderColumns, values: values}, nil
+	})
+	handler := handleHolderStream(db)
+
+	stream := func(url string) ([]int64, holderStreamEnd) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("期望状态码 %d, 实际 %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		var ids []int64
+		for _, line := range lines[:len(lines)-1] {
+			var h Holder
+			if err := json.Unmarshal([]byte(line), &h); err != nil {
+				t.Fatalf("解析行失败: %v: %s", err, line)
+			}
+			ids = append(ids, h.ID)
+		}
+		var end holderStreamEnd
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &end); err != nil {
+			t.Fatalf("解析最后一行失败: %v", err)
+		}
+		return ids, end
+	}
+
+	ids, end := stream("/holders/stream?mint=mint1&limit=3")
+	if fmt.Sprint(ids) != "[1 2 3]" || end.NextCursor != "3" {
+		t.Fatalf("第一段期望 [1 2 3] 和游标3，实际 %v, %+v", ids, end)
+	}
+	ids, end = stream("/holders/stream?mint=mint1&cursor=" + end.NextCursor)
+	if fmt.Sprint(ids) != "[4 5]" || end.NextCursor != "" {
+		t.Fatalf("第二段期望 [4 5] 且导出结束，实际 %v, %+v", ids, end)
+	}
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/holders/stream?mint=mint1&cursor=abc", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("无效游标期望状态码 %d, 实际 %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// 不支持按字节续传：忽略 Range 头并返回完整响应
+	req := httptest.NewRequest(http.MethodGet, "/holders/stream?mint=mint1", nil)
+	req.Header.Set("Range", "bytes=100-")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Accept-Ranges") != "none" || !strings.HasPrefix(rec.Body.String(), `{"id":1,`) {
+		t.Errorf("期望忽略Range返回完整响应和 Accept-Ranges: none，实际 %d %v: %.40s", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
+
+// TestHoldersQueryUsesMintIndex 按mint查询并按余额排序时使用 idx_mint_ui_amount，不需要 filesort。
+// 需要已初始化且有数据的数据库，通过 SPLHOLDER_TEST_DSN 指定，未设置时跳过
+func TestHoldersQueryUsesMintIndex(t *testing.T) {
+	var selectQuery string
+	var selectArgs []interface{}
+	fake := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(0)}}}, nil
+		}
+		selectQuery = query
+		for _, arg := range args {
+			selectArgs = append(selectArgs, arg.Value)
+		}
+		return &fakeRows{columns: holderColumns}, nil
+	})
+	req := httptest.NewRequest(http.MethodGet, "/holders?state=initialized&mint=mint1&sort=-ui_amount", nil)
+	apiHandlerMariaDB(fake, 0, newCollectionRegistry(), nil, nil)(httptest.NewRecorder(), req)
+	if selectQuery == "" {
+		t.Fatal("未执行持有者查询")
+	}
+
+	dsn := os.Getenv("SPLHOLDER_TEST_DSN")
+	if dsn == "" {
+		t.Skip("未设置 SPLHOLDER_TEST_DSN，跳过 EXPLAIN 检查")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("EXPLAIN "+selectQuery, selectArgs...)
+	if err != nil {
+		t.Fatalf("EXPLAIN 失败: %v", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatal(err)
+		}
+		plan := make(map[string]string, len(columns))
+		for i, col := range columns {
+			plan[col] = values[i].String
+		}
+		if plan["table"] != "h" {
+			continue
+		}
+		if plan["key"] != "idx_mint_ui_amount" {
+			t.Errorf("期望使用 idx_mint_ui_amount 索引，实际执行计划: %v", plan)
+		}
+		if strings.Contains(plan["Extra"], "Using filesort") {
+			t.Errorf("按索引顺序读取时不应需要 filesort，实际执行计划: %v", plan)
+		}
+	}
+}
+
+// =============================================================================
+// 数据一致性核对测试
+// =============================================================================
+
+// rpcAccount 生成 getProgramAccounts jsonParsed 格式的单个账户
+func rpcAccount(pubkey, amount string) string {
+	return fmt.Sprintf(`{"pubkey":%q,"account":{"lamports":2039280,"owner":"TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA","data":{"parsed":{"type":"account","info":{"mint":"mint1","owner":"owner1","state":"initialized","tokenAmount":{"amount":%q,"decimals":6,"uiAmount":1,"uiAmountString":"1"}}}}}}`, pubkey, amount)
+}
+
+// TestVerifyHolders 按 pubkey 比对链上与数据库记录并分类统计
+func TestVerifyHolders(t *testing.T) {
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":[%s,%s,%s]}`,
+			rpcAccount("same", "100"), rpcAccount("changed", "200"), rpcAccount("new", "300"))
+	}))
+	defer rpc.Close()
+
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		if !strings.HasPrefix(query, "SELECT pubkey, amount FROM holder") {
+			return nil, fmt.Errorf("意外的查询: %s", query)
+		}
+		return &fakeRows{columns: []string{"pubkey", "amount"}, values: [][]driver.Value{
+			{"same", "100"}, {"changed", "150"}, {"closed", "50"},
+		}}, nil
+	})
+
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: "jsonParsed"}
+	report, err := verifyHolders(context.Background(), config, db, NewSolanaRPCClient(rpc.URL, rpc.Client()), "mint1", MintOptions{})
+	if err != nil {
+		t.Fatalf("核对失败: %v", err)
+	}
+
+	if report.OnChain != 3 || report.Stored != 3 {
+		t.Errorf("期望链上3条、数据库3条，实际链上%d条、数据库%d条", report.OnChain, report.Stored)
+	}
+	if report.Matching != 1 || report.Divergent != 1 || report.MissingInDB != 1 || report.StaleInDB != 1 {
+		t.Errorf("分类统计错误: %+v", report)
+	}
+	if len(report.Samples.Divergent) != 1 || report.Samples.Divergent[0] != "changed" {
+		t.Errorf("余额不一致示例错误: %v", report.Samples.Divergent)
+	}
+	if len(report.Samples.MissingInDB) != 1 || report.Samples.MissingInDB[0] != "new" {
+		t.Errorf("数据库缺失示例错误: %v", report.Samples.MissingInDB)
+	}
+	if len(report.Samples.StaleInDB) != 1 || report.Samples.StaleInDB[0] != "closed" {
+		t.Errorf("数据库过期示例错误: %v", report.Samples.StaleInDB)
+	}
+}
+
+// TestVerifyHoldersMinUIAmount 设置 min_ui_amount 时低于阈值的链上账户与采集一样被过滤，不报告为数据库缺失
+func TestVerifyHoldersMinUIAmount(t *testing.T) {
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":[%s,%s,%s]}`,
+			rpcAccount("dust1", "100"), rpcAccount("dust2", "249"), rpcAccount("holder", "300"))
+	}))
+	defer rpc.Close()
+
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		if !strings.HasPrefix(query, "SELECT pubkey, amount FROM holder") {
+			return nil, fmt.Errorf("意外的查询: %s", query)
+		}
+		return &fakeRows{columns: []string{"pubkey", "amount"}, values: [][]driver.Value{{"holder", "300"}}}, nil
+	})
+
+	// decimals 为6，0.00025 换算为原始数量250
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: "jsonParsed", MinUIAmount: "0.00025"}
+	report, err := verifyHolders(context.Background(), config, db, NewSolanaRPCClient(rpc.URL, rpc.Client()), "mint1", MintOptions{})
+	if err != nil {
+		t.Fatalf("核对失败: %v", err)
+	}
+	if report.OnChain != 1 || report.Matching != 1 || report.MissingInDB != 0 || report.StaleInDB != 0 || report.Divergent != 0 {
+		t.Errorf("期望只核对余额不低于阈值的1个账户且全部一致，实际: %+v", report)
+	}
+}
+
+// TestFetchAndStoreDataTruncatedResponse 响应在账户数组中途截断时整次采集失败，不写入已解析的部分账户
+func TestFetchAndStoreDataTruncatedResponse(t *testing.T) {
+	accounts := rpcAccount("holder1", "100") + "," + rpcAccount("holder2", "200")
+	tests := []struct {
+		name        string
+		body        string
+		withContext bool
+	}{
+		{"数组未结束", `{"jsonrpc":"2.0","id":"1","result":[` + accounts, false},
+		{"账户中途截断", `{"jsonrpc":"2.0","id":"1","result":[` + accounts[:len(accounts)-40], false},
+		{"数组结束但对象未结束", `{"jsonrpc":"2.0","id":"1","result":[` + accounts + `]`, false},
+		{"withContext 数组未结束", `{"jsonrpc":"2.0","id":"1","result":{"context":{"slot":100},"value":[` + accounts, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, tt.body)
+			}))
+			defer rpc.Close()
+
+			var queries int
+			db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+				queries++
+				return nil, fmt.Errorf("不应访问数据库: %s", query)
+			})
+
+			config := &Config{RPCURL: rpc.URL, RPCEncoding: "jsonParsed"}
+			var minSlot *slotTracker
+			if tt.withContext {
+				minSlot = &slotTracker{}
+			}
+			result, err := fetchAndStoreData(context.Background(), config, db, NewSolanaRPCClient(rpc.URL, rpc.Client()), "mint1", MintOptions{}, minSlot)
+			if err == nil || !strings.Contains(err.Error(), "解析JSON响应") {
+				t.Fatalf("期望解析响应失败，实际: %v", err)
+			}
+			if result.Upserted != 0 || queries != 0 {
+				t.Errorf("截断的响应不应写入数据库: upserted=%d, queries=%d", result.Upserted, queries)
+			}
+			if minSlot != nil && minSlot.Load() != 0 {
+				t.Errorf("截断的响应不应更新最高slot: %d", minSlot.Load())
+			}
+		})
+	}
+
+	// 完整响应正常解析
+	resp, err := decodeProgramAccountsResponse(strings.NewReader(`{"jsonrpc":"2.0","result":[`+accounts+`],"id":"1"}`), false)
+	if err != nil || len(resp.Result) != 2 {
+		t.Fatalf("期望解析出2个账户，实际 %d 个, 错误: %v", len(resp.Result), err)
+	}
+}
+
+// TestFetchAndStoreDataAtomicity 账户写入失败时 best-effort 跳过并提交，strict 回滚整个mint
+func TestFetchAndStoreDataAtomicity(t *testing.T) {
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":[%s,%s]}`, rpcAccount("holder1", "100"), rpcAccount("holder2", "200"))
+	}))
+	defer rpc.Close()
+
+	tests := []struct {
+		atomicity   string
+		wantErr     bool
+		wantSkipped int
+		wantTx      string
+	}{
+		{CollectionAtomicityBestEffort, false, 2, "COMMIT"},
+		{CollectionAtomicityStrict, true, 0, "ROLLBACK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.atomicity, func(t *testing.T) {
+			var txEvents []string
+			// 测试驱动不支持写操作，每个账户的写入都会失败
+			db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+				if strings.HasPrefix(query, "SELECT id, amount, decimals FROM holder") {
+					return &fakeRows{columns: []string{"id", "amount", "decimals"}}, nil
+				}
+				txEvents = append(txEvents, query)
+				return nil, nil
+			})
+			config := &Config{RPCURL: rpc.URL, RPCEncoding: "jsonParsed", CollectionAtomicity: tt.atomicity}
+			result, err := fetchAndStoreData(context.Background(), config, db, NewSolanaRPCClient(rpc.URL, rpc.Client()), "mint1", MintOptions{}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误 = %v, 期望出错 = %v", err, tt.wantErr)
+			}
+			if result.Skipped != tt.wantSkipped || result.Upserted != 0 {
+				t.Errorf("期望跳过 %d 条、写入 0 条，实际 %+v", tt.wantSkipped, result)
+			}
+			if len(txEvents) != 1 || txEvents[0] != tt.wantTx {
+				t.Errorf("期望事务结束方式 %s, 实际 %v", tt.wantTx, txEvents)
+			}
+		})
+	}
+}
+
+// TestFetchAndStoreDataDecimalsChange 本次写入后仍带着旧精度的记录标记为精度变化，并在提交前按新精度重新计算
+func TestFetchAndStoreDataDecimalsChange(t *testing.T) {
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":[%s]}`, rpcAccount("holder1", "100"))
+	}))
+	defer rpc.Close()
+
+	tests := []struct {
+		name        string
+		stored      [][]driver.Value // id, amount, decimals
+		wantChanged bool
+		wantWrites  []string
+	}{
+		{"首次采集", nil, false, nil},
+		{"精度未变化", [][]driver.Value{{int64(1), "100", int64(6)}}, false, nil},
+		{"旧记录精度不同", [][]driver.Value{{int64(2), "1234567", int64(9)}}, true,
+			[]string{"UPDATE holder SET decimals = ?, ui_amount = ?, ui_amount_string = ? WHERE id = ? [6 1.234567 1.234567 2]"}},
+		{"多种精度", [][]driver.Value{{int64(1), "100", int64(6)}, {int64(2), "1234567", int64(9)}, {int64(3), "5000000", int64(8)}}, true,
+			[]string{
+				"UPDATE holder SET decimals = ?, ui_amount = ?, ui_amount_string = ? WHERE id = ? [6 1.234567 1.234567 2]",
+				"UPDATE holder SET decimals = ?, ui_amount = ?, ui_amount_string = ? WHERE id = ? [6 5 5 3]",
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events, writes []string
+			db := openFakeDBWithExec(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+				if strings.HasPrefix(query, "SELECT id, amount, decimals FROM holder WHERE mint = ? AND decimals <> ?") {
+					if len(args) != 2 || args[0].Value != "mint1" || args[1].Value != int64(6) {
+						t.Errorf("应按本次采集的精度查询该mint的旧记录, 参数: %v", args)
+					}
+					rows := &fakeRows{columns: []string{"id", "amount", "decimals"}}
+					for _, row := range tt.stored {
+						if row[2] != args[1].Value {
+							rows.values = append(rows.values, row)
+						}
+					}
+					return rows, nil
+				}
+				events = append(events, query)
+				return nil, nil
+			}, func(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+				if !strings.HasPrefix(query, "UPDATE holder SET decimals") {
+					return nil, driver.ErrSkip
+				}
+				values := make([]string, len(args))
+				for i, arg := range args {
+					values[i] = fmt.Sprint(arg.Value)
+				}
+				writes = append(writes, fmt.Sprintf("%s [%s]", query, strings.Join(values, " ")))
+				return driver.RowsAffected(1), nil
+			})
+			config := &Config{RPCURL: rpc.URL, RPCEncoding: "jsonParsed"}
+			result, err := fetchAndStoreData(context.Background(), config, db, NewSolanaRPCClient(rpc.URL, rpc.Client()), "mint1", MintOptions{}, nil)
+			if err != nil {
+				t.Fatalf("采集失败: %v", err)
+			}
+			if result.DecimalsChanged != tt.wantChanged {
+				t.Errorf("DecimalsChanged = %v, 期望 %v", result.DecimalsChanged, tt.wantChanged)
+			}
+			if strings.Join(writes, "\n") != strings.Join(tt.wantWrites, "\n") {
+				t.Errorf("期望重新计算\n%v\n实际\n%v", tt.wantWrites, writes)
+			}
+			if strings.Join(events, ",") != "COMMIT" {
+				t.Errorf("期望重新计算后提交事务, 实际 %v", events)
+			}
+		})
+	}
+}
+
+// TestFetchAndStoreDataRPCRetry RPC节点返回可重试的状态码时按退避重试，重试成功后照常入库；重试用尽时采集失败
+func TestFetchAndStoreDataRPCRetry(t *testing.T) {
+	oldDelay := rpcRetryBaseDelay
+	rpcRetryBaseDelay = time.Millisecond
+	defer func() { rpcRetryBaseDelay = oldDelay }()
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		wantErr    bool
+		wantCalls  int
+	}{
+		{"重试后成功", 2, false, 3},
+		{"重试次数不足", 1, true, 2},
+		{"不重试", 0, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				// 每次重试都应带上完整的请求体
+				var req RPCRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "getProgramAccounts" {
+					t.Errorf("第%d次请求的请求体无效: %+v, %v", calls, req, err)
+				}
+				switch calls {
+				case 1:
+					w.WriteHeader(http.StatusServiceUnavailable)
+				case 2:
+					w.WriteHeader(http.StatusTooManyRequests)
+				default:
+					fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":[%s,%s]}`, rpcAccount("holder1", "100"), rpcAccount("holder2", "200"))
+				}
+			}))
+			defer rpc.Close()
+
+			var txEvents, written []string
+			db := openFakeDBWithExec(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+				if strings.HasPrefix(query, "SELECT id, amount, decimals FROM holder") {
+					return &fakeRows{columns: []string{"id", "amount", "decimals"}}, nil
+				}
+				txEvents = append(txEvents, query)
+				return nil, nil
+			}, func(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+				if !strings.HasPrefix(query, holderUpsertInsert) {
+					return nil, fmt.Errorf("意外的写操作: %s", query)
+				}
+				// 每行的第二个参数为pubkey
+				perRow := strings.Count(holderUpsertRow, "?")
+				for i := 1; i < len(args); i += perRow {
+					written = append(written, args[i].Value.(string))
+				}
+				return driver.RowsAffected(len(args) / perRow), nil
+			})
+			client := NewSolanaRPCClient(rpc.URL, rpc.Client())
+			client.SetMaxRetries(tt.maxRetries)
+			config := &Config{RPCURL: rpc.URL, RPCEncoding: "jsonParsed"}
+			result, err := fetchAndStoreData(context.Background(), config, db, client, "mint1", MintOptions{}, nil)
+			if calls != tt.wantCalls {
+				t.Errorf("期望请求 %d 次，实际 %d 次", tt.wantCalls, calls)
+			}
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "状态码") {
+					t.Errorf("期望状态码错误，实际 %v", err)
+				}
+				if len(txEvents) != 0 || len(written) != 0 {
+					t.Errorf("RPC失败时不应写入数据库，实际 %v %v", txEvents, written)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("采集失败: %v", err)
+			}
+			if result.Upserted != 2 || result.Skipped != 0 || len(txEvents) != 1 || txEvents[0] != "COMMIT" {
+				t.Errorf("期望两个账户写入并提交，实际 %+v, 事务 %v", result, txEvents)
+			}
+			if strings.Join(written, ",") != "holder1,holder2" {
+				t.Errorf("期望写入 holder1、holder2，实际 %v", written)
+			}
+		})
+	}
+}
+
+// benchmarkHolderItems 生成n个模拟的 getProgramAccounts 账户
+func benchmarkHolderItems(n int) []ResultItem {
+	items := make([]ResultItem, n)
+	for i := range items {
+		items[i].Pubkey = fmt.Sprintf("bench_pubkey_%06d", i)
+		items[i].Account.Lamports = 2039280
+		items[i].Account.Data.Parsed.Type = "account"
+		info := &items[i].Account.Data.Parsed.Info
+		info.Owner = fmt.Sprintf("bench_owner_%06d", i)
+		info.State = "initialized"
+		info.TokenAmount.Decimals = 6
+		info.TokenAmount.Amount = BigAmount{i: big.NewInt(int64(i+1) * 1000000)}
+		info.TokenAmount.UIAmount = float64(i + 1)
+		info.TokenAmount.UIAmountString = strconv.Itoa(i + 1)
+	}
+	return items
+}
+
+// upsertHoldersBatched 与 upsertHolders 对照的多行写入实现，每条语句写入 batchSize 行
+func upsertHoldersBatched(tx *sql.Tx, mintAddress string, items []ResultItem, batchSize int) error {
+	for start := 0; start < len(items); start += batchSize {
+		end := min(start+batchSize, len(items))
+		rows := make([]string, 0, end-start)
+		var args []interface{}
+		for _, item := range items[start:end] {
+			rowArgs, err := holderUpsertArgs(mintAddress, item)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, holderUpsertRow)
+			args = append(args, rowArgs...)
+		}
+		if _, err := tx.Exec(holderUpsertInsert+strings.Join(rows, ", ")+holderUpsertOnDuplicate, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BenchmarkHolderUpsert 比较逐条写入（采集使用的 upsertHolders）与多行批量写入的吞吐量，
+// 每次迭代在事务中写入1000个账户后回滚。需要已初始化的数据库，通过 SPLHOLDER_TEST_DSN 指定，未设置时跳过
+func BenchmarkHolderUpsert(b *testing.B) {
+	dsn := os.Getenv("SPLHOLDER_TEST_DSN")
+	if dsn == "" {
+		b.Skip("未设置 SPLHOLDER_TEST_DSN，跳过写入基准测试")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		b.Fatalf("打开测试数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	const mintAddress = "bench_mint"
+	items := benchmarkHolderItems(1000)
+	config := &Config{}
+
+	run := func(b *testing.B, upsert func(tx *sql.Tx) error) {
+		for i := 0; i < b.N; i++ {
+			tx, err := db.Begin()
+			if err != nil {
+				b.Fatalf("开始事务失败: %v", err)
+			}
+			if err := upsert(tx); err != nil {
+				tx.Rollback()
+				b.Fatalf("写入失败: %v", err)
+			}
+			if err := tx.Rollback(); err != nil {
+				b.Fatalf("回滚事务失败: %v", err)
+			}
+		}
+		b.ReportMetric(float64(len(items)*b.N)/b.Elapsed().Seconds(), "rows/s")
+	}
+
+	b.Run("single", func(b *testing.B) {
+		run(b, func(tx *sql.Tx) error {
+			result, _, err := upsertHolders(tx, config, mintAddress, items, 0)
+			if err == nil && result.Upserted != len(items) {
+				err = fmt.Errorf("期望写入%d条，实际%+v", len(items), result)
+			}
+			return err
+		})
+	})
+	for _, batchSize := range []int{100, 500} {
+		b.Run(fmt.Sprintf("batched_%d", batchSize), func(b *testing.B) {
+			run(b, func(tx *sql.Tx) error {
+				return upsertHoldersBatched(tx, mintAddress, items, batchSize)
+			})
+		})
+	}
+}
+
+// TestFetchProgramAccountsNullResult result 为 null 时返回错误，空数组视为没有持有者
+func TestFetchProgramAccountsNullResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		withContext bool
+		wantErr     bool
+	}{
+		{"空数组", `{"jsonrpc":"2.0","id":"1","result":[]}`, false, false},
+		{"null", `{"jsonrpc":"2.0","id":"1","result":null}`, false, true},
+		{"缺少result", `{"jsonrpc":"2.0","id":"1"}`, false, true},
+		{"withContext 空数组", `{"jsonrpc":"2.0","id":"1","result":{"context":{"slot":1},"value":[]}}`, true, false},
+		{"withContext value为null", `{"jsonrpc":"2.0","id":"1","result":{"context":{"slot":1},"value":null}}`, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, tt.body)
+			}))
+			defer rpc.Close()
+
+			config := &Config{RPCURL: rpc.URL, RPCEncoding: "jsonParsed"}
+			var minSlot *slotTracker
+			if tt.withContext {
+				minSlot = &slotTracker{}
+			}
+			items, _, err := fetchProgramAccounts(context.Background(), config, NewSolanaRPCClient(rpc.URL, rpc.Client()), "mint1", MintOptions{}, minSlot)
+			if tt.wantErr {
+				if !errors.Is(err, errNullRPCResult) {
+					t.Errorf("期望 errNullRPCResult, 实际: %v", err)
+				}
+				return
+			}
+			if err != nil || len(items) != 0 {
+				t.Errorf("空数组期望无错误且没有账户, 实际 %d 个账户, 错误: %v", len(items), err)
+			}
+		})
+	}
+}
+
+// TestMinRawAmount 最小余额按decimals换算为原始数量并向上取整
+func TestMinRawAmount(t *testing.T) {
+	tests := []struct {
+		raw      string
+		decimals int
+		want     string
+		wantErr  bool
+	}{
+		{"0.01", 6, "10000", false},
+		{"1", 0, "1", false},
+		{"0.0000001", 6, "1", false}, // 0.1 向上取整
+		{"1.5", 9, "1500000000", false},
+		{"", 6, "", false},
+		{"0", 6, "", false},
+		{"-1", 6, "", true},
+		{"1/3", 6, "", true},
+		{"abc", 6, "", true},
+	}
+	for _, tt := range tests {
+		min, err := parseMinUIAmount(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMinUIAmount(%q) 错误 = %v, 期望出错 = %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if tt.want == "" {
+			if min != nil {
+				t.Errorf("parseMinUIAmount(%q) 期望不过滤，实际 %v", tt.raw, min)
+			}
+			continue
+		}
+		if got := minRawAmount(min, tt.decimals).String(); got != tt.want {
+			t.Errorf("minRawAmount(%q, %d) = %s, 期望 %s", tt.raw, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+// TestConfigValidateTLS 证书和私钥必须同时设置且可以加载
+func TestConfigValidateTLS(t *testing.T) {
+	base := Config{RPCURL: "http://rpc", DBConnStr: "dsn", IntervalTime: 60, ListenPort: 8091, RPCEncoding: RPCEncodingJSONParsed}
+	if err := base.Validate(); err != nil {
+		t.Fatalf("未配置TLS时应通过校验: %v", err)
+	}
+	onlyCert := base
+	onlyCert.TLSCertFile = "cert.pem"
+	if err := onlyCert.Validate(); err == nil || !strings.Contains(err.Error(), "同时设置") {
+		t.Errorf("只设置证书时期望报错，实际: %v", err)
+	}
+	missing := base
+	missing.TLSCertFile, missing.TLSKeyFile = "/nonexistent/cert.pem", "/nonexistent/key.pem"
+	if err := missing.Validate(); err == nil || !strings.Contains(err.Error(), "加载TLS证书") {
+		t.Errorf("证书文件不存在时期望报错，实际: %v", err)
+	}
+}
+
+// TestConfigCollectionInterval 设置 Interval 时覆盖 IntervalTime，且同样不能小于10秒
+func TestConfigCollectionInterval(t *testing.T) {
+	base := Config{RPCURL: "http://rpc", DBConnStr: "dsn", IntervalTime: 60, ListenPort: 8091, RPCEncoding: RPCEncodingJSONParsed}
+	if got := base.CollectionInterval(); got != time.Minute {
+		t.Errorf("未设置 Interval 时期望1m0s，实际%v", got)
+	}
+	tests := []struct {
+		interval time.Duration
+		wantErr  bool
+	}{
+		{90 * time.Minute, false},
+		{10 * time.Second, false},
+		{5 * time.Second, true},
+		{-time.Minute, true},
+	}
+	for _, tt := range tests {
+		config := base
+		config.Interval = tt.interval
+		if got := config.CollectionInterval(); got != tt.interval {
+			t.Errorf("期望采集间隔%v，实际%v", tt.interval, got)
+		}
+		if err := config.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Interval=%v: 错误 = %v, 期望出错 = %v", tt.interval, err, tt.wantErr)
+		}
+	}
+}
+
+// TestHolderDropAlerter 持有者数量下降超过阈值时告警，回升前不重复告警
+func TestHolderDropAlerter(t *testing.T) {
+	var received []HolderDropAlert
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert HolderDropAlert
+		json.NewDecoder(r.Body).Decode(&alert)
+		received = append(received, alert)
+	}))
+	defer webhook.Close()
+
+	alerts := NewHolderDropAlerter(10, webhook.URL)
+	noOverride := sql.NullFloat64{}
+	steps := []struct {
+		previous, current int64
+		wantAlert         bool
+	}{
+		{1000, 950, false}, // 下降5%，未超过阈值
+		{950, 800, true},   // 下降15.8%，告警
+		{800, 700, false},  // 仍处于告警状态
+		{700, 900, false},  // 未回升到 950*(1-5%)=902.5，不解除
+		{900, 700, false},  // 仍处于告警状态
+		{700, 910, false},  // 回升，解除告警
+		{910, 800, true},   // 再次下降，重新告警
+	}
+	for i, step := range steps {
+		if got := alerts.Check(context.Background(), "mint1", step.previous, step.current, noOverride); got != step.wantAlert {
+			t.Errorf("第%d步 %d→%d: 告警 = %v, 期望 %v", i+1, step.previous, step.current, got, step.wantAlert)
+		}
+	}
+	if len(received) != 2 || received[0].PreviousCount != 950 || received[0].CurrentCount != 800 {
+		t.Errorf("webhook收到的告警不符合预期: %+v", received)
+	}
+
+	// 按mint覆盖阈值：50%时下降20%不告警，0表示该mint不告警
+	if alerts.Check(context.Background(), "mint2", 1000, 800, sql.NullFloat64{Float64: 50, Valid: true}) {
+		t.Error("覆盖阈值50%时下降20%不应告警")
+	}
+	if alerts.enabled(sql.NullFloat64{Float64: 0, Valid: true}) {
+		t.Error("覆盖阈值为0时应不检查")
+	}
+}
+
+// TestSolanaRPCClientGetProgramAccounts 请求参数按选项构造，RPC错误以 *RPCError 返回
+func TestSolanaRPCClientGetProgramAccounts(t *testing.T) {
+	var params []json.RawMessage
+	rpcErr := false
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "getProgramAccounts" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		params = req.Params
+		if rpcErr {
+			io.WriteString(w, `{"jsonrpc":"2.0","id":"1","error":{"code":-32016,"message":"Minimum context slot has not been reached"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":{"context":{"slot":200},"value":[%s]}}`, rpcAccount("holder1", "100"))
+	}))
+	defer rpc.Close()
+
+	client := NewSolanaRPCClient(rpc.URL, rpc.Client())
+	filters := []map[string]interface{}{{"dataSize": 165}}
+	opts := ProgramAccountsOptions{Encoding: RPCEncodingJSONParsed, WithContext: true, MinContextSlot: 150}
+	result, err := client.GetProgramAccounts(context.Background(), tokenProgramID, filters, opts)
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if len(result.Accounts) != 1 || result.ContextSlot != 200 {
+		t.Errorf("期望1个账户、slot 200，实际 %d 个、slot %d", len(result.Accounts), result.ContextSlot)
+	}
+	if len(params) != 2 || string(params[0]) != `"`+tokenProgramID+`"` {
+		t.Fatalf("请求参数错误: %s", params)
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(params[1], &config); err != nil {
+		t.Fatal(err)
+	}
+	if config["encoding"] != RPCEncodingJSONParsed || config["withContext"] != true || config["minContextSlot"] != float64(150) {
+		t.Errorf("请求配置错误: %v", config)
+	}
+
+	rpcErr = true
+	_, err = client.GetProgramAccounts(context.Background(), tokenProgramID, filters, opts)
+	var target *RPCError
+	if !errors.As(err, &target) || target.Code != rpcErrMinContextSlotNotReached {
+		t.Errorf("期望 *RPCError 代码 %d，实际: %v", rpcErrMinContextSlotNotReached, err)
+	}
+}
+
+// TestFetchProgramAccountsProgramID 按mint配置的program_id查询经典Token Program或Token-2022，
+// Token-2022账户中未知的扩展不影响解析，不支持的程序不发送请求
+func TestFetchProgramAccountsProgramID(t *testing.T) {
+	var programs []string
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Params) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var program string
+		json.Unmarshal(req.Params[0], &program)
+		programs = append(programs, program)
+		io.WriteString(w, `{"jsonrpc":"2.0","id":"1","result":[{"pubkey":"holder1","account":{"lamports":2039280,"owner":"`+program+`",`+
+			`"data":{"parsed":{"type":"account","info":{"mint":"mint1","owner":"owner1","state":"initialized",`+
+			`"tokenAmount":{"amount":"100","decimals":6,"uiAmount":0.0001,"uiAmountString":"0.0001"},`+
+			`"extensions":[{"extension":"immutableOwner"},{"extension":"futureExtension","state":{"x":1}}]}}}}}]}`)
+	}))
+	defer rpc.Close()
+	client := NewSolanaRPCClient(rpc.URL, rpc.Client())
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: RPCEncodingJSONParsed}
+
+	for _, tc := range []struct {
+		programID string
+		want      string
+	}{
+		{"", token2022ProgramID},
+		{tokenProgramID, tokenProgramID},
+		{token2022ProgramID, token2022ProgramID},
+	} {
+		programs = nil
+		items, _, err := fetchProgramAccounts(context.Background(), config, client, "mint1", MintOptions{ProgramID: tc.programID}, nil)
+		if err != nil || len(items) != 1 || items[0].Account.Data.Parsed.Info.TokenAmount.Amount.String() != "100" {
+			t.Fatalf("program_id=%q: 解析失败 %+v, 错误: %v", tc.programID, items, err)
+		}
+		if len(programs) != 1 || programs[0] != tc.want {
+			t.Errorf("program_id=%q: 期望查询 %s，实际 %v", tc.programID, tc.want, programs)
+		}
+	}
+
+	programs = nil
+	_, _, err := fetchProgramAccounts(context.Background(), config, client, "mint1", MintOptions{ProgramID: "11111111111111111111111111111111"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "不支持的program_id") || len(programs) != 0 {
+		t.Errorf("期望不支持的program_id直接返回错误，实际: %v, 请求: %v", err, programs)
+	}
+}
+
+// TestFetchProgramAccountsMaxResponseBytes 响应体超过 rpc_max_response_bytes 时采集失败，未超过时正常解析
+func TestFetchProgramAccountsMaxResponseBytes(t *testing.T) {
+	body := fmt.Sprintf(`{"jsonrpc":"2.0","id":"1","result":[%s,%s]}`, rpcAccount("holder1", "100"), rpcAccount("holder2", "200"))
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer rpc.Close()
+	client := NewSolanaRPCClient(rpc.URL, rpc.Client())
+
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: RPCEncodingJSONParsed, RPCMaxResponseBytes: int64(len(body))}
+	items, _, err := fetchProgramAccounts(context.Background(), config, client, "mint1", MintOptions{}, nil)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("响应体等于上限时应正常解析: %d 个账户, 错误: %v", len(items), err)
+	}
+
+	config.RPCMaxResponseBytes = int64(len(body)) - 1
+	if _, _, err := fetchProgramAccounts(context.Background(), config, client, "mint1", MintOptions{}, nil); !errors.Is(err, errRPCResponseTooLarge) {
+		t.Errorf("期望响应体超限错误，实际: %v", err)
+	}
+}
+
+// TestFetchProgramAccountsProfile context中带有分阶段耗时记录时，分别记录等待响应头和读取解析响应体的耗时
+func TestFetchProgramAccountsProfile(t *testing.T) {
+	const delay = 30 * time.Millisecond
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		io.WriteString(w, `{"jsonrpc":"2.0","id":"1","result":[`+rpcAccount("holder1", "100"))
+		w.(http.Flusher).Flush()
+		time.Sleep(delay)
+		io.WriteString(w, `]}`)
+	}))
+	defer rpc.Close()
+	client := NewSolanaRPCClient(rpc.URL, rpc.Client())
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: RPCEncodingJSONParsed}
+
+	profile := &collectionProfile{}
+	items, _, err := fetchProgramAccounts(withCollectionProfile(context.Background(), profile), config, client, "mint1", MintOptions{}, nil)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("期望解析出1个账户，实际 %d 个, 错误: %v", len(items), err)
+	}
+	if profile.RPC.Request < delay || profile.RPC.Decode < delay {
+		t.Errorf("期望请求和解析阶段都不少于 %v，实际 %+v", delay, profile.RPC)
+	}
+
+	// 未启用时不记录
+	if collectionProfileFromContext(context.Background()) != nil {
+		t.Error("未设置时应返回nil")
+	}
+}
+
+// TestFetchProgramAccountsMinContextSlot 记录响应的slot，后续请求以其作为 minContextSlot
+func TestFetchProgramAccountsMinContextSlot(t *testing.T) {
+	var requests []map[string]interface{}
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var params map[string]interface{}
+		json.Unmarshal(req.Params[1], &params)
+		requests = append(requests, params)
+
+		if len(requests) == 1 {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":{"context":{"slot":100},"value":[%s]}}`, rpcAccount("holder1", "100"))
+			return
+		}
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":"1","error":{"code":-32016,"message":"Minimum context slot has not been reached"}}`)
+	}))
+	defer rpc.Close()
+
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: "jsonParsed"}
+	var minSlot slotTracker
+	items, slot, err := fetchProgramAccounts(context.Background(), config, NewSolanaRPCClient(rpc.URL, rpc.Client()), "mint1", MintOptions{}, &minSlot)
+	if err != nil {
+		t.Fatalf("首次请求失败: %v", err)
+	}
+	if len(items) != 1 || slot != 100 || minSlot.Load() != 100 {
+		t.Fatalf("期望1条记录且响应slot和最高slot为100，实际%d条、响应slot %d、最高slot %d", len(items), slot, minSlot.Load())
+	}
+	if requests[0]["withContext"] != true || requests[0]["minContextSlot"] != nil {
+		t.Errorf("首次请求参数错误: %v", requests[0])
+	}
+
+	// 落后节点返回 -32016 时采集失败，而不是写入旧快照
+	if _, _, err := fetchProgramAccounts(context.Background(), config, NewSolanaRPCClient(rpc.URL, rpc.Client()), "mint1", MintOptions{}, &minSlot); err == nil {
+		t.Fatal("期望落后节点返回错误")
+	}
+	if got := requests[1]["minContextSlot"]; got != float64(100) {
+		t.Errorf("minContextSlot = %v, 期望 100", got)
+	}
+
+	// slot只增不减
+	minSlot.Observe(50)
+	if minSlot.Load() != 100 {
+		t.Errorf("最高slot被较小的值覆盖: %d", minSlot.Load())
+	}
+}
+
+// =============================================================================
+// 响应头测试
+// =============================================================================
+
+// TestWithCacheControl 只为GET请求的成功响应设置 Cache-Control
+func TestWithCacheControl(t *testing.T) {
+	handler := withCacheControl(60, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") == "true" {
+			sendJSONResponse(w, http.StatusNotFound, APIResponse{Success: false, Error: "not found"})
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, APIResponse{Success: true})
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		want   string
+	}{
+		{"GET成功", http.MethodGet, "/holders", "public, max-age=60"},
+		{"GET错误", http.MethodGet, "/holders?fail=true", ""},
+		{"POST请求", http.MethodPost, "/holders", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(tt.method, tt.target, nil))
+			if got := rec.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+
+	// 与 ?pretty=true 组合时仍缩进输出
+	rec := httptest.NewRecorder()
+	withPrettyJSON(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/holders?pretty=true", nil))
+	if !strings.Contains(rec.Body.String(), "\n  ") {
+		t.Errorf("期望缩进的JSON，实际: %s", rec.Body.String())
+	}
+}
+
+func TestEncodeMsgpack(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"正fixint", 5, []byte{0x05}},
+		{"负fixint", -5, []byte{0xfb}},
+		{"int16", -200, []byte{0xd1, 0xff, 0x38}},
+		{"uint16", 300, []byte{0xcd, 0x01, 0x2c}},
+		{"字符串", "ab", []byte{0xa2, 'a', 'b'}},
+		{"数组", []string{"a"}, []byte{0x91, 0xa1, 'a'}},
+		{"time按JSON输出编码", ts, append([]byte{0xb4}, "2024-01-02T03:04:05Z"...)},
+		{"BigAmount按字符串编码", BigAmount{i: big.NewInt(12)}, []byte{0xa2, '1', '2'}},
+		{"RawMessage按解析后的值编码", json.RawMessage(`{"a":[1,1.5]}`), []byte{0x81, 0xa1, 'a', 0x92, 0x01, 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"json标签与omitempty", struct {
+			A int        `json:"a"`
+			B string     `json:"b,omitempty"`
+			C int        `json:"-"`
+			T *time.Time `json:"t"`
+		}{A: 1, C: 2, T: &ts}, append([]byte{0x82, 0xa1, 'a', 0x01, 0xa1, 't', 0xb4}, "2024-01-02T03:04:05Z"...)},
+		{
+			"响应结构与JSON一致",
+			APIResponse{Success: true, Data: map[string]int{"a": 1}, Total: 300},
+			append(append([]byte{0x83, 0xa7}, "success"...), append(append([]byte{0xc3, 0xa4}, "data"...),
+				append([]byte{0x81, 0xa1, 'a', 0x01, 0xa5}, append([]byte("total"), 0xcd, 0x01, 0x2c)...)...)...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := encodeMsgpack(&buf, tt.value); err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("编码结果 = % x, 期望 % x", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithMsgpack(t *testing.T) {
+	handler := withMsgpack(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sendJSONResponse(w, http.StatusOK, APIResponse{Success: true})
+	}))
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+	}{
+		{"默认JSON", "", "application/json"},
+		{"msgpack", "application/msgpack", contentTypeMsgpack},
+		{"x-msgpack", "application/json;q=0.5, application/x-msgpack", contentTypeMsgpack},
+		{"q=0表示拒绝", "application/msgpack;q=0", "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/holders", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, 期望 %q", got, tt.contentType)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q, 期望 Accept", got)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// 数据库配置测试
+// =============================================================================
+
+// TestCheckDSNTimeOptions 缺少 parseTime=True 时启动报错
+func TestCheckDSNTimeOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		wantErr bool
+	}{
+		{"完整参数", "root:123456@tcp(localhost:3306)/rwa?charset=utf8mb4&parseTime=True&loc=Local", false},
+		{"缺少loc", "root:123456@tcp(localhost:3306)/rwa?parseTime=true", false},
+		{"缺少parseTime", "root:123456@tcp(localhost:3306)/rwa?charset=utf8mb4&loc=Local", true},
+		{"没有参数", "root:123456@tcp(localhost:3306)/rwa", true},
+		{"parseTime=false", "root:123456@tcp(localhost:3306)/rwa?parseTime=false", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDSNTimeOptions(tt.dsn)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkDSNTimeOptions(%q) 错误 = %v, 期望出错 = %v", tt.dsn, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// Server 测试
+// =============================================================================
+
+// TestServerHandler 通过 NewServerWithDB 直接测试真实路由，不需要真实数据库
+func TestServerHandler(t *testing.T) {
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(1)}}}, nil
+		}
+		return &fakeRows{columns: holderColumns, values: [][]driver.Value{holderRow(0)}}, nil
+	})
+	handler := NewServerWithDB(&Config{IntervalTime: 300}, db, nil).Handler()
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"健康检查", http.MethodGet, "/health", http.StatusOK, `"status":"healthy"`},
+		{"持有者列表", http.MethodGet, "/holders?mint=mint1", http.StatusOK, `"pubkey":"pubkey0"`},
+		{"只返回总数", http.MethodGet, "/holders?mint=mint1&count_only=true", http.StatusOK, `"data":{"total":1}`},
+		{"HEAD只执行计数", http.MethodHead, "/holders?mint=mint1", http.StatusOK, ""},
+		{"无效的owner", http.MethodGet, "/holders?owner=invalid!", http.StatusBadRequest, `"success":false`},
+		{"不支持的方法", http.MethodDelete, "/holders/mint1/pubkey0", http.StatusMethodNotAllowed, `"success":false`},
+		{"地址格式错误", http.MethodPut, "/holders/Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg/not-an-address", http.StatusBadRequest, `pubkey格式错误`},
+		{"采集指标", http.MethodGet, "/metrics", http.StatusOK, "solana_spl_holder_active_workers 0"},
+		{"未知路径", http.MethodGet, "/unknown", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("期望状态码 %d, 实际 %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("响应中缺少 %s，实际: %s", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+// =============================================================================
+// 入库校验测试
+// =============================================================================
+
+// TestUpsertHolderRejectsInvalidDecimals 精度超出 0-18 时不写入数据库
+func TestUpsertHolderRejectsInvalidDecimals(t *testing.T) {
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		t.Errorf("精度无效时不应访问数据库: %s", query)
+		return nil, fmt.Errorf("unexpected query")
+	})
+
+	for _, decimals := range []int{-1, 19, 255} {
+		item := ResultItem{Pubkey: "pubkey1"}
+		item.Account.Data.Parsed.Info.TokenAmount.Decimals = decimals
+		err := upsertHolderMariaDB(db, "mint1", item)
+		if err == nil || !strings.Contains(err.Error(), "超出范围") {
+			t.Errorf("精度 %d: 期望超出范围错误，实际: %v", decimals, err)
+		}
+	}
+}
+
+// TestUpsertHolderUIAmountStringFallback RPC响应缺少 uiAmountString 时按 amount 和 decimals 计算
+func TestUpsertHolderUIAmountStringFallback(t *testing.T) {
+	account := `{"pubkey":"pubkey1","account":{"lamports":2039280,"data":{"parsed":{"type":"account","info":{"mint":"mint1","owner":"owner1","state":"initialized","tokenAmount":{"amount":"150000000","decimals":8,"uiAmount":1.5}}}}}}`
+	resp, err := decodeProgramAccountsResponse(strings.NewReader(`{"jsonrpc":"2.0","id":"1","result":[`+account+`]}`), false)
+	if err != nil || len(resp.Result) != 1 {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	item := resp.Result[0]
+	if item.Account.Data.Parsed.Info.TokenAmount.UIAmountString != "" {
+		t.Fatalf("测试数据不应包含 uiAmountString")
+	}
+
+	args, err := holderUpsertArgs("mint1", item)
+	if err != nil {
+		t.Fatalf("生成参数失败: %v", err)
+	}
+	// 参数顺序与 holderUpsertRow 一致，第10个为 ui_amount_string
+	if args[9] != "1.5" {
+		t.Errorf("期望 ui_amount_string 为 1.5，实际 %v", args[9])
+	}
+
+	item.Account.Data.Parsed.Info.TokenAmount.UIAmountString = "1.50"
+	if args, _ := holderUpsertArgs("mint1", item); args[9] != "1.50" {
+		t.Errorf("RPC返回的 uiAmountString 应原样保存，实际 %v", args[9])
+	}
+}
+
+// =============================================================================
+// 采集周期测试
+// =============================================================================
+
+// TestRunID 采集周期ID为UUID v4格式，通过context传递
+func TestRunID(t *testing.T) {
+	id := newRunID()
+	parts := strings.Split(id, "-")
+	if len(parts) != 5 || len(id) != 36 || parts[2][0] != '4' {
+		t.Fatalf("期望UUID v4格式，实际 %s", id)
+	}
+	if other := newRunID(); other == id {
+		t.Errorf("两次生成的ID相同: %s", id)
+	}
+
+	if got := runIDFromContext(context.Background()); got != "-" {
+		t.Errorf("不在采集周期中时期望 -，实际 %s", got)
+	}
+	ctx, cancel := context.WithCancel(withRunID(context.Background(), id))
+	defer cancel()
+	if got := runIDFromContext(ctx); got != id {
+		t.Errorf("派生的context应保留采集周期ID，期望 %s，实际 %s", id, got)
+	}
+}
+
+// TestWorkerFailureRatio 失败的mint比例超过阈值时采集周期返回错误
+func TestWorkerFailureRatio(t *testing.T) {
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer rpc.Close()
+
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case query == "SELECT mint FROM spl":
+			return &fakeRows{columns: []string{"mint"}, values: [][]driver.Value{{"mint1"}, {"mint2"}}}, nil
+		case strings.Contains(query, "information_schema.COLUMNS"):
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(0)}}}, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+
+	tests := []struct {
+		name     string
+		maxRatio float64
+		wantErr  bool
+	}{
+		{"超过阈值", 0.5, true},
+		{"不检查", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{RPCURL: rpc.URL, RPCEncoding: RPCEncodingJSONParsed, MaxFailureRatio: tt.maxRatio}
+			err := worker(context.Background(), config, db, newCollectionRegistry(), &RPCHealthMonitor{}, nil, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("worker() 错误 = %v, 期望出错 = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestWorkerDecodeFailure RPC响应截断时记录解析错误，并在采集周期的汇总中统计无法解析的mint数
+func TestWorkerDecodeFailure(t *testing.T) {
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"jsonrpc":"2.0","id":"1","result":[`+rpcAccount("holder1", "100"))
+	}))
+	defer rpc.Close()
+
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case query == "SELECT mint FROM spl":
+			return &fakeRows{columns: []string{"mint"}, values: [][]driver.Value{{"mint1"}}}, nil
+		case strings.Contains(query, "information_schema.COLUMNS"):
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(0)}}}, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+
+	var logs bytes.Buffer
+	errorLog.SetOutput(&logs)
+	defer errorLog.SetOutput(os.Stderr)
+
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: RPCEncodingJSONParsed, MaxFailureRatio: 0}
+	err := worker(context.Background(), config, db, newCollectionRegistry(), &RPCHealthMonitor{}, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "其中 1 个RPC响应无法解析") {
+		t.Errorf("期望汇总中统计1个无法解析的响应，实际: %v", err)
+	}
+	if !strings.Contains(logs.String(), "采集mint地址 mint1: 解析JSON响应: ") {
+		t.Errorf("期望记录解析错误，实际日志: %s", logs.String())
+	}
+
+	// 直接调用时可通过 errors.Is 判断，错误信息保留底层的解析错误
+	_, err = NewSolanaRPCClient(rpc.URL, rpc.Client()).GetProgramAccounts(context.Background(), tokenProgramID, nil, ProgramAccountsOptions{Encoding: RPCEncodingJSONParsed})
+	if !errors.Is(err, errRPCResponseDecode) || !strings.Contains(err.Error(), "unexpected end of JSON input") {
+		t.Errorf("期望 errRPCResponseDecode 并包含底层解析错误，实际: %v", err)
+	}
+}
+
+// TestRequestTracking 统计正在处理的请求数，耗时超过阈值的请求计为慢请求，并在 /metrics 中输出
+func TestRequestTracking(t *testing.T) {
+	requests := NewRequestTracker(20 * time.Millisecond)
+	var inFlight int64
+	handler := withRequestTracking(requests, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight = requests.InFlight()
+		if r.URL.Path == "/slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if inFlight != 1 || requests.InFlight() != 0 || requests.SlowRequests() != 0 {
+		t.Errorf("快请求: 处理中%d、结束后%d、慢请求%d", inFlight, requests.InFlight(), requests.SlowRequests())
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if requests.SlowRequests() != 1 {
+		t.Errorf("期望记录1次慢请求，实际%d", requests.SlowRequests())
+	}
+
+	// 阈值为0时不记录慢请求
+	disabled := NewRequestTracker(0)
+	withRequestTracking(disabled, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if disabled.SlowRequests() != 0 {
+		t.Errorf("阈值为0时不应记录慢请求，实际%d", disabled.SlowRequests())
+	}
+
+	rec := httptest.NewRecorder()
+	handleMetrics(NewWorkerLimiter(2), requests)(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{"solana_spl_holder_http_requests_in_flight 0\n", "solana_spl_holder_http_slow_requests_total 1\n"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("/metrics 缺少 %q:\n%s", want, rec.Body.String())
+		}
+	}
+}
+
+// TestWorkerLimiter 活跃worker达到上限后拒绝启动并计数
+func TestWorkerLimiter(t *testing.T) {
+	workers := NewWorkerLimiter(2)
+	if !workers.TryAcquire() || !workers.TryAcquire() {
+		t.Fatal("未达上限时应允许启动")
+	}
+	if workers.TryAcquire() {
+		t.Fatal("达到上限时应拒绝启动")
+	}
+	if workers.Active() != 2 || workers.Rejected() != 1 {
+		t.Errorf("期望活跃2个、拒绝1次，实际活跃%d个、拒绝%d次", workers.Active(), workers.Rejected())
+	}
+	workers.Release()
+	if !workers.TryAcquire() {
+		t.Error("释放后应允许再次启动")
+	}
+
+	unlimited := NewWorkerLimiter(0)
+	for i := 0; i < 10; i++ {
+		if !unlimited.TryAcquire() {
+			t.Fatal("上限为0时不应拒绝")
+		}
+	}
+}
+
+// TestPriceFeed 价格在TTL内缓存，价格源失败时使用旧缓存，没有价格的mint不设置 value_usd
+func TestPriceFeed(t *testing.T) {
+	requests := 0
+	failing := false
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"mint1": 2.5}`)
+	}))
+	defer source.Close()
+
+	feed := NewPriceFeed(source.URL, time.Hour)
+	for i := 0; i < 2; i++ {
+		if _, err := feed.Prices(context.Background()); err != nil {
+			t.Fatalf("获取价格失败: %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("TTL内期望只请求1次价格源，实际%d次", requests)
+	}
+
+	// 缓存过期后价格源失败，继续使用旧价格
+	feed.ttl = 0
+	failing = true
+	prices, err := feed.Prices(context.Background())
+	if err != nil || prices["mint1"] != 2.5 {
+		t.Fatalf("期望使用缓存的价格，实际 %v, %v", prices, err)
+	}
+
+	holders := []Holder{{Mint: "mint1", UIAmount: 4}, {Mint: "mint2", UIAmount: 4}}
+	annotateHolderValues(holders, prices)
+	if holders[0].ValueUSD == nil || *holders[0].ValueUSD != 10 {
+		t.Errorf("mint1的value_usd错误: %v", holders[0].ValueUSD)
+	}
+	if holders[1].ValueUSD != nil {
+		t.Errorf("没有价格的mint不应设置value_usd: %v", *holders[1].ValueUSD)
+	}
+
+	if NewPriceFeed("", time.Minute) != nil {
+		t.Error("未配置URL时应返回nil")
+	}
+}
+
+// TestListMultiTokenOwners 按owner汇总持有的mint列表
+func TestListMultiTokenOwners(t *testing.T) {
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT COUNT(*)"):
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(2)}}}, nil
+		case strings.HasPrefix(query, "SELECT owner, COUNT(DISTINCT mint)"):
+			if args[0].Value != int64(2) {
+				return nil, fmt.Errorf("min_tokens参数错误: %v", args[0].Value)
+			}
+			return &fakeRows{columns: []string{"owner", "mint_count"}, values: [][]driver.Value{
+				{"owner1", int64(3)}, {"owner2", int64(2)},
+			}}, nil
+		case strings.HasPrefix(query, "SELECT DISTINCT owner, mint"):
+			if len(args) != 2 {
+				return nil, fmt.Errorf("期望2个owner参数，实际%d个", len(args))
+			}
+			return &fakeRows{columns: []string{"owner", "mint"}, values: [][]driver.Value{
+				{"owner1", "mintA"}, {"owner1", "mintB"}, {"owner1", "mintC"}, {"owner2", "mintA"}, {"owner2", "mintB"},
+			}}, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+
+	owners, total, err := listMultiTokenOwners(db, 2, 10, 0, nil)
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if total != 2 || len(owners) != 2 {
+		t.Fatalf("期望总数2、返回2条，实际总数%d、返回%d条", total, len(owners))
+	}
+	if owners[0].Owner != "owner1" || owners[0].MintCount != 3 || strings.Join(owners[0].Mints, ",") != "mintA,mintB,mintC" {
+		t.Errorf("owner1汇总错误: %+v", owners[0])
+	}
+	if strings.Join(owners[1].Mints, ",") != "mintA,mintB" {
+		t.Errorf("owner2汇总错误: %+v", owners[1])
+	}
+}
+
+// TestExcludeKnown exclude_known=true 时按配置的标签分类排除pubkey或owner为已知地址的账户，未配置分类时返回400
+func TestExcludeKnown(t *testing.T) {
+	if got, err := ParseLabelCategories(" Program, pool ,"); err != nil || strings.Join(got, ",") != "program,pool" {
+		t.Errorf("解析分类错误: %v, %v", got, err)
+	}
+	if _, err := ParseLabelCategories("program,lp"); err == nil {
+		t.Error("期望未知分类返回错误")
+	}
+
+	type call struct {
+		query string
+		args  []driver.Value
+	}
+	var calls []call
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		c := call{query: query}
+		for _, a := range args {
+			c.args = append(c.args, a.Value)
+		}
+		calls = append(calls, c)
+		switch {
+		case strings.HasPrefix(query, "SELECT COALESCE"):
+			return &fakeRows{columns: []string{"tier"}, values: [][]driver.Value{{int64(7)}}}, nil
+		case strings.HasPrefix(query, "SELECT COUNT(*), MIN("):
+			return &fakeRows{columns: []string{"count", "min", "max"}, values: [][]driver.Value{{int64(2), float64(0), float64(2)}}}, nil
+		case strings.HasPrefix(query, "SELECT COUNT(*)"):
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(0)}}}, nil
+		}
+		return &fakeRows{columns: []string{"bucket", "count"}}, nil
+	})
+	categories := []string{"program", "pool"}
+	wantCond := func(c call, table string) bool {
+		n := len(c.args)
+		return strings.Contains(c.query, "NOT EXISTS (SELECT 1 FROM address_label x WHERE x.address IN ("+table+".owner, "+table+".pubkey) AND x.category IN (?, ?))") &&
+			n >= 2 && c.args[n-2] == "program" && c.args[n-1] == "pool"
+	}
+
+	serve := func(handler http.HandlerFunc, target string) *httptest.ResponseRecorder {
+		calls = nil
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := serve(handleHolderTiers(db, categories), "/holders/tiers?mint=mint1&tiers=1&exclude_known=true")
+	if rec.Code != http.StatusOK || len(calls) != 1 || !wantCond(calls[0], "holder") {
+		t.Errorf("tiers未排除已知地址(%d): %+v", rec.Code, calls)
+	}
+	rec = serve(handleHolderTiers(db, categories), "/holders/tiers?mint=mint1&tiers=1")
+	if rec.Code != http.StatusOK || len(calls) != 1 || strings.Contains(calls[0].query, "address_label") {
+		t.Errorf("未指定exclude_known时不应排除(%d): %+v", rec.Code, calls)
+	}
+	rec = serve(handleHolderTiers(db, nil), "/holders/tiers?mint=mint1&tiers=1&exclude_known=true")
+	if rec.Code != http.StatusBadRequest || len(calls) != 0 {
+		t.Errorf("未配置分类时期望400且不查询，实际%d: %+v", rec.Code, calls)
+	}
+
+	rec = serve(apiHandlerMariaDB(db, 0, newCollectionRegistry(), nil, categories), "/holders?mint=mint1&exclude_known=true&count_only=true")
+	if rec.Code != http.StatusOK || len(calls) != 1 || !wantCond(calls[0], "h") || calls[0].args[0] != "mint1" {
+		t.Errorf("/holders未排除已知地址(%d): %+v", rec.Code, calls)
+	}
+
+	// 直方图三次查询都要排除，分桶查询的分类参数在分桶参数之后
+	rec = serve(handleHolderHistogram(db, categories), "/holders/histogram?mint=mint1&buckets=2&scale=log&exclude_known=true")
+	if rec.Code != http.StatusOK || len(calls) != 3 {
+		t.Fatalf("直方图查询错误(%d): %s %+v", rec.Code, rec.Body.String(), calls)
+	}
+	for _, c := range calls {
+		if !wantCond(c, "holder") {
+			t.Errorf("直方图查询未排除已知地址: %+v", c)
+		}
+	}
+
+	calls = nil
+	if _, _, err := listMultiTokenOwners(db, 2, 10, 0, categories); err != nil {
+		t.Fatalf("查询多币种持有者失败: %v", err)
+	}
+	if len(calls) != 2 || !strings.Contains(calls[0].query, "x.category IN (?, ?)") ||
+		fmt.Sprint(calls[0].args) != "[program pool 2]" || fmt.Sprint(calls[1].args) != "[program pool 2 10 0]" {
+		t.Errorf("多币种持有者参数顺序错误: %+v", calls)
+	}
+}
+
+// TestWatchlistBalances 关注地址按owner和pubkey两种方式关联holder表，mint参数同时作用于两个分支
+func TestWatchlistBalances(t *testing.T) {
+	updatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		if !strings.Contains(query, "ON h.owner = w.address") || !strings.Contains(query, "ON h.pubkey = w.address") {
+			return nil, fmt.Errorf("缺少owner或pubkey关联: %s", query)
+		}
+		if len(args) != 2 || args[0].Value != "mintA" || args[1].Value != "mintA" {
+			return nil, fmt.Errorf("mint参数错误: %v", args)
+		}
+		return &fakeRows{columns: []string{"address", "note", "mint", "pubkey", "owner", "decimals", "amount", "ui_amount", "ui_amount_string", "updated_at"}, values: [][]driver.Value{
+			{"owner1", "团队钱包", "mintA", "pubkey1", "owner1", int64(6), "1500000", 1.5, "1.5", updatedAt},
+			{"pubkey2", nil, "mintA", "pubkey2", "owner2", int64(6), "2000000", 2.0, "2", updatedAt},
+		}}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	handleWatchlistBalances(db)(rec, httptest.NewRequest(http.MethodGet, "/watchlist/balances?mint=mintA", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望状态码200，实际%d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data  []WatchlistBalance `json:"data"`
+		Total int                `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Data) != 2 {
+		t.Fatalf("期望返回2条，实际总数%d、返回%d条", resp.Total, len(resp.Data))
+	}
+	if resp.Data[0].Note != "团队钱包" || resp.Data[0].UIAmountString != "1.5" {
+		t.Errorf("第1条余额错误: %+v", resp.Data[0])
+	}
+	if resp.Data[1].Note != "" || resp.Data[1].Owner != "owner2" {
+		t.Errorf("第2条余额错误: %+v", resp.Data[1])
+	}
+
+	rec = httptest.NewRecorder()
+	handleWatchlistBalances(db)(rec, httptest.NewRequest(http.MethodPost, "/watchlist/balances", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST期望状态码405，实际%d", rec.Code)
+	}
+}
+
+// TestHolderStats 按mint统计持有者数和余额合计，可按mint过滤，没有记录时返回空数组
+func TestHolderStats(t *testing.T) {
+	var gotQuery string
+	var gotArgs []driver.NamedValue
+	var values [][]driver.Value
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		gotQuery, gotArgs = query, args
+		return &fakeRows{columns: []string{"mint", "holders", "total", "decimals"}, values: values}, nil
+	})
+	handler := handleHolderStats(db, nil)
+
+	var resp struct {
+		Data  []HolderStats `json:"data"`
+		Total int           `json:"total"`
+	}
+	values = [][]driver.Value{
+		{"mintA", int64(2), "1500000123", int64(9)},
+		{"mintB", int64(1), "42", int64(0)},
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/holders/stats", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if rec.Code != http.StatusOK || resp.Total != 2 || len(gotArgs) != 0 || !strings.Contains(gotQuery, "WHERE amount > 0 GROUP BY mint") {
+		t.Fatalf("全部mint统计错误(%d): %s, 查询: %s %v", rec.Code, rec.Body.String(), gotQuery, gotArgs)
+	}
+	if resp.Data[0] != (HolderStats{Mint: "mintA", Holders: 2, TotalSupplyHeld: "1.500000123"}) ||
+		resp.Data[1] != (HolderStats{Mint: "mintB", Holders: 1, TotalSupplyHeld: "42"}) {
+		t.Errorf("统计结果错误: %+v", resp.Data)
+	}
+
+	// 指定的mint没有记录时返回空数组
+	values = nil
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/holders/stats?mint=mintC", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"data":[]`) {
+		t.Errorf("没有记录时期望200和空数组，实际%d: %s", rec.Code, rec.Body.String())
+	}
+	if len(gotArgs) != 1 || gotArgs[0].Value != "mintC" || !strings.Contains(gotQuery, "AND mint = ?") {
+		t.Errorf("mint过滤错误: %s %v", gotQuery, gotArgs)
+	}
+
+	// 以下部分需要已初始化的数据库，通过 SPLHOLDER_TEST_DSN 指定，未设置时跳过
+	dsn := os.Getenv("SPLHOLDER_TEST_DSN")
+	if dsn == "" {
+		t.Skip("未设置 SPLHOLDER_TEST_DSN，跳过数据库统计检查")
+	}
+	mysqlDB, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	defer mysqlDB.Close()
+
+	mint := fmt.Sprintf("test_stats_%d", time.Now().UnixNano())
+	defer mysqlDB.Exec("DELETE FROM holder WHERE mint = ?", mint)
+	for i, amount := range []string{"1500000", "2000001", "0"} {
+		_, err := mysqlDB.Exec(`INSERT INTO holder (mint, pubkey, lamports, is_native, owner, state, decimals, amount, ui_amount, ui_amount_string)
+			VALUES (?, ?, 2039280, 0, ?, 'initialized', 6, ?, 0, '0')`, mint, fmt.Sprintf("pubkey%d", i), fmt.Sprintf("owner%d", i), amount)
+		if err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+	got, err := listHolderStats(mysqlDB, mint, nil)
+	if err != nil {
+		t.Fatalf("统计失败: %v", err)
+	}
+	if len(got) != 1 || got[0] != (HolderStats{Mint: mint, Holders: 2, TotalSupplyHeld: "3.500001"}) {
+		t.Errorf("期望余额大于0的2个持有者、合计3.500001，实际 %+v", got)
+	}
+	if got, err := listHolderStats(mysqlDB, mint+"_none", nil); err != nil || len(got) != 0 {
+		t.Errorf("没有记录的mint期望空结果，实际 %+v, 错误: %v", got, err)
+	}
+}
+
+// TestSnapshotMerkle 叶子按pubkey字节序排列，证明可以按文档中的规则独立验证到根，并与保存的快照根比较
+func TestSnapshotMerkle(t *testing.T) {
+	// 5个叶子：最后一层出现奇数节点，覆盖原样提升的情况
+	var pubkeys []string
+	for i := byte(1); i <= 5; i++ {
+		key := make([]byte, 32)
+		key[0] = 0x10 * i
+		pubkeys = append(pubkeys, base58Encode(key))
+	}
+	amounts := []string{"100", "200", "300", "400", "500"}
+
+	var holderRows, snapshotRows [][]driver.Value
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT pubkey, amount FROM holder"):
+			return &fakeRows{columns: []string{"pubkey", "amount"}, values: holderRows}, nil
+		case strings.Contains(query, "FROM snapshot_root"):
+			return &fakeRows{columns: []string{"root", "leaf_count", "slot", "created_at"}, values: snapshotRows}, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+	handler := handleSPLHolders(db, 0, nil, nil, nil)
+
+	// 数据库按倒序返回，结果应与顺序无关
+	for i := len(pubkeys) - 1; i >= 0; i-- {
+		holderRows = append(holderRows, []driver.Value{pubkeys[i], amounts[i]})
+	}
+	leaves, err := loadSnapshotLeaves(context.Background(), db, "mint1")
+	if err != nil {
+		t.Fatalf("读取叶子失败: %v", err)
+	}
+	for i, leaf := range leaves {
+		if leaf.pubkey != pubkeys[i] {
+			t.Fatalf("叶子未按pubkey字节序排列: %d = %s", i, leaf.pubkey)
+		}
+	}
+
+	// 按文档中的规则独立计算根
+	hash := func(parts ...[]byte) []byte {
+		sum := sha256.Sum256(bytes.Join(parts, nil))
+		return sum[:]
+	}
+	var level [][]byte
+	for i, pubkey := range pubkeys {
+		key, _ := base58Decode(pubkey)
+		amount, _ := strconv.ParseUint(amounts[i], 10, 64)
+		level = append(level, hash([]byte{0x00}, key, binary.BigEndian.AppendUint64(nil, amount)))
+	}
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, hash([]byte{0x01}, level[i], level[i+1]))
+			}
+		}
+		level = next
+	}
+	wantRoot := hex.EncodeToString(level[0])
+
+	snapshotRows = [][]driver.Value{{wantRoot, int64(5), int64(312345678), time.Now()}}
+	for _, pubkey := range pubkeys {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/spls/mint1/snapshot-proof?pubkey="+pubkey, nil))
+		var resp struct {
+			Data SnapshotProof `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("生成证明失败(%d): %s", rec.Code, rec.Body.String())
+		}
+		proof := resp.Data
+		if proof.Root != wantRoot || !proof.MatchesSnapshot || proof.Snapshot == nil || *proof.Snapshot.Slot != 312345678 {
+			t.Errorf("%s: 根或快照比较错误: %+v", pubkey, proof)
+		}
+		node, _ := hex.DecodeString(proof.Leaf)
+		for _, step := range proof.Proof {
+			sibling, _ := hex.DecodeString(step.Hash)
+			if step.Position == "left" {
+				node = hash([]byte{0x01}, sibling, node)
+			} else {
+				node = hash([]byte{0x01}, node, sibling)
+			}
+		}
+		if hex.EncodeToString(node) != wantRoot {
+			t.Errorf("%s: 证明无法验证到根", pubkey)
+		}
+	}
+
+	// 余额变化后证明对应当前余额，与保存的快照根不一致
+	holderRows[0][1] = "501"
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/spls/mint1/snapshot-proof?pubkey="+pubkeys[0], nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"matches_snapshot":false`) {
+		t.Errorf("余额变化后期望 matches_snapshot=false，实际%d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/spls/mint1/snapshot-root", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), wantRoot) || !strings.Contains(rec.Body.String(), `"slot":312345678`) {
+		t.Errorf("查询快照根错误(%d): %s", rec.Code, rec.Body.String())
+	}
+
+	// 错误情况
+	unknown := base58Encode(make([]byte, 32))
+	for _, tc := range []struct {
+		url  string
+		code int
+	}{
+		{"/spls/mint1/snapshot-proof?pubkey=invalid", http.StatusBadRequest},
+		{"/spls/mint1/snapshot-proof?pubkey=" + unknown, http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		if rec.Code != tc.code {
+			t.Errorf("%s: 期望%d，实际%d: %s", tc.url, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+	snapshotRows = nil
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/spls/mint1/snapshot-root", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("没有快照根时期望404，实际%d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 只有一个叶子时根就是叶子哈希
+	levels := merkleLevels([][]byte{level[0]})
+	if len(levels) != 1 || len(merkleProof(levels, 0)) != 0 {
+		t.Errorf("单个叶子时期望没有兄弟节点: %v", levels)
+	}
+}
+
+// TestHolderOverlap 统计两个mint的共同持有者和各自独有的持有者，include_owners=true 时分页返回共同持有者
+func TestHolderOverlap(t *testing.T) {
+	var ownerQueries int
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case strings.Contains(query, "MAX(mint = ?) AS has_a"):
+			if len(args) != 4 || args[0].Value != "mintA" || args[1].Value != "mintB" {
+				return nil, fmt.Errorf("统计参数错误: %v", args)
+			}
+			return &fakeRows{columns: []string{"both", "only_a", "only_b"}, values: [][]driver.Value{{int64(2), int64(3), int64(5)}}}, nil
+		case strings.Contains(query, "HAVING COUNT(DISTINCT mint) = 2"):
+			ownerQueries++
+			if len(args) != 4 || args[2].Value != int64(1) || args[3].Value != int64(1) {
+				return nil, fmt.Errorf("分页参数错误: %v", args)
+			}
+			return &fakeRows{columns: []string{"owner"}, values: [][]driver.Value{{"owner2"}}}, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+	handler := handleHolderOverlap(db, 0)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/holders/overlap?mint_a=mintA&mint_b=mintB", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望状态码200，实际%d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data  HolderOverlap `json:"data"`
+		Total int           `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if resp.Data.Both != 2 || resp.Data.OnlyA != 3 || resp.Data.OnlyB != 5 || resp.Data.Owners != nil || ownerQueries != 0 {
+		t.Errorf("统计结果错误: %+v, owner查询%d次", resp.Data, ownerQueries)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/holders/overlap?mint_a=mintA&mint_b=mintB&include_owners=true&page=2&limit=1", nil))
+	resp.Data = HolderOverlap{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if rec.Code != http.StatusOK || resp.Total != 2 || len(resp.Data.Owners) != 1 || resp.Data.Owners[0] != "owner2" {
+		t.Errorf("期望第2页返回owner2、总数2，实际%d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, target := range []string{"/holders/overlap?mint_a=mintA", "/holders/overlap?mint_a=mintA&mint_b=mintA"} {
+		rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s 期望状态码400，实际%d", target, rec.Code)
+		}
+	}
+}
+
+// TestRetryReadOnConnError 查询遇到连接级错误时重试一次，其他错误不重试
+func TestRetryReadOnConnError(t *testing.T) {
+	var calls int
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		calls++
+		if calls == 1 {
+			return nil, mysql.ErrInvalidConn
+		}
+		return &fakeRows{columns: []string{"mint", "holders", "in_spl"}, values: [][]driver.Value{{"mintA", int64(3), true}}}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	handleHolderMints(db)(rec, httptest.NewRequest(http.MethodGet, "/holders/mints", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望重试后状态码200，实际%d: %s", rec.Code, rec.Body.String())
+	}
+	if calls != 2 {
+		t.Errorf("期望查询2次，实际%d次", calls)
+	}
+
+	calls = 0
+	err := retryRead(context.Background(), func() error {
+		calls++
+		return errors.New("语法错误")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("非连接错误不应重试: err=%v, 调用%d次", err, calls)
+	}
+}
+
+// TestSPLExport GET /spls/export 导出全部mint及视图中存在的可选配置列，缺少的列导出为null
+func TestSPLExport(t *testing.T) {
+	var selectQuery string
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		if strings.Contains(query, "information_schema.COLUMNS") {
+			var count int64
+			if col := args[1].Value; col == "program_id" || col == "rpc_filters" {
+				count = 1
+			}
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{count}}}, nil
+		}
+		selectQuery = query
+		return &fakeRows{columns: []string{"symbol", "mint", "program_id", "rpc_filters", "keep_top_n", "holder_drop_alert_pct"}, values: [][]driver.Value{
+			{"AMZNx", "mintA", token2022ProgramID, `[{"dataSize":165}]`, nil, nil},
+			{"USDC", "mintB", nil, "not json", nil, nil},
+		}}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	handleSPLExport(db)(rec, httptest.NewRequest(http.MethodGet, "/spls/export", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望状态码200，实际%d: %s", rec.Code, rec.Body.String())
+	}
+	if selectQuery != "SELECT symbol, mint, program_id, rpc_filters, NULL, NULL FROM spl ORDER BY mint" {
+		t.Errorf("视图中缺少的列应查询为NULL，实际: %s", selectQuery)
+	}
+
+	var resp struct {
+		Data struct {
+			Version int               `json:"version"`
+			SPLs    []json.RawMessage `json:"spls"`
+		} `json:"data"`
+		Total int `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if resp.Data.Version != SPLExportVersion || resp.Total != 2 || len(resp.Data.SPLs) != 2 {
+		t.Fatalf("期望版本%d、2条记录，实际: %s", SPLExportVersion, rec.Body.String())
+	}
+	want := []string{
+		`{"symbol":"AMZNx","mint":"mintA","program_id":"` + token2022ProgramID + `","rpc_filters":[{"dataSize":165}],"keep_top_n":null,"holder_drop_alert_pct":null}`,
+		`{"symbol":"USDC","mint":"mintB","program_id":null,"rpc_filters":"not json","keep_top_n":null,"holder_drop_alert_pct":null}`,
+	}
+	for i, entry := range resp.Data.SPLs {
+		if string(entry) != want[i] {
+			t.Errorf("第%d条记录\n期望 %s\n实际 %s", i+1, want[i], entry)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	handleSPLExport(db)(rec, httptest.NewRequest(http.MethodPost, "/spls/export", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST 期望状态码405，实际%d", rec.Code)
+	}
+}
+
+// TestSPLTokenIncludeStatus GET /spls/{mint} 默认只返回Token信息，include_status=true 时附带最近的采集情况
+func TestSPLTokenIncludeStatus(t *testing.T) {
+	finishedAt := time.Date(2025, 1, 1, 0, 5, 0, 0, time.UTC)
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT symbol, mint FROM spl"):
+			if args[0].Value != "mint1" {
+				return &fakeRows{columns: []string{"symbol", "mint"}}, nil
+			}
+			return &fakeRows{columns: []string{"symbol", "mint"}, values: [][]driver.Value{{"TSLAx", "mint1"}}}, nil
+		case strings.Contains(query, "LEFT JOIN collection_status"):
+			// 最近一次采集失败，最近一次成功的记录仍然返回
+			return &fakeRows{columns: []string{"symbol", "mint", "finished_at", "holder_count", "status", "error_message"}, values: [][]driver.Value{
+				{"TSLAx", "mint1", finishedAt, int64(42), collectionStatusFailed, "RPC超时"},
+			}}, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+	handler := handleSPLHolders(db, 0, newCollectionRegistry(), nil, nil)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/spls/mint1", nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"status"`) {
+		t.Fatalf("默认不应包含status，实际%d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/spls/mint1?include_status=true", nil))
+	var resp struct {
+		Data SPLToken `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	st := resp.Data.Status
+	if st == nil || st.LastHolderCount == nil || *st.LastHolderCount != 42 || st.LastCollectedAt == nil || !st.LastCollectedAt.Equal(finishedAt) || st.LastError != "RPC超时" {
+		t.Errorf("采集情况错误: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/spls/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("不存在的mint期望404，实际%d", rec.Code)
+	}
+}
+
+// TestSPLSummary 汇总spl视图、holder表和最近一次成功采集的时间，余额合计按精度换算
+func TestSPLSummary(t *testing.T) {
+	finishedAt := time.Date(2025, 1, 1, 0, 5, 0, 0, time.UTC)
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case strings.Contains(query, "LEFT JOIN collection_status"):
+			if args[1].Value != "mint1" {
+				return &fakeRows{columns: []string{"symbol", "mint", "finished_at", "holder_count", "status", "error_message"}}, nil
+			}
+			return &fakeRows{columns: []string{"symbol", "mint", "finished_at", "holder_count", "status", "error_message"}, values: [][]driver.Value{
+				{"TSLAx", "mint1", finishedAt, int64(3), collectionStatusSuccess, nil},
+			}}, nil
+		case strings.HasPrefix(query, "SELECT COUNT(*), COUNT(DISTINCT owner)"):
+			return &fakeRows{columns: []string{"holders", "owners", "total", "decimals"}, values: [][]driver.Value{
+				{int64(3), int64(2), "123456789012345678901", int64(8)},
+			}}, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+	handler := handleSPLHolders(db, 0, newCollectionRegistry(), nil, nil)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/spls/mint1/summary", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望状态码200，实际%d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data SPLSummary `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	got := resp.Data
+	if got.Symbol != "TSLAx" || got.Decimals == nil || *got.Decimals != 8 || got.HolderCount != 3 || got.DistinctOwners != 2 {
+		t.Errorf("概览错误: %s", rec.Body.String())
+	}
+	if got.TotalAmount != "123456789012345678901" || got.TotalUIAmount != "1234567890123.45678901" {
+		t.Errorf("余额合计错误: %s / %s", got.TotalAmount, got.TotalUIAmount)
+	}
+	if got.LastCollectedAt == nil || !got.LastCollectedAt.Equal(finishedAt) {
+		t.Errorf("最近采集时间错误: %v", got.LastCollectedAt)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/spls/unknown/summary", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("不存在的mint期望404，实际%d", rec.Code)
+	}
+}
+
+// TestParseHolderSort sort 支持逗号分隔的多个排序字段，每个字段独立指定方向并校验白名单
+func TestParseHolderSort(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr string
+	}{
+		{"", "", ""},
+		{"-ui_amount", "h.ui_amount DESC", ""},
+		{"owner,-amount", "h.owner ASC, h.amount DESC", ""},
+		{"-owner, amount", "h.owner DESC, h.amount ASC", ""},
+		{"owner,-ui_amount,pubkey", "h.owner ASC, h.ui_amount DESC, h.pubkey ASC", ""},
+		{"owner,amount,pubkey,id", "", "最多支持"},
+		{"owner,-owner", "", "重复"},
+		{"ui_amount; DROP TABLE holder", "", "无效的排序字段"},
+		{"owner,", "", "无效的排序字段"},
+	}
+	for _, tt := range tests {
+		got, err := parseHolderSort(tt.raw)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("sort=%q: 期望错误包含 %q，实际: %v", tt.raw, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("sort=%q: 期望 %q，实际 %q, 错误: %v", tt.raw, tt.want, got, err)
+		}
+	}
+
+	// 两个排序字段按顺序组合进查询
+	var selectQuery string
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(0)}}}, nil
+		}
+		selectQuery = query
+		return &fakeRows{columns: holderColumns}, nil
+	})
+	handler := apiHandlerMariaDB(db, 0, newCollectionRegistry(), nil, nil)
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/holders?sort=owner,-amount", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(selectQuery, " ORDER BY h.owner ASC, h.amount DESC LIMIT ") {
+		t.Errorf("期望按owner升序、amount降序排序，实际%d: %s", rec.Code, selectQuery)
+	}
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/holders?sort=nonexistent", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("无效的排序字段期望400，实际%d", rec.Code)
+	}
+}
+
+// TestHoldersCountCache 翻页时复用按过滤条件缓存的总数，不同过滤条件分别计数，该mint采集结束后重新计数
+func TestHoldersCountCache(t *testing.T) {
+	var counts []string
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			counts = append(counts, fmt.Sprint(args))
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(25)}}}, nil
+		}
+		return &fakeRows{columns: holderColumns}, nil
+	})
+	registry := newCollectionRegistry()
+	handler := apiHandlerMariaDB(db, 0, registry, nil, nil)
+	get := func(path string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"total":25`) {
+			t.Fatalf("%s: 期望200且total为25，实际%d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	get("/holders?mint=mint1&page=1&limit=10")
+	get("/holders?mint=mint1&page=2&limit=10&sort=-amount")
+	get("/holders?mint=mint1&state=frozen&page=1")
+	get("/holders?page=3")
+	if len(counts) != 3 {
+		t.Fatalf("同一过滤条件翻页应复用总数，期望计数3次，实际 %v", counts)
+	}
+
+	// mint2 的采集结束后只有不限mint的总数失效
+	_, done := registry.Start(context.Background(), "mint2")
+	done()
+	get("/holders?mint=mint1&page=3")
+	get("/holders?page=1")
+	if len(counts) != 4 {
+		t.Fatalf("mint2采集后应只重新计算不限mint的总数，实际 %v", counts)
+	}
+
+	_, done = registry.Start(context.Background(), "mint1")
+	done()
+	get("/holders?mint=mint1&page=1")
+	if len(counts) != 5 {
+		t.Errorf("mint1采集后应重新计算mint1的总数，实际 %v", counts)
+	}
+}
+
+// TestHoldersCountCacheWriteInvalidation 地址标签修改等采集以外的写操作成功后缓存的总数立即失效，写入失败时保留
+func TestHoldersCountCacheWriteInvalidation(t *testing.T) {
+	var counts int
+	db := openFakeDBWithExec(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			counts++
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(25)}}}, nil
+		}
+		return &fakeRows{columns: holderColumns}, nil
+	}, func(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+		if args[0].Value == "known" {
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	})
+	registry := newCollectionRegistry()
+	holders := apiHandlerMariaDB(db, 0, registry, nil, []string{"exchange"})
+	labels := handleAddressLabel(newStorage(db, nil), registry)
+	serve := func(handler http.HandlerFunc, method, path string, wantCode int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(method, path, nil))
+		if rec.Code != wantCode {
+			t.Fatalf("%s %s: 期望状态码%d，实际%d: %s", method, path, wantCode, rec.Code, rec.Body.String())
+		}
+	}
+
+	serve(holders, http.MethodGet, "/holders?mint=mint1&exclude_known=true&count_only=true", http.StatusOK)
+	serve(labels, http.MethodDelete, "/labels/unknown", http.StatusNotFound)
+	serve(holders, http.MethodHead, "/holders?mint=mint1&exclude_known=true", http.StatusOK)
+	if counts != 1 {
+		t.Fatalf("删除标签失败时应复用缓存的总数，实际计数 %d 次", counts)
+	}
+
+	serve(labels, http.MethodDelete, "/labels/known", http.StatusOK)
+	serve(holders, http.MethodGet, "/holders?mint=mint1&exclude_known=true&count_only=true", http.StatusOK)
+	if counts != 2 {
+		t.Errorf("删除标签后应重新计算总数，实际计数 %d 次", counts)
+	}
+}
+
+// TestHoldersSortInjection sort 中不在白名单内的表达式返回400，不会拼接进查询
+func TestHoldersSortInjection(t *testing.T) {
+	var queries []string
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		queries = append(queries, query)
+		return nil, fmt.Errorf("不应执行查询: %s", query)
+	})
+	handler := apiHandlerMariaDB(db, 0, newCollectionRegistry(), nil, nil)
+	for _, sort := range []string{
+		"(select sleep(5))",
+		"-(select 1 from dual)",
+		"ui_amount desc, (select 1)",
+		"ui_amount/**/DESC",
+		"h.ui_amount",
+		"IF(1=1,ui_amount,pubkey)",
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/holders?sort="+url.QueryEscape(sort), nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("sort=%q 期望400，实际%d", sort, rec.Code)
+		}
+	}
+	if len(queries) != 0 {
+		t.Errorf("无效的sort不应访问数据库，实际执行了: %v", queries)
+	}
+}
+
+// TestParsePagination 列表接口统一的分页参数解析
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPage  int
+		wantLimit int
+		wantErr   bool
+	}{
+		{"", 1, 10, false},
+		{"page=3&limit=50", 3, 50, false},
+		{"page=0&limit=0", 1, 10, false},
+		{"page=-1&limit=-5", 1, 10, false},
+		{"limit=100000", 1, 1000, false},
+		{"page=abc", 0, 0, true},
+		{"limit=1e3", 0, 0, true},
+		{"page=9223372036854775807&limit=1000", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			page, limit, err := parsePagination(httptest.NewRequest(http.MethodGet, "/holders?"+tt.query, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误 = %v, 期望出错 = %v", err, tt.wantErr)
+			}
+			if page != tt.wantPage || limit != tt.wantLimit {
+				t.Errorf("page=%d limit=%d, 期望 page=%d limit=%d", page, limit, tt.wantPage, tt.wantLimit)
+			}
+		})
+	}
+}
+
+// TestReadOnlyMode 只读维护模式下写操作返回503、查询照常，采集被跳过
+func TestReadOnlyMode(t *testing.T) {
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(1)}}}, nil
+		}
+		if strings.Contains(query, "FROM spl") {
+			t.Errorf("只读模式下不应开始采集: %s", query)
+		}
+		return &fakeRows{columns: holderColumns, values: [][]driver.Value{holderRow(0)}}, nil
+	})
+	config := &Config{IntervalTime: 300, ReadOnly: true}
+	s := NewServerWithDB(config, db, nil)
+	handler := s.Handler()
+
+	const updatePath = "/holders/Xs3eBt7uRfJX8QUs4suhyU8p2M6DoUDrJyWBa8LLZsg/not-an-address"
+	steps := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{"查询照常", http.MethodGet, "/holders?mint=mint1", http.StatusOK},
+		{"写操作被拒绝", http.MethodPut, updatePath, http.StatusServiceUnavailable},
+		{"健康检查显示只读", http.MethodGet, "/health", http.StatusOK},
+		{"退出只读模式", http.MethodPost, "/admin/read-only?enabled=false", http.StatusOK},
+		{"恢复写操作", http.MethodPut, updatePath, http.StatusBadRequest},
+		{"无效的enabled", http.MethodPost, "/admin/read-only?enabled=maybe", http.StatusBadRequest},
+	}
+	for _, step := range steps {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(step.method, step.target, nil))
+		if rec.Code != step.wantStatus {
+			t.Errorf("%s: 期望状态码 %d, 实际 %d: %s", step.name, step.wantStatus, rec.Code, rec.Body.String())
+		}
+		if step.target == "/health" && !strings.Contains(rec.Body.String(), `"read_only":true`) {
+			t.Errorf("%s: 响应中缺少read_only，实际: %s", step.name, rec.Body.String())
+		}
+	}
+
+	if err := worker(context.Background(), config, db, newCollectionRegistry(), &RPCHealthMonitor{}, NewReadOnlyMode(true), nil, nil); err != nil {
+		t.Errorf("只读模式下跳过采集不应返回错误: %v", err)
+	}
+}
+
+// TestParseTokenAccountDelegate 解析原始账户数据中的 delegate 和 delegated_amount
+func TestParseTokenAccountDelegate(t *testing.T) {
+	data := make([]byte, tokenAccountSize)
+	binary.LittleEndian.PutUint64(data[64:72], 5000000)
+	data[tokenAccountStateOffset] = 1
+
+	info, err := parseTokenAccount(data)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if info.Delegate != "" || info.DelegatedAmount != nil || info.delegatedRawAmount().String() != "0" {
+		t.Errorf("未授权的账户不应有授权信息: %+v", info)
+	}
+
+	delegate := bytes.Repeat([]byte{7}, 32)
+	binary.LittleEndian.PutUint32(data[tokenAccountDelegateTag:], 1)
+	copy(data[tokenAccountDelegateTag+4:], delegate)
+	binary.LittleEndian.PutUint64(data[tokenAccountDelegatedAmount:], 1500000)
+
+	info, err = parseTokenAccount(data)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	applyDecimals(&info, 6)
+	if info.Delegate != base58Encode(delegate) {
+		t.Errorf("delegate = %s, 期望 %s", info.Delegate, base58Encode(delegate))
+	}
+	if info.DelegatedAmount == nil || info.DelegatedAmount.UIAmountString != "1.5" || info.delegatedRawAmount().String() != "1500000" {
+		t.Errorf("授权额度解析错误: %+v", info.DelegatedAmount)
+	}
+
+	// jsonParsed 格式中的授权信息
+	var parsed Info
+	if err := json.Unmarshal([]byte(`{"delegate":"owner1","delegatedAmount":{"amount":"42","decimals":0,"uiAmount":42,"uiAmountString":"42"}}`), &parsed); err != nil {
+		t.Fatalf("解析jsonParsed失败: %v", err)
+	}
+	if parsed.Delegate != "owner1" || parsed.delegatedRawAmount().String() != "42" {
+		t.Errorf("jsonParsed授权信息错误: %+v", parsed)
+	}
+}
+
+// TestTokenAccountExtensions 解析 closeAuthority 和 Token-2022 扩展
+func TestTokenAccountExtensions(t *testing.T) {
+	data := make([]byte, tokenAccountSize)
+	data[tokenAccountStateOffset] = 1
+	closeAuthority := bytes.Repeat([]byte{9}, 32)
+	binary.LittleEndian.PutUint32(data[tokenAccountCloseAuthority:], 1)
+	copy(data[tokenAccountCloseAuthority+4:], closeAuthority)
+
+	info, err := parseTokenAccount(data)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if info.CloseAuthority != base58Encode(closeAuthority) {
+		t.Errorf("closeAuthority = %s, 期望 %s", info.CloseAuthority, base58Encode(closeAuthority))
+	}
+	if info.extensionsValue().Valid {
+		t.Error("base64编码的账户不应有扩展")
+	}
+
+	var parsed Info
+	raw := `{"closeAuthority":"owner1","extensions":[{"extension":"immutableOwner"},{"extension":"transferFeeAmount","state":{"withheldAmount":0}}]}`
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		t.Fatalf("解析jsonParsed失败: %v", err)
+	}
+	ext := parsed.extensionsValue()
+	if parsed.CloseAuthority != "owner1" || !ext.Valid || !strings.Contains(ext.String, "transferFeeAmount") {
+		t.Errorf("扩展解析错误: closeAuthority=%s, extensions=%v", parsed.CloseAuthority, ext)
+	}
+
+	if err := json.Unmarshal([]byte(`{"extensions":[]}`), &parsed); err != nil {
+		t.Fatalf("解析jsonParsed失败: %v", err)
+	}
+	if parsed.extensionsValue().Valid {
+		t.Error("空扩展列表应存储为NULL")
+	}
+}
+
+// TestNativeBalance getMultipleAccounts 只请求lamports，不存在的账户按0处理；查询接口换算SOL并在没有记录时返回404
+func TestNativeBalance(t *testing.T) {
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "getMultipleAccounts" || len(req.Params) != 2 {
+			t.Errorf("请求无效: %+v, %v", req, err)
+		}
+		if !strings.Contains(string(req.Params[1]), `"dataSlice":{"length":0,"offset":0}`) {
+			t.Errorf("应通过dataSlice省略账户数据，实际参数: %s", req.Params[1])
+		}
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":"1","result":{"context":{"slot":42},"value":[{"lamports":1500000000,"data":["","base64"]},null]}}`)
+	}))
+	defer rpc.Close()
+
+	client := NewSolanaRPCClient(rpc.URL, rpc.Client())
+	lamports, slot, err := client.GetMultipleAccountLamports(context.Background(), []string{"owner1", "owner2"})
+	if err != nil {
+		t.Fatalf("查询SOL余额失败: %v", err)
+	}
+	if slot != 42 || len(lamports) != 2 || lamports[0] != 1500000000 || lamports[1] != 0 {
+		t.Errorf("期望 [1500000000 0] @42，实际 %v @%d", lamports, slot)
+	}
+	if _, _, err := client.GetMultipleAccountLamports(context.Background(), []string{"owner1"}); err == nil {
+		t.Error("返回的账户数与请求不一致时应报错")
+	}
+
+	const owner = "6VmnVgDuNVRJBVbuW9gbo9jtpMQnmTYBqnmT1LTtKmEL"
+	updatedAt := time.Date(2025, 1, 1, 0, 5, 0, 0, time.UTC)
+	db := openFakeDB(t, func(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+		rows := &fakeRows{columns: []string{"lamports", "slot", "updated_at"}}
+		if len(args) == 1 && args[0].Value == owner {
+			rows.values = [][]driver.Value{{int64(1500000000), int64(42), updatedAt}}
+		}
+		return rows, nil
+	})
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/owners/" + owner + "/native-balance", http.StatusOK, `"sol":"1.5"`},
+		{"/owners/11111111111111111111111111111111/native-balance", http.StatusNotFound, "不存在"},
+		{"/owners/invalid!/native-balance", http.StatusBadRequest, "owner"},
+		{"/owners/" + owner + "/other", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleNativeBalance(db)(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("%s: 期望 %d 且包含 %q，实际 %d %s", tt.path, tt.wantCode, tt.wantBody, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// recordingPublisher 记录发布的采集事件
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []CollectionEvent
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event CollectionEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+// TestCollectionPublisher 校验broker配置；NATS发布器携带URL中的认证信息连接并发布事件；worker只为采集成功的mint发布事件
+func TestCollectionPublisher(t *testing.T) {
+	for _, tt := range []struct {
+		url, topic string
+		wantErr    bool
+	}{
+		{"", "", false},
+		{"nats://localhost", "splholder.collection", false},
+		{"kafka://localhost:9092", "splholder.collection", true},
+		{"nats://", "splholder.collection", true},
+		{"nats://localhost:4222", "bad topic", true},
+	} {
+		if _, err := NewCollectionPublisher(tt.url, tt.topic); (err != nil) != tt.wantErr {
+			t.Errorf("NewCollectionPublisher(%q, %q) 错误 = %v, 期望出错 = %v", tt.url, tt.topic, err, tt.wantErr)
+		}
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n")
+		reader := bufio.NewReader(conn)
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			if line == "PING" {
+				io.WriteString(conn, "PONG\r\n")
+				continue
+			}
+			lines = append(lines, line)
+			if len(lines) == 3 { // CONNECT、PUB、payload
+				received <- lines
+			}
+		}
+	}()
+
+	publisher, err := NewCollectionPublisher("nats://user:secret@"+ln.Addr().String(), "splholder.collection")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+	slot := uint64(42)
+	if err := publisher.Publish(context.Background(), CollectionEvent{Type: CollectionEventMintUpdated, Mint: "mint1", Slot: &slot}); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	lines := <-received
+	if !strings.HasPrefix(lines[0], "CONNECT ") || !strings.Contains(lines[0], `"user":"user"`) || !strings.Contains(lines[0], `"pass":"secret"`) {
+		t.Errorf("CONNECT应携带URL中的用户名和密码，实际: %s", lines[0])
+	}
+	if lines[1] != fmt.Sprintf("PUB splholder.collection %d", len(lines[2])) {
+		t.Errorf("期望 PUB 主题 长度，实际: %q", lines[1:])
+	}
+	var event CollectionEvent
+	if err := json.Unmarshal([]byte(lines[2]), &event); err != nil || event.Mint != "mint1" || event.Slot == nil || *event.Slot != 42 {
+		t.Errorf("事件内容不正确: %s, %v", lines[2], err)
+	}
+
+	// worker: mint1 采集成功，mint2 RPC失败，只发布mint1的事件
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "mint2") {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":[%s]}`, rpcAccount("holder1", "100"))
+	}))
+	defer rpc.Close()
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case query == "SELECT mint FROM spl":
+			return &fakeRows{columns: []string{"mint"}, values: [][]driver.Value{{"mint1"}, {"mint2"}}}, nil
+		case strings.Contains(query, "information_schema.COLUMNS"):
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(0)}}}, nil
+		case strings.HasPrefix(query, "SELECT id, amount, decimals FROM holder"):
+			return &fakeRows{columns: []string{"id", "amount", "decimals"}}, nil
+		case query == "COMMIT" || query == "ROLLBACK":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+	recorder := &recordingPublisher{}
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: RPCEncodingJSONParsed, MaxFailureRatio: 1}
+	if err := worker(context.Background(), config, db, newCollectionRegistry(), &RPCHealthMonitor{}, nil, nil, recorder); err != nil {
+		t.Fatalf("worker() 错误: %v", err)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Mint != "mint1" || recorder.events[0].Type != CollectionEventMintUpdated || recorder.events[0].RunID == "" {
+		t.Errorf("期望只发布mint1的事件，实际 %+v", recorder.events)
+	}
+}
+
+// panicPublisher 发布指定mint的事件时panic，其他mint的事件正常记录
+type panicPublisher struct {
+	recordingPublisher
+	panicMint string
+}
+
+func (p *panicPublisher) Publish(ctx context.Context, event CollectionEvent) error {
+	if event.Mint == p.panicMint {
+		panic("测试panic")
+	}
+	return p.recordingPublisher.Publish(ctx, event)
+}
+
+// TestWorkerFetchConcurrency 并发采集时同时进行的RPC请求不超过 fetch_concurrency，全部mint都被处理，
+// 单个mint的panic不影响其他mint
+func TestWorkerFetchConcurrency(t *testing.T) {
+	const concurrency = 3
+	const mintCount = 8
+	var mu sync.Mutex
+	var inFlight, maxInFlight, commits int
+	requested := map[string]bool{}
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		for i := 1; i <= mintCount; i++ {
+			if mint := fmt.Sprintf("mint%d", i); strings.Contains(string(body), `"`+mint+`"`) {
+				requested[mint] = true
+			}
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":[%s]}`, rpcAccount("holder1", "100"))
+	}))
+	defer rpc.Close()
+
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case query == "SELECT mint FROM spl":
+			rows := &fakeRows{columns: []string{"mint"}}
+			for i := 1; i <= mintCount; i++ {
+				rows.values = append(rows.values, []driver.Value{fmt.Sprintf("mint%d", i)})
+			}
+			return rows, nil
+		case strings.Contains(query, "information_schema.COLUMNS"):
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(0)}}}, nil
+		case strings.HasPrefix(query, "SELECT id, amount, decimals FROM holder"):
+			return &fakeRows{columns: []string{"id", "amount", "decimals"}}, nil
+		case query == "COMMIT":
+			mu.Lock()
+			commits++
+			mu.Unlock()
+			return nil, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+
+	var logs bytes.Buffer
+	errorLog.SetOutput(&logs)
+	defer errorLog.SetOutput(os.Stderr)
+
+	publisher := &panicPublisher{panicMint: "mint5"}
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: RPCEncodingJSONParsed, MaxFailureRatio: 1, FetchConcurrency: concurrency}
+	if err := worker(context.Background(), config, db, newCollectionRegistry(), &RPCHealthMonitor{}, nil, nil, publisher); err != nil {
+		t.Fatalf("worker() 错误: %v", err)
+	}
+	if len(requested) != mintCount || commits != mintCount {
+		t.Errorf("期望%d个mint都发出RPC请求并各自提交事务，实际请求 %v、提交 %d 次", mintCount, requested, commits)
+	}
+	if maxInFlight > concurrency || maxInFlight < 2 {
+		t.Errorf("同时进行的RPC请求数应在 2..%d 之间，实际最大 %d", concurrency, maxInFlight)
+	}
+	if len(publisher.events) != mintCount-1 {
+		t.Errorf("panic的mint之外都应发布事件，实际 %d 个", len(publisher.events))
+	}
+	if !strings.Contains(logs.String(), "采集mint地址 mint5: panic: 测试panic") {
+		t.Errorf("期望记录mint5的panic，实际日志: %s", logs.String())
+	}
+}
+
+// TestWorkerConcurrentEventSlots 并发采集且共享最高slot时，每个mint的事件使用各自响应的slot
+func TestWorkerConcurrentEventSlots(t *testing.T) {
+	slots := map[string]uint64{"mint1": 100, "mint2": 90}
+	var arrived sync.WaitGroup
+	arrived.Add(len(slots))
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var mint string
+		for m := range slots {
+			if bytes.Contains(req.Params[1], []byte(`"`+m+`"`)) {
+				mint = m
+			}
+		}
+		var params struct {
+			MinContextSlot uint64 `json:"minContextSlot"`
+		}
+		json.Unmarshal(req.Params[1], &params)
+
+		// 两个请求都以 minContextSlot 0 发出后再响应，mint2 在 mint1 记录slot之后返回较早的slot
+		arrived.Done()
+		arrived.Wait()
+		if mint == "mint2" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if params.MinContextSlot > slots[mint] {
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":"1","error":{"code":-32016,"message":"Minimum context slot has not been reached"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"1","result":{"context":{"slot":%d},"value":[%s]}}`, slots[mint], rpcAccount("holder1", "100"))
+	}))
+	defer rpc.Close()
+
+	db := openFakeDB(t, func(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+		switch {
+		case query == "SELECT mint FROM spl":
+			return &fakeRows{columns: []string{"mint"}, values: [][]driver.Value{{"mint1"}, {"mint2"}}}, nil
+		case strings.Contains(query, "information_schema.COLUMNS"):
+			return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{int64(0)}}}, nil
+		case strings.HasPrefix(query, "SELECT id, amount, decimals FROM holder"):
+			return &fakeRows{columns: []string{"id", "amount", "decimals"}}, nil
+		case query == "COMMIT" || query == "ROLLBACK":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("意外的查询: %s", query)
+	})
+
+	recorder := &recordingPublisher{}
+	monitor := &RPCHealthMonitor{}
+	config := &Config{RPCURL: rpc.URL, RPCEncoding: RPCEncodingJSONParsed, MaxFailureRatio: 1, FetchConcurrency: 2,
+		EnforceMinSlot: true, EventBrokerURL: "nats://127.0.0.1:4222"}
+	if err := worker(context.Background(), config, db, newCollectionRegistry(), monitor, nil, nil, recorder); err != nil {
+		t.Fatalf("worker() 错误: %v", err)
+	}
+	if len(recorder.events) != len(slots) {
+		t.Fatalf("期望 %d 个事件，实际 %d 个", len(slots), len(recorder.events))
+	}
+	for _, event := range recorder.events {
+		if event.Slot == nil {
+			t.Errorf("mint地址 %s 的事件缺少slot", event.Mint)
+		} else if *event.Slot != slots[event.Mint] {
+			t.Errorf("mint地址 %s 的事件slot = %d, 期望 %d", event.Mint, *event.Slot, slots[event.Mint])
+		}
+	}
+	if got := monitor.contextSlot.Load(); got != 100 {
+		t.Errorf("最高slot = %d, 期望 100", got)
+	}
+}
@@ -0,0 +1,425 @@
+package splholder
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WatchlistEntry 对应数据库中的 'watchlist' 表结构，address 可以是钱包地址(owner)或token账户地址(pubkey)
+type WatchlistEntry struct {
+	ID        int64     `json:"id"`
+	Address   string    `json:"address"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WatchlistRequest 添加/更新关注地址的请求结构
+type WatchlistRequest struct {
+	Address string `json:"address"`
+	Note    string `json:"note"`
+}
+
+// 验证关注地址请求（更新时地址来自URL路径，不校验请求体中的address）
+func (req *WatchlistRequest) Validate(requireAddress bool) error {
+	if requireAddress {
+		if req.Address == "" {
+			return fmt.Errorf("address不能为空")
+		}
+		if err := validateSolanaAddress(req.Address); err != nil {
+			return err
+		}
+	}
+	if len(req.Note) > 255 {
+		return fmt.Errorf("note不能超过255个字符")
+	}
+	return nil
+}
+
+// WatchlistBalance 关注地址在某个被跟踪mint中的一个token账户余额
+type WatchlistBalance struct {
+	Address        string    `json:"address"`
+	Note           string    `json:"note"`
+	Mint           string    `json:"mint"`
+	Pubkey         string    `json:"pubkey"`
+	Owner          string    `json:"owner"`
+	Decimals       int       `json:"decimals"`
+	Amount         string    `json:"amount"`
+	UIAmount       float64   `json:"uiAmount"`
+	UIAmountString string    `json:"uiAmountString"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// 查询单个关注地址
+func getWatchlistEntry(db *sql.DB, address string) (*WatchlistEntry, error) {
+	var entry WatchlistEntry
+	var note sql.NullString
+	err := db.QueryRow(`
+		SELECT id, address, note, created_at, updated_at
+		FROM watchlist
+		WHERE address = ?
+	`, address).Scan(&entry.ID, &entry.Address, &note, &entry.CreatedAt, &entry.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("关注地址 %s 不存在", address)
+	}
+	if err != nil {
+		return nil, wrapError("查询关注地址", err)
+	}
+	entry.Note = note.String
+	return &entry, nil
+}
+
+// 分页查询关注地址
+func listWatchlist(db *sql.DB, limit, offset int) ([]WatchlistEntry, int, error) {
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM watchlist").Scan(&total); err != nil {
+		return nil, 0, wrapError("查询关注地址总数", err)
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT id, address, note, created_at, updated_at FROM watchlist ORDER BY id LIMIT %d OFFSET %d", limit, offset))
+	if err != nil {
+		return nil, 0, wrapError("查询关注地址", err)
+	}
+	defer rows.Close()
+
+	entries := []WatchlistEntry{}
+	for rows.Next() {
+		var e WatchlistEntry
+		var note sql.NullString
+		if err := rows.Scan(&e.ID, &e.Address, &note, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, 0, wrapError("扫描关注地址", err)
+		}
+		e.Note = note.String
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, wrapError("遍历关注地址", err)
+	}
+	return entries, total, nil
+}
+
+// 添加关注地址
+func createWatchlistEntry(db *sql.DB, req WatchlistRequest) (*WatchlistEntry, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM watchlist WHERE address = ?)", req.Address).Scan(&exists)
+	if err != nil {
+		return nil, wrapError("检查关注地址是否存在", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("关注地址 %s 已存在", req.Address)
+	}
+
+	_, err = db.Exec("INSERT INTO watchlist (address, note) VALUES (?, ?)", req.Address, req.Note)
+	if err != nil {
+		return nil, wrapError("添加关注地址", err)
+	}
+	return getWatchlistEntry(db, req.Address)
+}
+
+// 更新关注地址的备注
+func updateWatchlistEntry(db *sql.DB, address string, req WatchlistRequest) (*WatchlistEntry, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM watchlist WHERE address = ?)", address).Scan(&exists)
+	if err != nil {
+		return nil, wrapError("检查关注地址是否存在", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("关注地址 %s 不存在", address)
+	}
+
+	_, err = db.Exec("UPDATE watchlist SET note = ?, updated_at = CURRENT_TIMESTAMP WHERE address = ?", req.Note, address)
+	if err != nil {
+		return nil, wrapError("更新关注地址", err)
+	}
+	return getWatchlistEntry(db, address)
+}
+
+// 删除关注地址
+func deleteWatchlistEntry(db *sql.DB, address string) error {
+	result, err := db.Exec("DELETE FROM watchlist WHERE address = ?", address)
+	if err != nil {
+		return wrapError("删除关注地址", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return wrapError("获取删除行数", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("关注地址 %s 不存在", address)
+	}
+	return nil
+}
+
+// 查询全部关注地址在被跟踪mint中的当前余额。关注地址按钱包地址(owner)和token账户地址(pubkey)
+// 分别关联，两个分支各自使用 idx_owner_mint 和 idx_pubkey 索引，UNION 去掉同时匹配的重复行。
+// mintAddress 不为空时只返回该mint
+func listWatchlistBalances(db *sql.DB, mintAddress string) ([]WatchlistBalance, error) {
+	branch := `SELECT w.address, w.note, h.mint, h.pubkey, h.owner, h.decimals, h.amount, h.ui_amount, h.ui_amount_string, h.updated_at
+		FROM watchlist w JOIN holder h ON h.%s = w.address
+		WHERE h.mint IN (SELECT mint FROM spl)`
+	var args []interface{}
+	if mintAddress != "" {
+		branch += " AND h.mint = ?"
+		args = append(args, mintAddress, mintAddress)
+	}
+	query := fmt.Sprintf(branch, "owner") + " UNION " + fmt.Sprintf(branch, "pubkey") + " ORDER BY address, mint, pubkey"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, wrapError("查询关注地址余额", err)
+	}
+	defer rows.Close()
+
+	balances := []WatchlistBalance{}
+	for rows.Next() {
+		var b WatchlistBalance
+		var note sql.NullString
+		if err := rows.Scan(&b.Address, &note, &b.Mint, &b.Pubkey, &b.Owner, &b.Decimals, &b.Amount, &b.UIAmount, &b.UIAmountString, &b.UpdatedAt); err != nil {
+			return nil, wrapError("扫描关注地址余额", err)
+		}
+		b.Note = note.String
+		balances = append(balances, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError("遍历关注地址余额", err)
+	}
+	return balances, nil
+}
+
+// 处理 /watchlist 请求：GET 查询关注地址列表，POST 添加关注地址
+func handleWatchlist(store *Storage, maxOffset int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			page, limit, err := parsePagination(r)
+			if err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+			offset := (page - 1) * limit
+			if !checkMaxOffset(w, offset, maxOffset) {
+				return
+			}
+
+			var entries []WatchlistEntry
+			var total int
+			err = retryRead(r.Context(), func() (err error) {
+				entries, total, err = listWatchlist(store.Reader(), limit, offset)
+				return err
+			})
+			if err != nil {
+				logError("查询关注地址列表", err)
+				sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+					Success: false,
+					Error:   "查询数据失败",
+				})
+				return
+			}
+			sendJSONResponse(w, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    entries,
+				Total:   total,
+				Page:    page,
+				Limit:   limit,
+			})
+
+		case http.MethodPost:
+			var req WatchlistRequest
+			if !requireJSONContentType(w, r) {
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				logError("Failed to decode request body", err)
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "Invalid JSON format",
+				})
+				return
+			}
+			if err := req.Validate(true); err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+
+			entry, err := createWatchlistEntry(store.Writer(), req)
+			if err != nil {
+				logError("Failed to create watchlist entry", err)
+				if strings.Contains(err.Error(), "已存在") {
+					sendJSONResponse(w, http.StatusConflict, APIResponse{
+						Success: false,
+						Error:   err.Error(),
+					})
+				} else {
+					sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+						Success: false,
+						Error:   "Failed to create watchlist entry",
+					})
+				}
+				return
+			}
+			sendMutationResponse(w, r, http.StatusCreated, APIResponse{
+				Success: true,
+				Data:    entry,
+			})
+
+		default:
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+		}
+	}
+}
+
+// 处理 /watchlist/{address} 请求：GET 查询、PUT 更新备注、DELETE 删除
+func handleWatchlistEntry(store *Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := strings.Trim(strings.TrimPrefix(r.URL.Path, "/watchlist/"), "/")
+		if address == "" || strings.Contains(address, "/") {
+			sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "Invalid address",
+			})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			var entry *WatchlistEntry
+			err := retryRead(r.Context(), func() (err error) {
+				entry, err = getWatchlistEntry(store.Reader(), address)
+				return err
+			})
+			if err != nil {
+				if strings.Contains(err.Error(), "不存在") {
+					sendJSONResponse(w, http.StatusNotFound, APIResponse{
+						Success: false,
+						Error:   err.Error(),
+					})
+					return
+				}
+				logError("查询关注地址", err)
+				sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+					Success: false,
+					Error:   "查询数据失败",
+				})
+				return
+			}
+			sendJSONResponse(w, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    entry,
+			})
+
+		case http.MethodPut:
+			var req WatchlistRequest
+			if !requireJSONContentType(w, r) {
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				logError("Failed to decode request body", err)
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   "Invalid JSON format",
+				})
+				return
+			}
+			if err := req.Validate(false); err != nil {
+				sendJSONResponse(w, http.StatusBadRequest, APIResponse{
+					Success: false,
+					Error:   err.Error(),
+				})
+				return
+			}
+
+			entry, err := updateWatchlistEntry(store.Writer(), address, req)
+			if err != nil {
+				logError("Failed to update watchlist entry", err)
+				if strings.Contains(err.Error(), "不存在") {
+					sendJSONResponse(w, http.StatusNotFound, APIResponse{
+						Success: false,
+						Error:   err.Error(),
+					})
+				} else {
+					sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+						Success: false,
+						Error:   "Failed to update watchlist entry",
+					})
+				}
+				return
+			}
+			sendMutationResponse(w, r, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    entry,
+			})
+
+		case http.MethodDelete:
+			if err := deleteWatchlistEntry(store.Writer(), address); err != nil {
+				logError("Failed to delete watchlist entry", err)
+				if strings.Contains(err.Error(), "不存在") {
+					sendJSONResponse(w, http.StatusNotFound, APIResponse{
+						Success: false,
+						Error:   err.Error(),
+					})
+				} else {
+					sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+						Success: false,
+						Error:   "Failed to delete watchlist entry",
+					})
+				}
+				return
+			}
+			sendJSONResponse(w, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    map[string]string{"address": address},
+			})
+
+		default:
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "Method not allowed",
+			})
+		}
+	}
+}
+
+// 处理 GET /watchlist/balances 请求：返回全部关注地址在被跟踪mint中的余额，可按mint过滤
+func handleWatchlistBalances(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONResponse(w, http.StatusMethodNotAllowed, APIResponse{
+				Success: false,
+				Error:   "只支持GET方法",
+			})
+			return
+		}
+
+		var balances []WatchlistBalance
+		err := retryRead(r.Context(), func() (err error) {
+			balances, err = listWatchlistBalances(db, r.URL.Query().Get("mint"))
+			return err
+		})
+		if err != nil {
+			logError("查询关注地址余额", err)
+			sendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+				Success: false,
+				Error:   "查询数据失败",
+			})
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    balances,
+			Total:   len(balances),
+		})
+	}
+}
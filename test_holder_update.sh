@@ -50,13 +50,19 @@ curl -X PUT "$BASE_URL/holders/$MINT_ADDRESS/$PUBKEY" \
 
 echo "7. 测试不存在的holder记录"
 NON_EXISTENT_MINT="11111111111111111111111111111111"
-NON_EXISTENT_PUBKEY="22222222222222222222222222222222"
+NON_EXISTENT_PUBKEY="So11111111111111111111111111111111111111112"
 curl -X PUT "$BASE_URL/holders/$NON_EXISTENT_MINT/$NON_EXISTENT_PUBKEY" \
   -H "Content-Type: application/json" \
   -d '{"state": "initialized"}' \
   -w "\nHTTP Status: %{http_code}\n\n"
 
-echo "8. 测试不支持的HTTP方法"
+echo "8. 测试格式错误的地址"
+curl -X PUT "$BASE_URL/holders/$MINT_ADDRESS/not-a-valid-address" \
+  -H "Content-Type: application/json" \
+  -d '{"state": "initialized"}' \
+  -w "\nHTTP Status: %{http_code}\n\n"
+
+echo "9. 测试不支持的HTTP方法"
 curl -X GET "$BASE_URL/holders/$MINT_ADDRESS/$PUBKEY" \
   -H "Content-Type: application/json" \
   -w "\nHTTP Status: %{http_code}\n\n"